@@ -0,0 +1,194 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pointlander/injectsec"
+	"github.com/pointlander/injectsec/dataset"
+	"github.com/pointlander/injectsec/eval"
+	"github.com/pointlander/injectsec/gru"
+)
+
+var (
+	help            = flag.Bool("help", false, "print help")
+	weights         = flag.String("weights", "", "the weights file; the embedded weights are used by default")
+	data            = flag.String("data", "", "the labeled data; can be csv, tsv or jsonl")
+	header          = flag.Bool("header", false, "the first row of csv and tsv data files is a header")
+	textColumn      = flag.String("text", "", "the name or index of the text column")
+	labelColumn     = flag.String("label", "", "the name or index of the label column")
+	attackLabels    = flag.String("attack", "attack", "comma separated labels for attacks")
+	normalLabels    = flag.String("notattack", "not_attack", "comma separated labels for non attacks")
+	thresholds      = flag.String("thresholds", "50", "comma separated probability thresholds")
+	format          = flag.String("format", "text", "the output format: text, json or csv")
+	output          = flag.String("output", "", "the output file; stdout by default")
+	skipRegex       = flag.Bool("skipregex", false, "skip the regex filters")
+	normalize       = flag.Bool("normalize", false, "normalize the input before detection")
+	calibrate       = flag.String("calibrate", "", "fit a calibration to the -calibrationdata: platt or isotonic")
+	calibrationData = flag.String("calibrationdata", "", "the held out labeled data the calibration is fit to; it is not evaluated")
+	calibrated      = flag.String("calibrated", "calibrated.w", "the weights file the calibrated model is written to")
+	fpr             = flag.Float64("fpr", -1, "add the threshold for this target false positive rate")
+	rules           = flag.String("rules", "", "a JSON or YAML rules file that is applied before the regex filters and the network")
+	explain         = flag.String("explain", "", "explain which parts of this input drove the decision instead of evaluating data; the format is text or json")
+)
+
+func main() {
+	flag.Parse()
+	if *help || (*data == "" && *explain == "") {
+		flag.Usage()
+		return
+	}
+	// the calibration is fit to held out data so the metrics aren't in sample
+	if *calibrate != "" && *calibrationData == "" {
+		fmt.Fprintln(os.Stderr, "-calibrate needs -calibrationdata")
+		os.Exit(2)
+	}
+
+	var maker *gru.DetectorMaker
+	if *weights == "" {
+		m, err := injectsec.NewDetectorMaker()
+		if err != nil {
+			panic(err)
+		}
+		maker = m.DetectorMaker
+	} else {
+		model, err := gru.ReadModelFile(*weights)
+		if err != nil {
+			panic(err)
+		}
+		maker = &gru.DetectorMaker{
+			Model: model,
+		}
+	}
+	detector := maker.Make()
+	detector.SkipRegex = *skipRegex
+	if *normalize {
+		detector.Normalizers = gru.DefaultNormalizers
+	}
+	if *rules != "" {
+		detector.Rules = &gru.Rules{}
+		err := detector.Rules.LoadFile(*rules)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if *explain != "" {
+		explanation, err := detector.Explain(*explain)
+		if err != nil {
+			panic(err)
+		}
+		err = writeOutput(func(out io.Writer) error {
+			switch *format {
+			case "text":
+				return explanation.WriteText(out)
+			case "json":
+				return explanation.WriteJSON(out)
+			}
+			return fmt.Errorf("unknown format %q", *format)
+		})
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	var values []float32
+	for _, value := range strings.Split(*thresholds, ",") {
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(value), 32)
+		if err != nil {
+			panic(fmt.Errorf("invalid threshold %q", value))
+		}
+		values = append(values, float32(threshold))
+	}
+
+	samples, err := score(detector, *data)
+	if err != nil {
+		panic(err)
+	}
+	if *calibrate != "" {
+		held, err := score(detector, *calibrationData)
+		if err != nil {
+			panic(err)
+		}
+		fit, err := eval.Fit(held, *calibrate)
+		if err != nil {
+			panic(err)
+		}
+		maker.Model.SetCalibration(fit)
+		err = maker.Model.WriteFile(*calibrated)
+		if err != nil {
+			panic(err)
+		}
+		samples = eval.Calibrate(samples, fit)
+		fmt.Fprintf(os.Stderr, "wrote %s calibrated weights to %s\n", *calibrate, *calibrated)
+	}
+	if *fpr >= 0 {
+		threshold, err := eval.ThresholdForFPR(samples, *fpr)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(os.Stderr, "threshold for a false positive rate of %g: %g\n", *fpr, threshold)
+		values = append(values, threshold)
+	}
+	report := eval.Evaluate(samples, values)
+
+	err = writeOutput(func(out io.Writer) error {
+		switch *format {
+		case "text":
+			return report.WriteText(out)
+		case "json":
+			return report.WriteJSON(out)
+		case "csv":
+			return report.WriteCSV(out)
+		}
+		return fmt.Errorf("unknown format %q", *format)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// score loads a labeled data file and scores its examples with the detector
+func score(detector *gru.Detector, path string) ([]eval.Sample, error) {
+	config := dataset.Config{
+		Format:          dataset.FormatFromPath(path),
+		Header:          *header,
+		TextColumn:      *textColumn,
+		LabelColumn:     *labelColumn,
+		AttackLabels:    strings.Split(*attackLabels, ","),
+		NotAttackLabels: strings.Split(*normalLabels, ","),
+	}
+	examples, rowErrors, err := dataset.LoadFile(path, config)
+	if err != nil {
+		return nil, err
+	}
+	for _, rowError := range rowErrors {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, rowError)
+	}
+	return eval.Score(detector, examples)
+}
+
+// writeOutput calls write with the output file, or with stdout if there isn't one
+func writeOutput(write func(out io.Writer) error) error {
+	if *output == "" {
+		return write(os.Stdout)
+	}
+	out, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	err = write(out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pointlander/injectsec/data"
+)
+
+var (
+	help    = flag.Bool("help", false, "print help")
+	output  = flag.String("output", "", "write the built in generators to this JSON or YAML generator file")
+	verify  = flag.String("verify", "", "verify the regexes and samples of the generators in this JSON or YAML generator file")
+	samples = flag.Int("samples", 1024, "the number of samples of each generator that are verified")
+	seed    = flag.Int64("seed", 1, "the seed of the samples")
+)
+
+// check verifies that the regex of each generator matches its form and its samples
+func check(generators []data.Generator, rnd *rand.Rand) error {
+	for _, generator := range generators {
+		if generator.Regex == nil {
+			continue
+		}
+		parts := data.NewParts()
+		generator.Regex(parts)
+		exp, err := parts.Regex()
+		if err != nil {
+			return fmt.Errorf("%q: %w", generator.Form, err)
+		}
+		regex, err := regexp.Compile(exp)
+		if err != nil {
+			return fmt.Errorf("%q: %w", generator.Form, err)
+		}
+		form := strings.ToLower(generator.Form)
+		if generator.Case != "" {
+			form = strings.ToLower(generator.Case)
+		}
+		if !regex.MatchString(form) {
+			return fmt.Errorf("%q doesn't match %s", form, exp)
+		}
+		for i := 0; i < *samples; i++ {
+			sample, err := parts.Sample(rnd)
+			if err != nil {
+				return fmt.Errorf("%q: %w", generator.Form, err)
+			}
+			if !regex.MatchString(strings.ToLower(sample)) {
+				return fmt.Errorf("sample %q of %q doesn't match %s", sample, generator.Form, exp)
+			}
+		}
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if *help || (*output == "" && *verify == "") {
+		flag.Usage()
+		return
+	}
+
+	rnd := rand.New(rand.NewSource(*seed))
+	if *output != "" {
+		out, err := os.Create(*output)
+		if err != nil {
+			panic(err)
+		}
+		generators := data.TrainingDataGenerator(rnd)
+		err = data.WriteGenerators(out, data.GeneratorsFormat(*output), generators)
+		if err != nil {
+			panic(err)
+		}
+		err = out.Close()
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("wrote %d generators to %s\n", len(generators), *output)
+	}
+
+	if *verify != "" {
+		generators, err := data.LoadGenerators(*verify)
+		if err != nil {
+			panic(err)
+		}
+		err = check(generators, rnd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("verified %d generators in %s\n", len(generators), *verify)
+	}
+}
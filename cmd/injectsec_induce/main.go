@@ -0,0 +1,106 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pointlander/injectsec/data"
+)
+
+var (
+	help     = flag.Bool("help", false, "print help")
+	input    = flag.String("input", "", "a file of raw payloads, one per line")
+	format   = flag.String("format", "yaml", "the format of the proposed generators: go, json or yaml")
+	output   = flag.String("output", "", "the output file; stdout by default")
+	minCount = flag.Int("mincount", 1, "the minimum number of payloads in a cluster for a proposal")
+	stats    = flag.Bool("stats", true, "print the coverage of each proposal to stderr")
+)
+
+// readPayloads reads the payloads of a file; empty lines are skipped
+func readPayloads(path string) ([]string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	var payloads []string
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		payload := strings.TrimRight(scanner.Text(), "\r")
+		if payload != "" {
+			payloads = append(payloads, payload)
+		}
+	}
+	return payloads, scanner.Err()
+}
+
+func main() {
+	flag.Parse()
+	if *help || *input == "" {
+		flag.Usage()
+		return
+	}
+
+	payloads, err := readPayloads(*input)
+	if err != nil {
+		panic(err)
+	}
+	proposals, err := data.Induce(payloads)
+	if err != nil {
+		panic(err)
+	}
+
+	var generators []data.Generator
+	covered := 0
+	for _, proposal := range proposals {
+		if len(proposal.Payloads) < *minCount {
+			continue
+		}
+		generators = append(generators, proposal.Generator)
+		covered += len(proposal.Payloads)
+		if *stats {
+			fmt.Fprintf(os.Stderr, "%6.2f%% %5d matches %5d payloads %-10s %s\n",
+				100*proposal.Coverage, proposal.Matches, len(proposal.Payloads), proposal.Category, proposal.Parts)
+		}
+	}
+	if *stats {
+		fmt.Fprintf(os.Stderr, "%d proposals for %d of %d payloads in %d clusters\n",
+			len(generators), covered, len(payloads), len(proposals))
+	}
+
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if *output != "" {
+		file, err = os.Create(*output)
+		if err != nil {
+			panic(err)
+		}
+		out = file
+	}
+	switch *format {
+	case "go":
+		err = data.WriteGoGenerators(out, generators)
+	case data.GeneratorsJSON, data.GeneratorsYAML:
+		err = data.WriteGenerators(out, *format, generators)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		panic(err)
+	}
+	if file != nil {
+		err = file.Close()
+		if err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/pointlander/injectsec"
+	"github.com/pointlander/injectsec/gru"
+	"github.com/pointlander/injectsec/onnx"
+)
+
+var (
+	help    = flag.Bool("help", false, "print help")
+	weights = flag.String("weights", "", "the weights file; the embedded weights are used by default")
+	output  = flag.String("output", "model.onnx", "the ONNX file to write")
+	check   = flag.String("check", "", "run the exported model on this input and compare it with the Go engine")
+)
+
+func main() {
+	flag.Parse()
+	if *help {
+		flag.Usage()
+		return
+	}
+
+	var model *gru.Model
+	if *weights == "" {
+		maker, err := injectsec.NewDetectorMaker()
+		if err != nil {
+			panic(err)
+		}
+		model = maker.Model
+	} else {
+		m, err := gru.ReadModelFile(*weights)
+		if err != nil {
+			panic(err)
+		}
+		model = m
+	}
+
+	exported := model.ONNX()
+	err := model.WriteONNXFile(*output)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("wrote %s\n", *output)
+
+	if *check == "" {
+		return
+	}
+	tokens := model.Tokens(*check)
+	outputs, err := exported.Run(map[string]*onnx.Tensor{
+		gru.ONNXInput: onnx.NewInt64(gru.ONNXInput, tokens, int64(len(tokens))),
+	})
+	if err != nil {
+		panic(err)
+	}
+	input := make([]int, len(tokens))
+	for i, token := range tokens {
+		input[i] = int(token)
+	}
+	probabilities, err := gru.NewEngine(model).Probabilities(input)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("onnx: %v go: %v\n", outputs[gru.ONNXOutput].Float, probabilities)
+}
@@ -6,16 +6,23 @@ package main
 
 import (
 	"bufio"
-	"encoding/csv"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
 	"os"
 	"regexp"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 
 	dat "github.com/pointlander/injectsec/data"
+	"github.com/pointlander/injectsec/dataset"
 	"github.com/pointlander/injectsec/gru"
 )
 
@@ -28,26 +35,69 @@ var (
 	}
 )
 
-// Example is a training example
-type Example struct {
-	Data   []byte
-	Attack bool
+// DataFile is a custom data file and its training split ratio; a negative
+// split means the -split flag is used
+type DataFile struct {
+	Path  string
+	Split float64
 }
 
-// Examples are a set of examples
-type Examples []Example
+// DataFiles are the custom data files
+type DataFiles []DataFile
 
-// Permute puts the examples into random order
-func (e Examples) Permute() {
-	length := len(e)
-	for i := range e {
-		j := i + rand.Intn(length-i)
-		e[i], e[j] = e[j], e[i]
+// String implements flag.Value
+func (d *DataFiles) String() string {
+	files := make([]string, len(*d))
+	for i, file := range *d {
+		files[i] = fmt.Sprintf("%s:%v", file.Path, file.Split)
 	}
+	return strings.Join(files, ",")
 }
 
-func generateTrainingData() (training, validation Examples) {
-	generators := dat.TrainingDataGenerator(rnd)
+// Set implements flag.Value; the value is a path optionally followed by :split
+func (d *DataFiles) Set(value string) error {
+	file := DataFile{
+		Path:  value,
+		Split: -1,
+	}
+	if i := strings.LastIndex(value, ":"); i > 0 {
+		ratio, err := strconv.ParseFloat(value[i+1:], 64)
+		if err == nil {
+			if ratio < 0 || ratio > 1 {
+				return fmt.Errorf("split %v is not between 0 and 1", ratio)
+			}
+			file.Path, file.Split = value[:i], ratio
+		}
+	}
+	*d = append(*d, file)
+	return nil
+}
+
+// extraGenerators are the generators loaded from the -generators files
+var extraGenerators []dat.Generator
+
+// loadGenerators loads the generators of the -generators files
+func loadGenerators() error {
+	if *generatorFiles == "" {
+		return nil
+	}
+	for _, path := range strings.Split(*generatorFiles, ",") {
+		generators, err := dat.LoadGenerators(strings.TrimSpace(path))
+		if err != nil {
+			return err
+		}
+		extraGenerators = append(extraGenerators, generators...)
+	}
+	return nil
+}
+
+// trainingGenerators returns the built in generators followed by the loaded generators
+func trainingGenerators() []dat.Generator {
+	return append(dat.TrainingDataGenerator(rnd), extraGenerators...)
+}
+
+func generateTrainingData() (training, validation dataset.Examples) {
+	generators := trainingGenerators()
 	for _, generator := range generators {
 		if generator.SkipTrain == true {
 			continue
@@ -60,7 +110,7 @@ func generateTrainingData() (training, validation Examples) {
 				if err != nil {
 					panic(err)
 				}
-				training = append(training, Example{[]byte(strings.ToLower(line)), true})
+				training = append(training, dataset.Example{Data: []byte(strings.ToLower(line)), Attack: true, Category: generator.Category})
 			}
 		}
 	}
@@ -74,7 +124,7 @@ func generateTrainingData() (training, validation Examples) {
 		for j := 0; j < size; j++ {
 			example += string(symbols[rnd.Intn(len(symbols))])
 		}
-		training = append(training, Example{[]byte(strings.ToLower(example)), false})
+		training = append(training, dataset.Example{Data: []byte(strings.ToLower(example)), Attack: false})
 	}
 
 	for s := 'a'; s <= 'z'; s++ {
@@ -104,7 +154,7 @@ func generateTrainingData() (training, validation Examples) {
 		case 2:
 			example = "or" + right
 		}
-		training = append(training, Example{[]byte(strings.ToLower(example)), false})
+		training = append(training, dataset.Example{Data: []byte(strings.ToLower(example)), Attack: false})
 	}
 
 	var symbolsNumeric, symbolsAlphabet []rune
@@ -136,10 +186,10 @@ func generateTrainingData() (training, validation Examples) {
 			}
 			ws = " "
 		}
-		training = append(training, Example{[]byte(strings.ToLower(example)), false})
+		training = append(training, dataset.Example{Data: []byte(strings.ToLower(example)), Attack: false})
 	}
 
-	training.Permute()
+	training.Permute(rnd)
 	validation = training[:2000]
 	training = training[2000:]
 
@@ -148,17 +198,18 @@ func generateTrainingData() (training, validation Examples) {
 			continue
 		}
 		if generator.Case == "" {
-			training = append(training, Example{[]byte(strings.ToLower(generator.Form)), true})
+			training = append(training, dataset.Example{Data: []byte(strings.ToLower(generator.Form)), Attack: true, Category: generator.Category})
 		} else {
-			training = append(training, Example{[]byte(strings.ToLower(generator.Case)), true})
+			training = append(training, dataset.Example{Data: []byte(strings.ToLower(generator.Case)), Attack: true, Category: generator.Category})
 		}
 	}
 
 	return
 }
 
-func printChunks() {
-	chunks := make(map[string]int, 0)
+// fuzzLines reads the lower cased lines of the fuzzdb files
+func fuzzLines() [][]byte {
+	var lines [][]byte
 	for _, file := range FuzzFiles {
 		in, err := os.Open(file)
 		if err != nil {
@@ -167,21 +218,16 @@ func printChunks() {
 		reader := bufio.NewReader(in)
 		line, err := reader.ReadString('\n')
 		for err == nil {
-			line = strings.ToLower(strings.TrimSuffix(line, "\n"))
-			symbols, buffer := []rune(line), make([]rune, 0, 32)
-			for _, v := range symbols {
-				if v >= 'a' && v <= 'z' {
-					buffer = append(buffer, v)
-				} else if len(buffer) > 1 {
-					chunks[string(buffer)]++
-					buffer = buffer[:0]
-				} else {
-					buffer = buffer[:0]
-				}
-			}
+			lines = append(lines, []byte(strings.ToLower(strings.TrimSuffix(line, "\n"))))
 			line, err = reader.ReadString('\n')
 		}
+		in.Close()
 	}
+	return lines
+}
+
+func printChunks() {
+	chunks := gru.CountChunks(fuzzLines())
 	type Chunk struct {
 		Chunk string
 		Count int
@@ -203,23 +249,261 @@ func printChunks() {
 	fmt.Println(len(chunks))
 }
 
+// checkTokenizer checks that the tokenizer flags are used by the selected tokenizer
+func checkTokenizer() error {
+	switch *tokenizer {
+	case gru.TokenizerChunk:
+		if *dialects != "" {
+			return fmt.Errorf("-dialects is only used by the sql tokenizer")
+		}
+	case gru.TokenizerSQL:
+		if *vocabulary != 0 {
+			return fmt.Errorf("-vocabulary is only used by the chunk tokenizer")
+		}
+	default:
+		return fmt.Errorf("unknown tokenizer %q", *tokenizer)
+	}
+	return nil
+}
+
+// newTokenizer creates the tokenizer selected by the flags; a chunk
+// vocabulary is learned from the training data and the fuzzdb files
+func newTokenizer(training dataset.Examples) gru.Tokenizer {
+	switch *tokenizer {
+	case gru.TokenizerChunk:
+		if *vocabulary == 0 {
+			return gru.DefaultTokenizer
+		}
+		inputs := fuzzLines()
+		for _, example := range training {
+			inputs = append(inputs, example.Data)
+		}
+		tokenizer, err := gru.LearnTokenizer(inputs, *vocabulary, *minCount)
+		if err != nil {
+			panic(err)
+		}
+		return tokenizer
+	case gru.TokenizerSQL:
+		var names []string
+		if *dialects != "" {
+			names = strings.Split(*dialects, ",")
+		}
+		tokenizer, err := gru.NewSQLTokenizer(names...)
+		if err != nil {
+			panic(err)
+		}
+		return tokenizer
+	}
+	panic(fmt.Errorf("unknown tokenizer %q", *tokenizer))
+}
+
+var defaults = gru.DefaultOptions()
+
+// joinSizes joins layer sizes with commas
+func joinSizes(sizes []int) string {
+	joined := make([]string, len(sizes))
+	for i, size := range sizes {
+		joined[i] = strconv.Itoa(size)
+	}
+	return strings.Join(joined, ",")
+}
+
 var (
-	help   = flag.Bool("help", false, "print help")
-	chunks = flag.Bool("chunks", false, "generate chunks")
-	print  = flag.Bool("print", false, "print training data")
-	parts  = flag.Bool("parts", false, "test parts")
-	data   = flag.String("data", "", "use data for training")
-	epochs = flag.Int("epochs", 1, "the number of epochs for training")
+	help           = flag.Bool("help", false, "print help")
+	chunks         = flag.Bool("chunks", false, "generate chunks")
+	print          = flag.Bool("print", false, "print training data")
+	parts          = flag.Bool("parts", false, "test parts")
+	data           DataFiles
+	split          = flag.Float64("split", .8, "the default fraction of custom data used for training")
+	header         = flag.Bool("header", false, "the first row of csv and tsv data files is a header")
+	textColumn     = flag.String("text", "", "the name or index of the text column in data files")
+	labelColumn    = flag.String("label", "", "the name or index of the label column in data files")
+	categoryColumn = flag.String("category", "", "the name or index of the attack category column in data files; \"category\" by default if the header or the JSON object has it")
+	attackLabels   = flag.String("attack", "attack", "comma separated labels for attacks in data files")
+	normalLabels   = flag.String("notattack", "not_attack", "comma separated labels for non attacks in data files")
+	epochs         = flag.Int("epochs", 1, "the number of epochs for training")
+	embedding      = flag.Int("embedding", defaults.EmbeddingSize, "the size of the embedding")
+	hidden         = flag.String("hidden", joinSizes(defaults.HiddenSizes), "comma separated sizes of the hidden layers")
+	bidirectional  = flag.Bool("bidirectional", defaults.Bidirectional, "feed the reversed input to the network")
+	steps          = flag.Int("steps", defaults.Steps, "the number of unrolled learn steps")
+	learnrate      = flag.Float64("learnrate", defaults.LearnRate, "the learn rate")
+	l2reg          = flag.Float64("l2reg", defaults.L2Reg, "the L2 regularization")
+	clip           = flag.Float64("clip", defaults.ClipVal, "the gradient clip value")
+	patience       = flag.Int("patience", 0, "stop after this many epochs without a lower validation loss; 0 disables early stopping")
+	resume         = flag.String("resume", "", "resume training from a weights file and its .state checkpoint")
+	schedule       = flag.String("schedule", "constant", "the learning rate schedule: constant, step, exponential or cosine")
+	decay          = flag.Float64("decay", 0.5, "the learning rate decay factor of the step and exponential schedules")
+	decayEpochs    = flag.Int("decayepochs", 1, "the number of epochs between learning rate steps of the step schedule")
+	workers        = flag.Int("workers", 1, "the number of data parallel workers that each mini batch is split across")
+	batch          = flag.Int("batch", 1, "the mini batch size; 1 steps the solver after every unrolled window")
+	oversample     = flag.Float64("oversample", 0, "oversample the minority class to this ratio of the majority class; 0 disables oversampling")
+	attackWeight   = flag.Float64("attackweight", 1, "the weight of attacks in the cost")
+	normalWeight   = flag.Float64("notattackweight", 1, "the weight of non attacks in the cost")
+	seed           = flag.Int64("seed", 1, "the seed for all random choices; runs with the same seed and flags are identical")
+	tokenizer      = flag.String("tokenizer", gru.TokenizerChunk, "the tokenizer: chunk or sql")
+	vocabulary     = flag.Int("vocabulary", 0, "learn a chunk vocabulary of this size from the training data and the fuzzdb files; 0 uses the built in chunks")
+	dialects       = flag.String("dialects", "", "comma separated SQL dialects of the sql tokenizer: mysql, postgres, mssql, oracle or sqlite; all by default")
+	minCount       = flag.Int("mincount", 2, "the minimum number of occurrences of a learned chunk")
+	generatorFiles = flag.String("generators", "", "comma separated JSON or YAML generator files whose generators are added to the built in generators")
+	categories     = flag.Bool("categories", false, "train a category head that predicts the attack category of the generated attacks and of the attacks with a category in data files")
 )
 
+func options() gru.Options {
+	var hiddenSizes []int
+	for _, size := range strings.Split(*hidden, ",") {
+		s, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil {
+			panic(fmt.Errorf("invalid hidden size %q", size))
+		}
+		hiddenSizes = append(hiddenSizes, s)
+	}
+	options := gru.Options{
+		EmbeddingSize: *embedding,
+		HiddenSizes:   hiddenSizes,
+		Bidirectional: *bidirectional,
+		Steps:         *steps,
+		LearnRate:     *learnrate,
+		L2Reg:         *l2reg,
+		ClipVal:       *clip,
+	}
+	if *categories {
+		options.Categories = int(dat.Categories) - 1
+	}
+	err := options.Validate()
+	if err != nil {
+		panic(err)
+	}
+	return options
+}
+
+// FileHash is the SHA-256 of a file
+type FileHash struct {
+	Path   string
+	Split  float64 `json:",omitempty"`
+	SHA256 string
+}
+
+func hashFile(path string, split float64) (*FileHash, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	hash := sha256.New()
+	_, err = io.Copy(hash, in)
+	if err != nil {
+		return nil, err
+	}
+	return &FileHash{
+		Path:   path,
+		Split:  split,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// Manifest records what is needed to reproduce a training run
+type Manifest struct {
+	Seed      int64
+	Args      []string
+	GoVersion string
+	Options   gru.Options
+	// Tokenizer is the kind of the tokenizer and Vocabulary is its vocabulary
+	Tokenizer  string
+	Vocabulary []string
+	Epochs     int
+	Split      float64
+	Schedule   gru.Schedule
+	Patience   int
+	Workers    int
+	Batch      int
+	// Oversample is the minority to majority class ratio and Weights are the
+	// class weights of attacks and non attacks
+	Oversample float64
+	Weights    [2]float64
+	// Resume is the weights file training was resumed from
+	Resume *FileHash `json:",omitempty"`
+	// Data are the custom data files
+	Data []FileHash
+	// Generators are the generator files
+	Generators []FileHash `json:",omitempty"`
+	// Training and Validation are the hashes of the examples in order
+	Training   string
+	Validation string
+	// Outputs are the weights written after each epoch
+	Outputs []FileHash
+}
+
+// WriteFile writes the manifest as JSON
+func (m *Manifest) WriteFile(file string) error {
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
+
+func init() {
+	flag.Var(&data, "data", "use data for training; path[:split], may be repeated, and can be csv, tsv or jsonl")
+}
+
+func loadData() (training, validation dataset.Examples) {
+	for _, file := range data {
+		config := dataset.Config{
+			Format:          dataset.FormatFromPath(file.Path),
+			Header:          *header,
+			TextColumn:      *textColumn,
+			LabelColumn:     *labelColumn,
+			CategoryColumn:  *categoryColumn,
+			AttackLabels:    strings.Split(*attackLabels, ","),
+			NotAttackLabels: strings.Split(*normalLabels, ","),
+		}
+		custom, rowErrors, err := dataset.LoadFile(file.Path, config)
+		if err != nil {
+			panic(err)
+		}
+		for _, rowError := range rowErrors {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file.Path, rowError)
+		}
+		for i := range custom {
+			custom[i].Data = []byte(strings.ToLower(string(custom[i].Data)))
+		}
+		custom, conflicts := custom.Dedupe()
+		for _, rowError := range conflicts {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file.Path, rowError)
+		}
+		rowErrors = append(rowErrors, conflicts...)
+		custom.Permute(rnd)
+		ratio := file.Split
+		if ratio < 0 {
+			ratio = *split
+		}
+		t, v := custom.Split(ratio)
+		fmt.Printf("%s: %d training %d validation %d malformed\n", file.Path, len(t), len(v), len(rowErrors))
+		training = append(training, t...)
+		validation = append(validation, v...)
+	}
+	return
+}
+
 func main() {
 	flag.Parse()
 	if *help {
 		flag.Usage()
 		return
 	}
+	if err := checkTokenizer(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := loadGenerators(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	rnd = rand.New(rand.NewSource(1))
+	rnd = rand.New(rand.NewSource(*seed))
 
 	if *chunks {
 		printChunks()
@@ -227,7 +511,7 @@ func main() {
 	}
 
 	if *print {
-		generators := dat.TrainingDataGenerator(rnd)
+		generators := trainingGenerators()
 		for _, generator := range generators {
 			fmt.Println(generator.Form)
 			if generator.Regex != nil {
@@ -243,7 +527,7 @@ func main() {
 	}
 
 	if *parts {
-		generators, count, attempts, nomatch := dat.TrainingDataGenerator(rnd), 0, 0, 0
+		generators, count, attempts, nomatch := trainingGenerators(), 0, 0, 0
 		for _, generator := range generators {
 			if generator.Regex != nil {
 				parts := dat.NewParts()
@@ -287,40 +571,152 @@ func main() {
 	}
 
 	training, validation := generateTrainingData()
-	if *data != "" {
-		in, err1 := os.Open(*data)
-		if err1 != nil {
-			panic(err1)
-		}
-		defer in.Close()
-		var custom Examples
-		reader := csv.NewReader(in)
-		line, err1 := reader.Read()
-		for err1 != nil {
-			example := Example{
-				Data:   []byte(line[0]),
-				Attack: line[1] == "attack",
+	customTraining, customValidation := loadData()
+	training = append(training, customTraining...)
+	validation = append(validation, customValidation...)
+
+	fmt.Println(len(training))
+	counts := make([]int, dat.Categories)
+	for _, example := range training {
+		if example.Attack {
+			counts[example.Category]++
+		}
+	}
+	for category, count := range counts {
+		if count > 0 {
+			fmt.Printf("%s attacks: %d\n", dat.Category(category), count)
+		}
+	}
+
+	var network *gru.GRU
+	start, checkpoint := 0, &gru.Checkpoint{Best: math.Inf(1), BestEpoch: -1}
+	if *resume != "" {
+		model, err := gru.ReadModelFile(*resume)
+		if err != nil {
+			panic(err)
+		}
+		network = gru.NewGRUFromModel(model)
+		state, err := gru.ReadCheckpointFile(*resume + ".state")
+		if err == nil {
+			checkpoint, start = state, state.Epoch+1
+			network.SetSolver(state.Solver)
+			printResults(fmt.Sprintf("resuming %s at epoch %d", *resume, start))
+		} else if os.IsNotExist(err) {
+			printResults(fmt.Sprintf("resuming %s without a checkpoint; the solver state is reset", *resume))
+		} else {
+			panic(err)
+		}
+	} else {
+		networkRnd := rand.New(rand.NewSource(*seed))
+		options := options()
+		options.Tokenizer = newTokenizer(training)
+		network = gru.NewGRUWithOptions(networkRnd, options)
+	}
+	rates := gru.Schedule{
+		Kind:   *schedule,
+		Decay:  *decay,
+		Epochs: *decayEpochs,
+	}
+	base := network.Options().LearnRate
+	network.SetClassWeights(float32(*attackWeight), float32(*normalWeight))
+	size := *batch
+	if size < *workers {
+		size = *workers
+	}
+	var trainBatch func(inputs [][]byte, attacks []bool, categories []dat.Category) float32
+	if *workers > 1 {
+		trainBatch = gru.NewParallel(network, *workers).TrainBatchCategories
+	} else if size > 1 {
+		trainBatch = network.TrainBatchCategories
+	}
+
+	manifest := Manifest{
+		Seed:       *seed,
+		Args:       os.Args[1:],
+		GoVersion:  runtime.Version(),
+		Options:    network.Options(),
+		Tokenizer:  network.Tokenizer().Kind(),
+		Vocabulary: network.Tokenizer().Vocabulary(),
+		Epochs:     *epochs,
+		Split:      *split,
+		Schedule:   rates,
+		Patience:   *patience,
+		Workers:    *workers,
+		Batch:      size,
+		Oversample: *oversample,
+		Weights:    [2]float64{*attackWeight, *normalWeight},
+		Training:   training.Hash(),
+		Validation: validation.Hash(),
+	}
+	if *resume != "" {
+		manifest.Resume, err = hashFile(*resume, 0)
+		if err != nil {
+			panic(err)
+		}
+	}
+	for _, file := range data {
+		hash, err := hashFile(file.Path, file.Split)
+		if err != nil {
+			panic(err)
+		}
+		manifest.Data = append(manifest.Data, *hash)
+	}
+	if *generatorFiles != "" {
+		for _, path := range strings.Split(*generatorFiles, ",") {
+			hash, err := hashFile(strings.TrimSpace(path), 0)
+			if err != nil {
+				panic(err)
 			}
-			custom = append(custom, example)
-			line, err1 = reader.Read()
+			manifest.Generators = append(manifest.Generators, *hash)
 		}
-		custom.Permute()
-		cutoff := (80 * len(custom)) / 100
-		training = append(training, custom[:cutoff]...)
-		validation = append(validation, custom[cutoff:]...)
+	}
+	err = manifest.WriteFile("output/manifest.json")
+	if err != nil {
+		panic(err)
 	}
 
-	fmt.Println(len(training))
+	for epoch := start; epoch < *epochs; epoch++ {
+		rate, err := rates.Rate(base, epoch, *epochs)
+		if err != nil {
+			panic(err)
+		}
+		network.Solver().LearnRate = rate
+		printResults(fmt.Sprintf("epoch %d learn rate %v", epoch, rate))
 
-	networkRnd := rand.New(rand.NewSource(1))
-	network := gru.NewGRU(networkRnd)
+		// each epoch oversamples and permutes the original order with its own
+		// source so that resumed runs match uninterrupted ones
+		epochRnd := rand.New(rand.NewSource(*seed + int64(epoch) + 1))
+		shuffled := append(dataset.Examples(nil), training...)
+		if *oversample > 0 {
+			shuffled = training.Oversample(epochRnd, *oversample)
+		}
+		shuffled.Permute(epochRnd)
+		attacks, notAttacks := shuffled.Counts()
+		weightedAttacks, weightedNotAttacks := float64(attacks)*(*attackWeight), float64(notAttacks)*(*normalWeight)
+		printResults(fmt.Sprintf("%d attacks %d non attacks, effective attack ratio %.4f",
+			attacks, notAttacks, weightedAttacks/(weightedAttacks+weightedNotAttacks)))
 
-	for epoch := 0; epoch < *epochs; epoch++ {
-		training.Permute()
-		for i, example := range training {
-			cost := network.Train(example.Data, example.Attack)
-			if i%100 == 0 {
-				fmt.Println(cost)
+		if trainBatch != nil {
+			inputs, labels, categories := make([][]byte, size), make([]bool, size), make([]dat.Category, size)
+			for i := 0; i < len(shuffled); i += size {
+				batch := shuffled[i:]
+				if len(batch) > size {
+					batch = batch[:size]
+				}
+				for j, example := range batch {
+					inputs[j], labels[j], categories[j] = example.Data, example.Attack, example.Category
+				}
+				cost := trainBatch(inputs[:len(batch)], labels[:len(batch)], categories[:len(batch)])
+				if (i/size)%100 == 0 {
+					fmt.Println(cost)
+				}
+			}
+		} else {
+			for i, example := range shuffled {
+				cost := network.TrainCategory(example.Data, example.Attack, example.Category)
+				if i%100 == 0 {
+					fmt.Println(cost)
+				}
 			}
 		}
 
@@ -331,9 +727,10 @@ func main() {
 			panic(err)
 		}
 
-		correct, attacks, nattacks := 0, 0, 0
+		correct, attacks, nattacks, loss := 0, 0, 0, 0.0
 		for i := range validation {
 			example := validation[i]
+			loss += float64(network.Loss(example.Data, example.Attack))
 			attack := network.Test(example.Data)
 			if example.Attack == attack {
 				correct++
@@ -347,5 +744,40 @@ func main() {
 			}
 		}
 		printResults(attacks, nattacks, correct, len(validation))
+		loss /= float64(len(validation))
+		printResults(fmt.Sprintf("validation loss %v", loss))
+
+		checkpoint.Epoch, checkpoint.Loss = epoch, loss
+		if loss < checkpoint.Best {
+			checkpoint.Best, checkpoint.BestEpoch, checkpoint.Waited = loss, epoch, 0
+			err = network.WriteFile("output/best.w")
+			if err != nil {
+				panic(err)
+			}
+			printResults(fmt.Sprintf("output/best.w is epoch %d", epoch))
+		} else {
+			checkpoint.Waited++
+		}
+		checkpoint.Solver = network.Solver()
+		err = gru.WriteCheckpointFile(file+".state", checkpoint)
+		if err != nil {
+			panic(err)
+		}
+
+		hash, err := hashFile(file, 0)
+		if err != nil {
+			panic(err)
+		}
+		manifest.Outputs = append(manifest.Outputs, *hash)
+		err = manifest.WriteFile("output/manifest.json")
+		if err != nil {
+			panic(err)
+		}
+
+		if *patience > 0 && checkpoint.Waited >= *patience {
+			printResults(fmt.Sprintf("stopping early, the best epoch was %d with a validation loss of %v",
+				checkpoint.BestEpoch, checkpoint.Best))
+			break
+		}
 	}
 }
@@ -0,0 +1,140 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Category is a family of SQL injection attacks
+type Category int
+
+const (
+	// CategoryNone means the category isn't known
+	CategoryNone Category = iota
+	// CategoryTautology is an always true condition: or 1=1
+	CategoryTautology
+	// CategoryTimeBased is time based blind injection: sleep, waitfor delay
+	CategoryTimeBased
+	// CategoryUnion is UNION based injection: union select
+	CategoryUnion
+	// CategoryStacked is a stacked query: ; exec
+	CategoryStacked
+	// CategoryFile is file access: load_file, into outfile
+	CategoryFile
+	// CategoryObfuscated is obfuscated string building: char(0x41)
+	CategoryObfuscated
+	// CategoryOther is any other attack
+	CategoryOther
+	// Categories is the number of categories including CategoryNone
+	Categories
+)
+
+var categoryNames = [...]string{
+	CategoryNone:       "none",
+	CategoryTautology:  "tautology",
+	CategoryTimeBased:  "time_based",
+	CategoryUnion:      "union",
+	CategoryStacked:    "stacked",
+	CategoryFile:       "file",
+	CategoryObfuscated: "obfuscated",
+	CategoryOther:      "other",
+}
+
+// String returns the name of the category
+func (c Category) String() string {
+	if c < 0 || c >= Categories {
+		return "unknown"
+	}
+	return categoryNames[c]
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (c Category) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (c *Category) UnmarshalText(text []byte) error {
+	category, ok := ParseCategory(string(text))
+	if !ok {
+		return fmt.Errorf("unknown category %q", text)
+	}
+	*c = category
+	return nil
+}
+
+// ParseCategory parses the name of a category
+func ParseCategory(name string) (Category, bool) {
+	for i, n := range categoryNames {
+		if n == name {
+			return Category(i), true
+		}
+	}
+	return CategoryNone, false
+}
+
+var (
+	fileAccess = regexp.MustCompile(`load_file|into\s+(outfile|dumpfile)|pg_read_file|lo_import|lo_export|utl_file|bulk\s+insert|openrowset|xp_dirtree|load\s+data`)
+	timeBased  = regexp.MustCompile(`sleep\s*\(|benchmark\s*\(|waitfor\s+(delay|time)|dbms_lock\.sleep|dbms_pipe\.receive_message|randomblob\s*\(`)
+	union      = regexp.MustCompile(`union(\s|/\*.*?\*/|\+)+(all(\s|/\*.*?\*/|\+)+)?(distinct(\s|/\*.*?\*/|\+)+)?\(?select`)
+	stacked    = regexp.MustCompile(`;\s*(exec|execute|declare|drop|shutdown|insert|update|delete|create|alter|select|truncate|waitfor|begin|set)\b`)
+	tautology  = regexp.MustCompile(`(^|[^a-z0-9_])(or|and|xor|\|\||&&)(\s|\(|/\*.*?\*/|\+)*('?[a-z0-9_]*'?|"?[a-z0-9_]*"?|\d+)\s*(=|<>|!=|<|>|like\b|is\b|between\b)|(^|[^a-z0-9_])or(\s|\()+(true|not\s+false|\d+)\b|'\s*=\s*'|"\s*=\s*"`)
+	obfuscated = regexp.MustCompile(`(char|chr|nchar)\s*\(\s*\d+\s*\)\s*(\+|\|\||,)|concat\s*\(\s*(char|chr)|0x[0-9a-f]{8,}`)
+)
+
+// hasPartType returns true if the parts or any of their sub parts are of the type
+func hasPartType(p *Parts, partType PartType) bool {
+	for _, part := range p.Parts {
+		if part.PartType == partType {
+			return true
+		}
+		if part.Parts != nil && hasPartType(part.Parts, partType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Categorize derives the category of a generator from its form and parts;
+// the first matching category of file access, time based, obfuscated,
+// union, stacked and tautology is used; it categorizes generators read from a
+// generator file that don't set a category
+func Categorize(g Generator) Category {
+	text := g.Form
+	if g.Case != "" {
+		text = g.Case
+	}
+	if unescaped, err := url.QueryUnescape(text); err == nil {
+		text = unescaped
+	}
+	text = strings.ToLower(text)
+
+	var parts *Parts
+	if g.Regex != nil {
+		parts = NewParts()
+		g.Regex(parts)
+	}
+	switch {
+	case fileAccess.MatchString(text):
+		return CategoryFile
+	case timeBased.MatchString(text):
+		return CategoryTimeBased
+	case parts != nil && hasPartType(parts, PartTypeObfuscated),
+		parts != nil && hasPartType(parts, PartTypeObfuscatedWithComments),
+		obfuscated.MatchString(text):
+		return CategoryObfuscated
+	case union.MatchString(text):
+		return CategoryUnion
+	case stacked.MatchString(text):
+		return CategoryStacked
+	case tautology.MatchString(text):
+		return CategoryTautology
+	}
+	return CategoryOther
+}
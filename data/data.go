@@ -15,6 +15,9 @@ type Generator struct {
 	SkipTrain bool
 	SkipMatch bool
 	Regex     func(p *Parts)
+	// Category is the attack family; generators read from a generator file
+	// are categorized with Categorize if it isn't set
+	Category Category
 }
 
 // TrainingDataGenerator returns a data generator
@@ -22,7 +25,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 	generators := []Generator{
 		// Generic-SQLi.txt
 		{
-			Form: ")%20or%20('x'='x",
+			Form:     ")%20or%20('x'='x",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral(")")
 				p.AddHexOr()
@@ -33,7 +37,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "%20or%201=1",
+			Form:     "%20or%201=1",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddHexOr()
 				p.AddNumber(0, 1337)
@@ -42,7 +47,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "; execute immediate 'sel' || 'ect us' || 'er'",
+			Form:     "; execute immediate 'sel' || 'ect us' || 'er'",
+			Category: CategoryObfuscated,
 			Regex: func(p *Parts) {
 				p.AddLiteral(";")
 				p.AddSpaces()
@@ -58,13 +64,15 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "benchmark(10000000,MD5(1))#",
+			Form:     "benchmark(10000000,MD5(1))#",
+			Category: CategoryTimeBased,
 			Regex: func(p *Parts) {
 				p.AddBenchmark()
 			},
 		},
 		{
-			Form: "update",
+			Form:     "update",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("update")
@@ -72,16 +80,18 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\";waitfor delay '0:0:__TIME__'--",
-			Case: "\";waitfor delay '0:0:24'--",
+			Form:     "\";waitfor delay '0:0:__TIME__'--",
+			Category: CategoryTimeBased,
+			Case:     "\";waitfor delay '0:0:24'--",
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"")
 				p.AddWaitfor()
 			},
 		},
 		{
-			Form: "1) or pg_sleep(__TIME__)--",
-			Case: "1) or pg_sleep(123)--",
+			Form:     "1) or pg_sleep(__TIME__)--",
+			Category: CategoryTimeBased,
+			Case:     "1) or pg_sleep(123)--",
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddLiteral(")")
@@ -92,7 +102,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "||(elt(-3+5,bin(15),ord(10),hex(char(45))))",
+			Form:     "||(elt(-3+5,bin(15),ord(10),hex(char(45))))",
+			Category: CategoryObfuscated,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("(elt(")
@@ -107,7 +118,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\"hi\"\") or (\"\"a\"\"=\"\"a\"",
+			Form:     "\"hi\"\") or (\"\"a\"\"=\"\"a\"",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"")
 				p.AddName(0)
@@ -121,7 +133,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "delete",
+			Form:     "delete",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("delete")
@@ -129,7 +142,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "like",
+			Form:     "like",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("like")
@@ -137,8 +151,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\" or sleep(__TIME__)#",
-			Case: "\" or sleep(123)#",
+			Form:     "\" or sleep(__TIME__)#",
+			Category: CategoryTimeBased,
+			Case:     "\" or sleep(123)#",
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"")
 				p.AddOr()
@@ -148,8 +163,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "pg_sleep(__TIME__)--",
-			Case: "pg_sleep(123)--",
+			Form:     "pg_sleep(__TIME__)--",
+			Category: CategoryTimeBased,
+			Case:     "pg_sleep(123)--",
 			Regex: func(p *Parts) {
 				p.AddLiteral("pg_sleep(")
 				p.AddNumber(0, 1337)
@@ -157,11 +173,13 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "*(|(objectclass=*))",
+			Form:     "*(|(objectclass=*))",
+			Category: CategoryOther,
 		},
 		{
-			Form: "declare @q nvarchar (200) 0x730065006c00650063 ...",
-			Case: "declare @q nvarchar (200) 0x730065006c00650063",
+			Form:     "declare @q nvarchar (200) 0x730065006c00650063 ...",
+			Category: CategoryObfuscated,
+			Case:     "declare @q nvarchar (200) 0x730065006c00650063",
 			Regex: func(p *Parts) {
 				p.AddLiteral("declare")
 				p.AddSpaces()
@@ -178,7 +196,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 0=0 #",
+			Form:     " or 0=0 #",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -189,7 +208,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "insert",
+			Form:     "insert",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("insert")
@@ -197,8 +217,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "1) or sleep(__TIME__)#",
-			Case: "1) or sleep(567)#",
+			Form:     "1) or sleep(__TIME__)#",
+			Category: CategoryTimeBased,
+			Case:     "1) or sleep(567)#",
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddLiteral(")")
@@ -209,7 +230,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: ") or ('a'='a",
+			Form:     ") or ('a'='a",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral(")")
 				p.AddOr()
@@ -220,7 +242,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "; exec xp_regread",
+			Form:     "; exec xp_regread",
+			Category: CategoryStacked,
 			Regex: func(p *Parts) {
 				p.AddLiteral(";")
 				p.AddSpaces()
@@ -230,10 +253,12 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "*|",
+			Form:     "*|",
+			Category: CategoryOther,
 		},
 		{
-			Form: "@var select @var as var into temp end --",
+			Form:     "@var select @var as var into temp end --",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("@")
 				p.AddName(0)
@@ -257,7 +282,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "1)) or benchmark(10000000,MD5(1))#",
+			Form:     "1)) or benchmark(10000000,MD5(1))#",
+			Category: CategoryTimeBased,
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddLiteral("))")
@@ -266,7 +292,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "asc",
+			Form:     "asc",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("asc")
@@ -274,7 +301,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "(||6)",
+			Form:     "(||6)",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("(||")
 				p.AddNumber(0, 1337)
@@ -282,7 +310,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\"a\"\" or 3=3--\"",
+			Form:     "\"a\"\" or 3=3--\"",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"")
 				p.AddName(0)
@@ -295,7 +324,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\" or benchmark(10000000,MD5(1))#",
+			Form:     "\" or benchmark(10000000,MD5(1))#",
+			Category: CategoryTimeBased,
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"")
 				p.AddOr()
@@ -303,7 +333,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "# from wapiti",
+			Form:     "# from wapiti",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("#")
 				p.AddSpaces()
@@ -313,7 +344,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 0=0 --",
+			Form:     " or 0=0 --",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -324,7 +356,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "1 waitfor delay '0:0:10'--",
+			Form:     "1 waitfor delay '0:0:10'--",
+			Category: CategoryTimeBased,
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddSpaces()
@@ -342,7 +375,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 'a'='a",
+			Form:     " or 'a'='a",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("'")
@@ -352,7 +386,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "hi or 1=1 --\"",
+			Form:     "hi or 1=1 --\"",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddOr()
@@ -364,7 +399,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "or a = a",
+			Form:     "or a = a",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddName(0)
@@ -375,7 +411,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " UNION ALL SELECT",
+			Form:     " UNION ALL SELECT",
+			Category: CategoryUnion,
 			Regex: func(p *Parts) {
 				p.AddSpaces()
 				p.AddLiteral("union")
@@ -386,8 +423,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: ") or sleep(__TIME__)='",
-			Case: ") or sleep(123)='",
+			Form:     ") or sleep(__TIME__)='",
+			Category: CategoryTimeBased,
+			Case:     ") or sleep(123)='",
 			Regex: func(p *Parts) {
 				p.AddLiteral(")")
 				p.AddOr()
@@ -397,7 +435,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: ")) or benchmark(10000000,MD5(1))#",
+			Form:     ")) or benchmark(10000000,MD5(1))#",
+			Category: CategoryTimeBased,
 			Regex: func(p *Parts) {
 				p.AddLiteral("))")
 				p.AddOr()
@@ -405,7 +444,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "hi' or 'a'='a",
+			Form:     "hi' or 'a'='a",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -418,13 +458,15 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 		},
 		{
 			Form:      "0",
+			Category:  CategoryOther,
 			SkipTrain: true,
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 			},
 		},
 		{
-			Form: "21 %",
+			Form:     "21 %",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddSpaces()
@@ -432,7 +474,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "limit",
+			Form:     "limit",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("limit")
@@ -440,7 +483,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 1=1",
+			Form:     " or 1=1",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -449,7 +493,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 2 > 1",
+			Form:     " or 2 > 1",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -460,7 +505,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\")) or benchmark(10000000,MD5(1))#",
+			Form:     "\")) or benchmark(10000000,MD5(1))#",
+			Category: CategoryTimeBased,
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"))")
 				p.AddOr()
@@ -468,7 +514,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "PRINT",
+			Form:     "PRINT",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("print")
@@ -476,7 +523,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "hi') or ('a'='a",
+			Form:     "hi') or ('a'='a",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("')")
@@ -488,7 +536,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 3=3",
+			Form:     " or 3=3",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -497,15 +546,17 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "));waitfor delay '0:0:__TIME__'--",
-			Case: "));waitfor delay '0:0:42'--",
+			Form:     "));waitfor delay '0:0:__TIME__'--",
+			Category: CategoryTimeBased,
+			Case:     "));waitfor delay '0:0:42'--",
 			Regex: func(p *Parts) {
 				p.AddLiteral("))")
 				p.AddWaitfor()
 			},
 		},
 		{
-			Form: "a' waitfor delay '0:0:10'--",
+			Form:     "a' waitfor delay '0:0:10'--",
+			Category: CategoryTimeBased,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -524,8 +575,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "1;(load_file(char(47,101,116,99,47,112,97,115, ...",
-			Case: "1;(load_file(char(47,101,116,99,47,112,97,115)))",
+			Form:     "1;(load_file(char(47,101,116,99,47,112,97,115, ...",
+			Category: CategoryFile,
+			Case:     "1;(load_file(char(47,101,116,99,47,112,97,115)))",
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 256)
 				p.AddLiteral(";(load_file(char(")
@@ -534,7 +586,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "or%201=1",
+			Form:     "or%201=1",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddHexOr()
 				p.AddNumber(0, 1337)
@@ -543,8 +596,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "1 or sleep(__TIME__)#",
-			Case: "1 or sleep(123)#",
+			Form:     "1 or sleep(__TIME__)#",
+			Category: CategoryTimeBased,
+			Case:     "1 or sleep(123)#",
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddOr()
@@ -556,7 +610,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "or 1=1",
+			Form:     "or 1=1",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -565,7 +620,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " and 1 in (select var from temp)--",
+			Form:     " and 1 in (select var from temp)--",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddAnd()
 				p.AddNumber(0, 1337)
@@ -583,7 +639,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or '7659'='7659",
+			Form:     " or '7659'='7659",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("'")
@@ -593,7 +650,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 'text' = n'text'",
+			Form:     " or 'text' = n'text'",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("'")
@@ -608,14 +666,16 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " --",
+			Form:     " --",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("--")
 			},
 		},
 		{
-			Form: " or 1=1 or ''='",
+			Form:     " or 1=1 or ''='",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -626,8 +686,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "declare @s varchar (200) select @s = 0x73656c6 ...",
-			Case: "declare @s varchar (200) select @s = 0x73656c6",
+			Form:     "declare @s varchar (200) select @s = 0x73656c6 ...",
+			Category: CategoryObfuscated,
+			Case:     "declare @s varchar (200) select @s = 0x73656c6",
 			Regex: func(p *Parts) {
 				p.AddLiteral("declare")
 				p.AddSpaces()
@@ -651,7 +712,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "exec xp",
+			Form:     "exec xp",
+			Category: CategoryStacked,
 			Regex: func(p *Parts) {
 				p.AddLiteral("exec")
 				p.AddSpaces()
@@ -659,7 +721,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "; exec master..xp_cmdshell 'ping 172.10.1.255'--",
+			Form:     "; exec master..xp_cmdshell 'ping 172.10.1.255'--",
+			Category: CategoryStacked,
 			Regex: func(p *Parts) {
 				p.AddLiteral(";")
 				p.AddSpaces()
@@ -680,14 +743,16 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "3.10E+17",
+			Form:     "3.10E+17",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddType(PartTypeScientificNumber)
 			},
 		},
 		{
-			Form: "\" or pg_sleep(__TIME__)--",
-			Case: "\" or pg_sleep(123)--",
+			Form:     "\" or pg_sleep(__TIME__)--",
+			Category: CategoryTimeBased,
+			Case:     "\" or pg_sleep(123)--",
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"")
 				p.AddOr()
@@ -699,7 +764,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "x' AND email IS NULL; --",
+			Form:     "x' AND email IS NULL; --",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -714,7 +780,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "&",
+			Form:     "&",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("&")
@@ -722,7 +789,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "admin' or '",
+			Form:     "admin' or '",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -731,7 +799,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 'unusual' = 'unusual'",
+			Form:     " or 'unusual' = 'unusual'",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("'")
@@ -746,7 +815,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "//",
+			Form:     "//",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("//")
@@ -754,7 +824,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "truncate",
+			Form:     "truncate",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("truncate")
@@ -762,7 +833,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "1) or benchmark(10000000,MD5(1))#",
+			Form:     "1) or benchmark(10000000,MD5(1))#",
+			Category: CategoryTimeBased,
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddLiteral(")")
@@ -771,7 +843,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\x27UNION SELECT",
+			Form:     "\x27UNION SELECT",
+			Category: CategoryUnion,
 			Regex: func(p *Parts) {
 				p.AddLiteral("\x27union")
 				p.AddSpaces()
@@ -779,8 +852,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "declare @s varchar(200) select @s = 0x77616974 ...",
-			Case: "declare @s varchar(200) select @s = 0x77616974",
+			Form:     "declare @s varchar(200) select @s = 0x77616974 ...",
+			Category: CategoryObfuscated,
+			Case:     "declare @s varchar(200) select @s = 0x77616974",
 			Regex: func(p *Parts) {
 				p.AddLiteral("declare")
 				p.AddSpaces()
@@ -802,7 +876,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "tz_offset",
+			Form:     "tz_offset",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("tz_offset")
@@ -810,8 +885,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "sqlvuln",
-			Case: "select a from b where 1=1",
+			Form:     "sqlvuln",
+			Category: CategoryOther,
+			Case:     "select a from b where 1=1",
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddSQL()
@@ -819,22 +895,25 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\"));waitfor delay '0:0:__TIME__'--",
-			Case: "\"));waitfor delay '0:0:23'--",
+			Form:     "\"));waitfor delay '0:0:__TIME__'--",
+			Category: CategoryTimeBased,
+			Case:     "\"));waitfor delay '0:0:23'--",
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"))")
 				p.AddWaitfor()
 			},
 		},
 		{
-			Form: "||6",
+			Form:     "||6",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
 			},
 		},
 		{
-			Form: "or%201=1 --",
+			Form:     "or%201=1 --",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddHexOr()
 				p.AddNumber(0, 1337)
@@ -845,7 +924,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "%2A%28%7C%28objectclass%3D%2A%29%29",
+			Form:     "%2A%28%7C%28objectclass%3D%2A%29%29",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("%2A%28%7C%28objectclass%3D%2A%29%29")
@@ -853,7 +933,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "or a=a",
+			Form:     "or a=a",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddName(0)
@@ -862,7 +943,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: ") union select * from information_schema.tables;",
+			Form:     ") union select * from information_schema.tables;",
+			Category: CategoryUnion,
 			Regex: func(p *Parts) {
 				p.AddLiteral(")")
 				p.AddSpaces()
@@ -878,7 +960,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "PRINT @@variable",
+			Form:     "PRINT @@variable",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("print")
 				p.AddSpaces()
@@ -887,7 +970,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "or isNULL(1/0) /*",
+			Form:     "or isNULL(1/0) /*",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("isnull(")
@@ -904,7 +988,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "26 %",
+			Form:     "26 %",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddSpaces()
@@ -912,7 +997,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\" or \"a\"=\"a",
+			Form:     "\" or \"a\"=\"a",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"")
 				p.AddOr()
@@ -923,8 +1009,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "(sqlvuln)",
-			Case: "(select a from b where 1=1)",
+			Form:     "(sqlvuln)",
+			Category: CategoryOther,
+			Case:     "(select a from b where 1=1)",
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("(")
@@ -934,7 +1021,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "x' AND members.email IS NULL; --",
+			Form:     "x' AND members.email IS NULL; --",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -949,7 +1037,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 1=1--",
+			Form:     " or 1=1--",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -959,8 +1048,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " and 1=( if((load_file(char(110,46,101,120,11 ...",
-			Case: " and 1=( if((load_file(char(110,46,101,120,11)))))",
+			Form:     " and 1=( if((load_file(char(110,46,101,120,11 ...",
+			Category: CategoryFile,
+			Case:     " and 1=( if((load_file(char(110,46,101,120,11)))))",
 			Regex: func(p *Parts) {
 				p.AddAnd()
 				p.AddNumber(0, 1337)
@@ -972,14 +1062,16 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "0x770061006900740066006F0072002000640065006C00 ...",
-			Case: "0x770061006900740066006F0072002000640065006C00",
+			Form:     "0x770061006900740066006F0072002000640065006C00 ...",
+			Category: CategoryObfuscated,
+			Case:     "0x770061006900740066006F0072002000640065006C00",
 			Regex: func(p *Parts) {
 				p.AddHex(1337 * 1336)
 			},
 		},
 		{
-			Form: "%20'sleep%2050'",
+			Form:     "%20'sleep%2050'",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddHexSpaces()
 				p.AddLiteral("'sleep")
@@ -989,7 +1081,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "as",
+			Form:     "as",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("as")
@@ -997,8 +1090,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "1)) or pg_sleep(__TIME__)--",
-			Case: "1)) or pg_sleep(123)--",
+			Form:     "1)) or pg_sleep(__TIME__)--",
+			Category: CategoryTimeBased,
+			Case:     "1)) or pg_sleep(123)--",
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddLiteral("))")
@@ -1011,7 +1105,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "/**/or/**/1/**/=/**/1",
+			Form:     "/**/or/**/1/**/=/**/1",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddComment()
 				p.AddLiteral("or")
@@ -1024,7 +1119,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " union all select @@version--",
+			Form:     " union all select @@version--",
+			Category: CategoryUnion,
 			Regex: func(p *Parts) {
 				p.AddSpaces()
 				p.AddLiteral("union")
@@ -1039,15 +1135,17 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: ",@variable",
+			Form:     ",@variable",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral(",@")
 				p.AddName(0)
 			},
 		},
 		{
-			Form: "(sqlattempt2)",
-			Case: "(select a from b where 1=1)",
+			Form:     "(sqlattempt2)",
+			Category: CategoryOther,
+			Case:     "(select a from b where 1=1)",
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("(")
@@ -1057,15 +1155,17 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or (EXISTS)",
+			Form:     " or (EXISTS)",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("(exists)")
 			},
 		},
 		{
-			Form: "t'exec master..xp_cmdshell 'nslookup www.googl ...",
-			Case: "t'exec master..xp_cmdshell 'nslookup www.google.com",
+			Form:     "t'exec master..xp_cmdshell 'nslookup www.googl ...",
+			Category: CategoryStacked,
+			Case:     "t'exec master..xp_cmdshell 'nslookup www.google.com",
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'exec")
@@ -1082,7 +1182,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "%20$(sleep%2050)",
+			Form:     "%20$(sleep%2050)",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddHexSpaces()
 				p.AddLiteral("$(sleep")
@@ -1092,7 +1193,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "1 or benchmark(10000000,MD5(1))#",
+			Form:     "1 or benchmark(10000000,MD5(1))#",
+			Category: CategoryTimeBased,
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddOr()
@@ -1100,14 +1202,16 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "%20or%20''='",
+			Form:     "%20or%20''='",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddHexOr()
 				p.AddLiteral("''='")
 			},
 		},
 		{
-			Form: "||UTL_HTTP.REQUEST",
+			Form:     "||UTL_HTTP.REQUEST",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("utl_http.request")
@@ -1115,8 +1219,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or pg_sleep(__TIME__)--",
-			Case: " or pg_sleep(123)--",
+			Form:     " or pg_sleep(__TIME__)--",
+			Category: CategoryTimeBased,
+			Case:     " or pg_sleep(123)--",
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("pg_sleep(")
@@ -1127,7 +1232,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "hi' or 'x'='x';",
+			Form:     "hi' or 'x'='x';",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -1140,8 +1246,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\") or sleep(__TIME__)=\"",
-			Case: "\") or sleep(857)=\"",
+			Form:     "\") or sleep(__TIME__)=\"",
+			Category: CategoryTimeBased,
+			Case:     "\") or sleep(857)=\"",
 			Regex: func(p *Parts) {
 				p.AddLiteral("\")")
 				p.AddOr()
@@ -1153,7 +1260,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 'whatever' in ('whatever')",
+			Form:     " or 'whatever' in ('whatever')",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("'")
@@ -1168,8 +1276,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "; begin declare @var varchar(8000) set @var=' ...",
-			Case: "; begin declare @var varchar(8000) set @var='abc'",
+			Form:     "; begin declare @var varchar(8000) set @var=' ...",
+			Category: CategoryStacked,
+			Case:     "; begin declare @var varchar(8000) set @var='abc'",
 			Regex: func(p *Parts) {
 				p.AddLiteral(";")
 				p.AddSpaces()
@@ -1194,7 +1303,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " union select 1,load_file('/etc/passwd'),1,1,1;",
+			Form:     " union select 1,load_file('/etc/passwd'),1,1,1;",
+			Category: CategoryFile,
 			Regex: func(p *Parts) {
 				p.AddSpaces()
 				p.AddLiteral("union")
@@ -1206,14 +1316,16 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "0x77616974666F722064656C61792027303A303A313027 ...",
-			Case: "0x77616974666F722064656C61792027303A303A313027",
+			Form:     "0x77616974666F722064656C61792027303A303A313027 ...",
+			Category: CategoryObfuscated,
+			Case:     "0x77616974666F722064656C61792027303A303A313027",
 			Regex: func(p *Parts) {
 				p.AddHex(1337 * 1337)
 			},
 		},
 		{
-			Form: "exec(@s)",
+			Form:     "exec(@s)",
+			Category: CategoryObfuscated,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("exec(@")
@@ -1223,8 +1335,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: ") or pg_sleep(__TIME__)--",
-			Case: ") or pg_sleep(123)--",
+			Form:     ") or pg_sleep(__TIME__)--",
+			Category: CategoryTimeBased,
+			Case:     ") or pg_sleep(123)--",
 			Regex: func(p *Parts) {
 				p.AddLiteral(")")
 				p.AddOr()
@@ -1234,7 +1347,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " union select",
+			Form:     " union select",
+			Category: CategoryUnion,
 			Regex: func(p *Parts) {
 				p.AddSpaces()
 				p.AddLiteral("union")
@@ -1243,8 +1357,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or sleep(__TIME__)#",
-			Case: " or sleep(123)#",
+			Form:     " or sleep(__TIME__)#",
+			Category: CategoryTimeBased,
+			Case:     " or sleep(123)#",
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("sleep(")
@@ -1255,7 +1370,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " select * from information_schema.tables--",
+			Form:     " select * from information_schema.tables--",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpaces()
 				p.AddLiteral("select")
@@ -1268,7 +1384,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "a' or 1=1--",
+			Form:     "a' or 1=1--",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -1280,7 +1397,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "a' or 'a' = 'a",
+			Form:     "a' or 'a' = 'a",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -1296,7 +1414,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "declare @s varchar(22) select @s =",
+			Form:     "declare @s varchar(22) select @s =",
+			Category: CategoryObfuscated,
 			Regex: func(p *Parts) {
 				p.AddLiteral("declare")
 				p.AddSpaces()
@@ -1316,7 +1435,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 2 between 1 and 3",
+			Form:     " or 2 between 1 and 3",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -1331,7 +1451,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or a=a--",
+			Form:     " or a=a--",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddName(0)
@@ -1341,7 +1462,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or '1'='1",
+			Form:     " or '1'='1",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("'")
@@ -1351,7 +1473,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "|",
+			Form:     "|",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("|")
@@ -1359,8 +1482,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or sleep(__TIME__)='",
-			Case: " or sleep(123)='",
+			Form:     " or sleep(__TIME__)='",
+			Category: CategoryTimeBased,
+			Case:     " or sleep(123)='",
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("sleep(")
@@ -1371,7 +1495,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 1 --'",
+			Form:     " or 1 --'",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -1380,7 +1505,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "or 0=0 #\"",
+			Form:     "or 0=0 #\"",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -1391,7 +1517,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "having",
+			Form:     "having",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("having")
@@ -1399,14 +1526,16 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "a'",
+			Form:     "a'",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
 			},
 		},
 		{
-			Form: "\" or isNULL(1/0) /*",
+			Form:     "\" or isNULL(1/0) /*",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"")
 				p.AddOr()
@@ -1424,8 +1553,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "declare @s varchar (8000) select @s = 0x73656c ...",
-			Case: "declare @s varchar (8000) select @s = 0x73656c",
+			Form:     "declare @s varchar (8000) select @s = 0x73656c ...",
+			Category: CategoryObfuscated,
+			Case:     "declare @s varchar (8000) select @s = 0x73656c",
 			Regex: func(p *Parts) {
 				p.AddLiteral("declare")
 				p.AddSpaces()
@@ -1449,7 +1579,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "â or 1=1 --",
+			Form:     "â or 1=1 --",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddOr()
@@ -1461,7 +1592,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "char%4039%41%2b%40SELECT",
+			Form:     "char%4039%41%2b%40SELECT",
+			Category: CategoryObfuscated,
 			Regex: func(p *Parts) {
 				p.AddLiteral("char%40")
 				p.AddNumber(0, 256)
@@ -1469,7 +1601,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "order by",
+			Form:     "order by",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("order")
@@ -1479,7 +1612,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "bfilename",
+			Form:     "bfilename",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("bfilename")
@@ -1487,7 +1621,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " having 1=1--",
+			Form:     " having 1=1--",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpaces()
 				p.AddLiteral("having")
@@ -1499,7 +1634,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: ") or benchmark(10000000,MD5(1))#",
+			Form:     ") or benchmark(10000000,MD5(1))#",
+			Category: CategoryTimeBased,
 			Regex: func(p *Parts) {
 				p.AddLiteral(")")
 				p.AddOr()
@@ -1507,7 +1643,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or username like char(37);",
+			Form:     " or username like char(37);",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddName(0)
@@ -1520,14 +1657,16 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: ";waitfor delay '0:0:__TIME__'--",
-			Case: ";waitfor delay '0:0:123'--",
+			Form:     ";waitfor delay '0:0:__TIME__'--",
+			Category: CategoryTimeBased,
+			Case:     ";waitfor delay '0:0:123'--",
 			Regex: func(p *Parts) {
 				p.AddWaitfor()
 			},
 		},
 		{
-			Form: "\" or 1=1--",
+			Form:     "\" or 1=1--",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"")
 				p.AddOr()
@@ -1538,7 +1677,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "x' AND userid IS NULL; --",
+			Form:     "x' AND userid IS NULL; --",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -1553,7 +1693,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "*/*",
+			Form:     "*/*",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("*")
@@ -1563,7 +1704,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 'text' > 't'",
+			Form:     " or 'text' > 't'",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("'")
@@ -1578,7 +1720,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " (select top 1",
+			Form:     " (select top 1",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpaces()
 				p.AddLiteral("(select")
@@ -1589,22 +1732,25 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or benchmark(10000000,MD5(1))#",
+			Form:     " or benchmark(10000000,MD5(1))#",
+			Category: CategoryTimeBased,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddBenchmark()
 			},
 		},
 		{
-			Form: "\");waitfor delay '0:0:__TIME__'--",
-			Case: "\");waitfor delay '0:0:42'--",
+			Form:     "\");waitfor delay '0:0:__TIME__'--",
+			Category: CategoryTimeBased,
+			Case:     "\");waitfor delay '0:0:42'--",
 			Regex: func(p *Parts) {
 				p.AddLiteral("\")")
 				p.AddWaitfor()
 			},
 		},
 		{
-			Form: "a' or 3=3--",
+			Form:     "a' or 3=3--",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -1616,7 +1762,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " -- &password=",
+			Form:     " -- &password=",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpaces()
 				p.AddLiteral("--")
@@ -1627,7 +1774,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " group by userid having 1=1--",
+			Form:     " group by userid having 1=1--",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpaces()
 				p.AddLiteral("group")
@@ -1645,14 +1793,16 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or ''='",
+			Form:     " or ''='",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("''='")
 			},
 		},
 		{
-			Form: "; exec master..xp_cmdshell",
+			Form:     "; exec master..xp_cmdshell",
+			Category: CategoryStacked,
 			Regex: func(p *Parts) {
 				p.AddLiteral(";")
 				p.AddSpaces()
@@ -1662,7 +1812,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "%20or%20x=x",
+			Form:     "%20or%20x=x",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddHexOr()
 				p.AddName(0)
@@ -1671,7 +1822,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "select",
+			Form:     "select",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("select")
@@ -1679,8 +1831,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\")) or sleep(__TIME__)=\"",
-			Case: "\")) or sleep(123)=\"",
+			Form:     "\")) or sleep(__TIME__)=\"",
+			Category: CategoryTimeBased,
+			Case:     "\")) or sleep(123)=\"",
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"))")
 				p.AddOr()
@@ -1692,8 +1845,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "0x730065006c0065006300740020004000400076006500 ...",
-			Case: "0x730065006c0065006300740020004000400076006500",
+			Form:     "0x730065006c0065006300740020004000400076006500 ...",
+			Category: CategoryObfuscated,
+			Case:     "0x730065006c0065006300740020004000400076006500",
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddHex(1337 * 1337)
@@ -1701,7 +1855,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "hi' or 1=1 --",
+			Form:     "hi' or 1=1 --",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -1714,8 +1869,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\") or pg_sleep(__TIME__)--",
-			Case: "\") or pg_sleep(123)--",
+			Form:     "\") or pg_sleep(__TIME__)--",
+			Category: CategoryTimeBased,
+			Case:     "\") or pg_sleep(123)--",
 			Regex: func(p *Parts) {
 				p.AddLiteral("\")")
 				p.AddOr()
@@ -1726,7 +1882,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "%20or%20'x'='x",
+			Form:     "%20or%20'x'='x",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddHexOr()
 				p.AddLiteral("'")
@@ -1736,7 +1893,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 'something' = 'some'+'thing'",
+			Form:     " or 'something' = 'some'+'thing'",
+			Category: CategoryObfuscated,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("'")
@@ -1751,7 +1909,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "exec sp",
+			Form:     "exec sp",
+			Category: CategoryStacked,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("exec")
@@ -1761,7 +1920,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "29 %",
+			Form:     "29 %",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddSpaces()
@@ -1769,7 +1929,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "(",
+			Form:     "(",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("(")
@@ -1777,7 +1938,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "Ã½ or 1=1 --",
+			Form:     "Ã½ or 1=1 --",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddOr()
@@ -1789,8 +1951,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "1 or pg_sleep(__TIME__)--",
-			Case: "1 or pg_sleep(123)--",
+			Form:     "1 or pg_sleep(__TIME__)--",
+			Category: CategoryTimeBased,
+			Case:     "1 or pg_sleep(123)--",
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddOr()
@@ -1802,7 +1965,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "0 or 1=1",
+			Form:     "0 or 1=1",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddOr()
@@ -1812,7 +1976,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: ") or (a=a",
+			Form:     ") or (a=a",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral(")")
 				p.AddOr()
@@ -1824,6 +1989,7 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 		},
 		{
 			Form:      "uni/**/on sel/**/ect",
+			Category:  CategoryObfuscated,
 			SkipMatch: true,
 			Regex: func(p *Parts) {
 				p.AddParts(PartTypeObfuscatedWithComments, func(p *Parts) {
@@ -1834,7 +2000,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "replace",
+			Form:     "replace",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("replace")
@@ -1842,7 +2009,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "%27%20or%201=1",
+			Form:     "%27%20or%201=1",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("%27")
 				p.AddHexOr()
@@ -1852,8 +2020,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: ")) or pg_sleep(__TIME__)--",
-			Case: ")) or pg_sleep(343)--",
+			Form:     ")) or pg_sleep(__TIME__)--",
+			Category: CategoryTimeBased,
+			Case:     ")) or pg_sleep(343)--",
 			Regex: func(p *Parts) {
 				p.AddLiteral("))")
 				p.AddOr()
@@ -1865,7 +2034,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "%7C",
+			Form:     "%7C",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("%7C")
@@ -1873,7 +2043,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "x' AND 1=(SELECT COUNT(*) FROM tabname); --",
+			Form:     "x' AND 1=(SELECT COUNT(*) FROM tabname); --",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -1891,14 +2062,16 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "&apos;%20OR",
+			Form:     "&apos;%20OR",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("&apos;")
 				p.AddHexOr()
 			},
 		},
 		{
-			Form: "; or '1'='1'",
+			Form:     "; or '1'='1'",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral(";")
 				p.AddOr()
@@ -1910,8 +2083,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "declare @q nvarchar (200) select @q = 0x770061 ...",
-			Case: "declare @q nvarchar (200) select @q = 0x770061",
+			Form:     "declare @q nvarchar (200) select @q = 0x770061 ...",
+			Category: CategoryObfuscated,
+			Case:     "declare @q nvarchar (200) select @q = 0x770061",
 			Regex: func(p *Parts) {
 				p.AddLiteral("declare")
 				p.AddSpaces()
@@ -1935,7 +2109,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "1 or 1=1",
+			Form:     "1 or 1=1",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddOr()
@@ -1945,7 +2120,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "; exec ('sel' + 'ect us' + 'er')",
+			Form:     "; exec ('sel' + 'ect us' + 'er')",
+			Category: CategoryObfuscated,
 			Regex: func(p *Parts) {
 				p.AddLiteral(";")
 				p.AddSpaces()
@@ -1961,7 +2137,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "23 OR 1=1",
+			Form:     "23 OR 1=1",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddOr()
@@ -1971,7 +2148,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "/",
+			Form:     "/",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("/")
@@ -1979,7 +2157,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "anything' OR 'x'='x",
+			Form:     "anything' OR 'x'='x",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -1991,7 +2170,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "declare @q nvarchar (4000) select @q =",
+			Form:     "declare @q nvarchar (4000) select @q =",
+			Category: CategoryObfuscated,
 			Regex: func(p *Parts) {
 				p.AddLiteral("declare")
 				p.AddSpaces()
@@ -2013,7 +2193,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "or 0=0 --",
+			Form:     "or 0=0 --",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -2024,7 +2205,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "desc",
+			Form:     "desc",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("desc")
@@ -2032,7 +2214,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "||'6",
+			Form:     "||'6",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("||'6")
@@ -2040,7 +2223,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: ")",
+			Form:     ")",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral(")")
@@ -2048,8 +2232,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "1)) or sleep(__TIME__)#",
-			Case: "1)) or sleep(123)#",
+			Form:     "1)) or sleep(__TIME__)#",
+			Category: CategoryTimeBased,
+			Case:     "1)) or sleep(123)#",
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddLiteral("))")
@@ -2062,7 +2247,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "or 0=0 #",
+			Form:     "or 0=0 #",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -2073,8 +2259,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " select name from syscolumns where id = (sele ...",
-			Case: " select name from syscolumns where id = (select 3)",
+			Form:     " select name from syscolumns where id = (sele ...",
+			Category: CategoryOther,
+			Case:     " select name from syscolumns where id = (select 3)",
 			Regex: func(p *Parts) {
 				p.AddSpaces()
 				p.AddLiteral("select")
@@ -2097,7 +2284,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "hi or a=a",
+			Form:     "hi or a=a",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddOr()
@@ -2107,7 +2295,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "*(|(mail=*))",
+			Form:     "*(|(mail=*))",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("*(|(")
 				p.AddName(0)
@@ -2115,7 +2304,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "password:*/=1--",
+			Form:     "password:*/=1--",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("password:*/=")
 				p.AddNumber(0, 1337)
@@ -2123,7 +2313,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "distinct",
+			Form:     "distinct",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("distinct")
@@ -2131,15 +2322,17 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: ");waitfor delay '0:0:__TIME__'--",
-			Case: ");waitfor delay '0:0:123'--",
+			Form:     ");waitfor delay '0:0:__TIME__'--",
+			Category: CategoryTimeBased,
+			Case:     ");waitfor delay '0:0:123'--",
 			Regex: func(p *Parts) {
 				p.AddLiteral(")")
 				p.AddWaitfor()
 			},
 		},
 		{
-			Form: "to_timestamp_tz",
+			Form:     "to_timestamp_tz",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("to_timestamp_tz")
@@ -2147,7 +2340,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\") or benchmark(10000000,MD5(1))#",
+			Form:     "\") or benchmark(10000000,MD5(1))#",
+			Category: CategoryTimeBased,
 			Regex: func(p *Parts) {
 				p.AddLiteral("\")")
 				p.AddOr()
@@ -2155,7 +2349,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " UNION SELECT",
+			Form:     " UNION SELECT",
+			Category: CategoryUnion,
 			Regex: func(p *Parts) {
 				p.AddSpaces()
 				p.AddLiteral("union")
@@ -2164,7 +2359,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "%2A%28%7C%28mail%3D%2A%29%29",
+			Form:     "%2A%28%7C%28mail%3D%2A%29%29",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddHexSpacesOptional()
 				p.AddLiteral("%2A%28%7C%28")
@@ -2174,15 +2370,17 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "+sqlvuln",
-			Case: "+select a from b where 1=1",
+			Form:     "+sqlvuln",
+			Category: CategoryOther,
+			Case:     "+select a from b where 1=1",
 			Regex: func(p *Parts) {
 				p.AddLiteral("+")
 				p.AddSQL()
 			},
 		},
 		{
-			Form: " or 1=1 /*",
+			Form:     " or 1=1 /*",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -2193,8 +2391,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: ")) or sleep(__TIME__)='",
-			Case: ")) or sleep(123)='",
+			Form:     ")) or sleep(__TIME__)='",
+			Category: CategoryTimeBased,
+			Case:     ")) or sleep(123)='",
 			Regex: func(p *Parts) {
 				p.AddLiteral("))")
 				p.AddOr()
@@ -2206,7 +2405,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "or 1=1 or \"\"=",
+			Form:     "or 1=1 or \"\"=",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -2217,7 +2417,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 1 in (select @@version)--",
+			Form:     " or 1 in (select @@version)--",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -2232,16 +2433,18 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "sqlvuln;",
-			Case: "select a from b where 1=1;",
+			Form:     "sqlvuln;",
+			Category: CategoryOther,
+			Case:     "select a from b where 1=1;",
 			Regex: func(p *Parts) {
 				p.AddSQL()
 				p.AddLiteral(";")
 			},
 		},
 		{
-			Form: " union select * from users where login = char ...",
-			Case: " union select * from users where login = char 1, 2, 3",
+			Form:     " union select * from users where login = char ...",
+			Category: CategoryUnion,
+			Case:     " union select * from users where login = char 1, 2, 3",
 			Regex: func(p *Parts) {
 				p.AddSpaces()
 				p.AddLiteral("union")
@@ -2266,7 +2469,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "x' or 1=1 or 'x'='y",
+			Form:     "x' or 1=1 or 'x'='y",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -2282,7 +2486,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "28 %",
+			Form:     "28 %",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddSpaces()
@@ -2290,7 +2495,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "â or 3=3 --",
+			Form:     "â or 3=3 --",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddOr()
@@ -2302,14 +2508,16 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "@variable",
+			Form:     "@variable",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("@")
 				p.AddName(0)
 			},
 		},
 		{
-			Form: " or '1'='1'--",
+			Form:     " or '1'='1'--",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddLiteral("'")
@@ -2320,7 +2528,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\"a\"\" or 1=1--\"",
+			Form:     "\"a\"\" or 1=1--\"",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"")
 				p.AddName(0)
@@ -2333,7 +2542,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "//*",
+			Form:     "//*",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("//*")
@@ -2341,7 +2551,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "%2A%7C",
+			Form:     "%2A%7C",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("%2A%7C")
@@ -2349,7 +2560,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\" or 0=0 --",
+			Form:     "\" or 0=0 --",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"")
 				p.AddOr()
@@ -2361,8 +2573,9 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "\")) or pg_sleep(__TIME__)--",
-			Case: "\")) or pg_sleep(123)--",
+			Form:     "\")) or pg_sleep(__TIME__)--",
+			Category: CategoryTimeBased,
+			Case:     "\")) or pg_sleep(123)--",
 			Regex: func(p *Parts) {
 				p.AddLiteral("\"))")
 				p.AddOr()
@@ -2374,7 +2587,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "?",
+			Form:     "?",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("?")
@@ -2382,7 +2596,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 1/*",
+			Form:     " or 1/*",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -2390,7 +2605,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "!",
+			Form:     "!",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("!")
@@ -2398,14 +2614,16 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "'",
+			Form:     "'",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddSpacesOptional()
 			},
 		},
 		{
-			Form: " or a = a",
+			Form:     " or a = a",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddName(0)
@@ -2416,7 +2634,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "declare @q nvarchar (200) select @q = 0x770061006900740066006F0072002000640065006C00610079002000270030003A0030003A0031003000270000 exec(@q)",
+			Form:     "declare @q nvarchar (200) select @q = 0x770061006900740066006F0072002000640065006C00610079002000270030003A0030003A0031003000270000 exec(@q)",
+			Category: CategoryObfuscated,
 			Regex: func(p *Parts) {
 				p.AddLiteral("declare")
 				p.AddSpaces()
@@ -2444,7 +2663,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "declare @s varchar(200) select @s = 0x77616974666F722064656C61792027303A303A31302700 exec(@s) ",
+			Form:     "declare @s varchar(200) select @s = 0x77616974666F722064656C61792027303A303A31302700 exec(@s) ",
+			Category: CategoryObfuscated,
 			Regex: func(p *Parts) {
 				p.AddLiteral("declare")
 				p.AddSpaces()
@@ -2471,7 +2691,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "declare @q nvarchar (200) 0x730065006c00650063007400200040004000760065007200730069006f006e00 exec(@q)",
+			Form:     "declare @q nvarchar (200) 0x730065006c00650063007400200040004000760065007200730069006f006e00 exec(@q)",
+			Category: CategoryObfuscated,
 			Regex: func(p *Parts) {
 				p.AddLiteral("declare")
 				p.AddSpaces()
@@ -2492,7 +2713,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "declare @s varchar (200) select @s = 0x73656c65637420404076657273696f6e exec(@s)",
+			Form:     "declare @s varchar (200) select @s = 0x73656c65637420404076657273696f6e exec(@s)",
+			Category: CategoryObfuscated,
 			Regex: func(p *Parts) {
 				p.AddLiteral("declare")
 				p.AddSpaces()
@@ -2520,7 +2742,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' or 1=1",
+			Form:     "' or 1=1",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddOr()
@@ -2530,7 +2753,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 1=1 --",
+			Form:     " or 1=1 --",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -2541,7 +2765,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "x' OR full_name LIKE '%Bob%",
+			Form:     "x' OR full_name LIKE '%Bob%",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddLiteral("'")
@@ -2556,7 +2781,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "'; exec master..xp_cmdshell 'ping 172.10.1.255'--",
+			Form:     "'; exec master..xp_cmdshell 'ping 172.10.1.255'--",
+			Category: CategoryStacked,
 			Regex: func(p *Parts) {
 				p.AddLiteral("';")
 				p.AddSpaces()
@@ -2577,7 +2803,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "'%20or%20''='",
+			Form:     "'%20or%20''='",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddHexOr()
@@ -2585,7 +2812,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "'%20or%20'x'='x",
+			Form:     "'%20or%20'x'='x",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddHexOr()
@@ -2596,7 +2824,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "')%20or%20('x'='x",
+			Form:     "')%20or%20('x'='x",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("')")
 				p.AddHexOr()
@@ -2607,7 +2836,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' or 0=0 --",
+			Form:     "' or 0=0 --",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddOr()
@@ -2619,7 +2849,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' or 0=0 #",
+			Form:     "' or 0=0 #",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddOr()
@@ -2631,7 +2862,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 0=0 #\"",
+			Form:     " or 0=0 #\"",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -2642,7 +2874,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' or 1=1--",
+			Form:     "' or 1=1--",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddOr()
@@ -2653,7 +2886,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' or '1'='1'--",
+			Form:     "' or '1'='1'--",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddOr()
@@ -2665,7 +2899,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' or 1 --'",
+			Form:     "' or 1 --'",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddOr()
@@ -2675,7 +2910,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "or 1=1--",
+			Form:     "or 1=1--",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -2685,7 +2921,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' or 1=1 or ''='",
+			Form:     "' or 1=1 or ''='",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddOr()
@@ -2697,7 +2934,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 1=1 or \"\"=",
+			Form:     " or 1=1 or \"\"=",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddNumber(0, 1337)
@@ -2708,7 +2946,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' or a=a--",
+			Form:     "' or a=a--",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddOr()
@@ -2719,7 +2958,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or a=a",
+			Form:     " or a=a",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddOr()
 				p.AddName(0)
@@ -2728,7 +2968,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "') or ('a'='a",
+			Form:     "') or ('a'='a",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("')")
 				p.AddOr()
@@ -2739,7 +2980,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "'hi' or 'x'='x';",
+			Form:     "'hi' or 'x'='x';",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddName(0)
@@ -2753,7 +2995,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "or",
+			Form:     "or",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("or")
@@ -2761,7 +3004,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "procedure",
+			Form:     "procedure",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("procedure")
@@ -2769,7 +3013,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "handler",
+			Form:     "handler",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("handler")
@@ -2777,7 +3022,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' or username like '%",
+			Form:     "' or username like '%",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddOr()
@@ -2789,19 +3035,24 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' or uname like '%",
+			Form:     "' or uname like '%",
+			Category: CategoryTautology,
 		},
 		{
-			Form: "' or userid like '%",
+			Form:     "' or userid like '%",
+			Category: CategoryTautology,
 		},
 		{
-			Form: "' or uid like '%",
+			Form:     "' or uid like '%",
+			Category: CategoryTautology,
 		},
 		{
-			Form: "' or user like '%",
+			Form:     "' or user like '%",
+			Category: CategoryTautology,
 		},
 		{
-			Form: "'; exec master..xp_cmdshell",
+			Form:     "'; exec master..xp_cmdshell",
+			Category: CategoryStacked,
 			Regex: func(p *Parts) {
 				p.AddLiteral("';")
 				p.AddSpaces()
@@ -2811,7 +3062,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "'; exec xp_regread",
+			Form:     "'; exec xp_regread",
+			Category: CategoryStacked,
 			Regex: func(p *Parts) {
 				p.AddLiteral("';")
 				p.AddSpaces()
@@ -2821,7 +3073,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "t'exec master..xp_cmdshell 'nslookup www.google.com'--",
+			Form:     "t'exec master..xp_cmdshell 'nslookup www.google.com'--",
+			Category: CategoryStacked,
 			Regex: func(p *Parts) {
 				p.AddLiteral("t'exec")
 				p.AddSpaces()
@@ -2838,7 +3091,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "--sp_password",
+			Form:     "--sp_password",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("--")
 				p.AddSpacesOptional()
@@ -2847,7 +3101,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' UNION SELECT",
+			Form:     "' UNION SELECT",
+			Category: CategoryUnion,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddSpaces()
@@ -2857,7 +3112,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' UNION ALL SELECT",
+			Form:     "' UNION ALL SELECT",
+			Category: CategoryUnion,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddSpaces()
@@ -2869,7 +3125,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' or (EXISTS)",
+			Form:     "' or (EXISTS)",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddOr()
@@ -2877,7 +3134,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' (select top 1",
+			Form:     "' (select top 1",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddSpaces()
@@ -2889,7 +3147,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "'||UTL_HTTP.REQUEST",
+			Form:     "'||UTL_HTTP.REQUEST",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddOr()
@@ -2897,7 +3156,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "1;SELECT%20*",
+			Form:     "1;SELECT%20*",
+			Category: CategoryStacked,
 			Regex: func(p *Parts) {
 				p.AddNumber(0, 1337)
 				p.AddLiteral(";select")
@@ -2906,10 +3166,12 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "<>\"'%;)(&+",
+			Form:     "<>\"'%;)(&+",
+			Category: CategoryOther,
 		},
 		{
-			Form: "'%20or%201=1",
+			Form:     "'%20or%201=1",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddHexOr()
@@ -2919,15 +3181,17 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "'sqlattempt1",
-			Case: "'select a from b where 1=1",
+			Form:     "'sqlattempt1",
+			Category: CategoryOther,
+			Case:     "'select a from b where 1=1",
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddSQL()
 			},
 		},
 		{
-			Form: "%28",
+			Form:     "%28",
+			Category: CategoryOther,
 			Regex: func(p *Parts) {
 				p.AddSpacesOptional()
 				p.AddLiteral("%")
@@ -2936,16 +3200,20 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "%29",
+			Form:     "%29",
+			Category: CategoryOther,
 		},
 		{
-			Form: "%26",
+			Form:     "%26",
+			Category: CategoryOther,
 		},
 		{
-			Form: "%21",
+			Form:     "%21",
+			Category: CategoryOther,
 		},
 		{
-			Form: "' or ''='",
+			Form:     "' or ''='",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddOr()
@@ -2953,7 +3221,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: "' or 3=3",
+			Form:     "' or 3=3",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddLiteral("'")
 				p.AddOr()
@@ -2963,7 +3232,8 @@ func TrainingDataGenerator(rnd *rand.Rand) []Generator {
 			},
 		},
 		{
-			Form: " or 3=3 --",
+			Form:     " or 3=3 --",
+			Category: CategoryTautology,
 			Regex: func(p *Parts) {
 				p.AddName(0)
 				p.AddOr()
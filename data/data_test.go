@@ -5,15 +5,16 @@
 package data
 
 import (
+	"bytes"
 	"math/rand"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
 )
 
-func TestRegex(t *testing.T) {
-	rnd := rand.New(rand.NewSource(1))
-	generators := TrainingDataGenerator(rnd)
+// testRegex checks that the regex of each generator matches its form
+func testRegex(t *testing.T, generators []Generator) {
 	for _, generator := range generators {
 		if generator.Regex != nil {
 			parts := NewParts()
@@ -37,9 +38,8 @@ func TestRegex(t *testing.T) {
 	}
 }
 
-func TestSample(t *testing.T) {
-	rnd := rand.New(rand.NewSource(1))
-	generators := TrainingDataGenerator(rnd)
+// testSample checks that the regex of each generator matches its samples
+func testSample(t *testing.T, generators []Generator, rnd *rand.Rand) {
 	for _, generator := range generators {
 		if generator.Regex != nil {
 			parts := NewParts()
@@ -65,3 +65,251 @@ func TestSample(t *testing.T) {
 		}
 	}
 }
+
+func TestRegex(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	testRegex(t, TrainingDataGenerator(rnd))
+}
+
+func TestSample(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	testSample(t, TrainingDataGenerator(rnd), rnd)
+}
+
+func TestSampleDeterministic(t *testing.T) {
+	sample := func(seed int64) []string {
+		rnd := rand.New(rand.NewSource(seed))
+		var samples []string
+		for _, generator := range TrainingDataGenerator(rnd) {
+			if generator.Regex != nil {
+				parts := NewParts()
+				generator.Regex(parts)
+				for i := 0; i < 8; i++ {
+					s, err := parts.Sample(rnd)
+					if err != nil {
+						t.Fatal(err)
+					}
+					samples = append(samples, s)
+				}
+			}
+		}
+		return samples
+	}
+	a, b := sample(1), sample(1)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatal("samples with the same seed differ", a[i], b[i])
+		}
+	}
+}
+
+func TestCategorize(t *testing.T) {
+	generators := []struct {
+		Generator
+		Category
+	}{
+		{Generator{Form: "' or 1=1 --"}, CategoryTautology},
+		{Generator{Form: "%20or%20'x'='x"}, CategoryTautology},
+		{Generator{Form: "'; waitfor delay '0:0:10'--"}, CategoryTimeBased},
+		{Generator{Form: "1 and sleep(5)"}, CategoryTimeBased},
+		{Generator{Form: "' union all select @@version --"}, CategoryUnion},
+		{Generator{Form: "'; exec master..xp_cmdshell 'dir'"}, CategoryStacked},
+		{Generator{Form: "' union select load_file('/etc/passwd')"}, CategoryFile},
+		{Generator{Form: "uni/**/on", Regex: func(p *Parts) {
+			p.AddParts(PartTypeObfuscatedWithComments, func(p *Parts) {
+				p.AddLiteral("union")
+			})
+		}}, CategoryObfuscated},
+		{Generator{Form: "@@version"}, CategoryOther},
+	}
+	for _, g := range generators {
+		if category := Categorize(g.Generator); category != g.Category {
+			t.Fatal(g.Form, category, g.Category)
+		}
+	}
+
+	for _, generator := range TrainingDataGenerator(rand.New(rand.NewSource(1))) {
+		if generator.Category <= CategoryNone || generator.Category >= Categories {
+			t.Fatal("generator doesn't have a category", generator.Form)
+		}
+	}
+	for c := CategoryNone; c < Categories; c++ {
+		if parsed, ok := ParseCategory(c.String()); !ok || parsed != c {
+			t.Fatal("category doesn't round trip", c)
+		}
+	}
+}
+
+func TestParseParts(t *testing.T) {
+	p, err := ParseParts("'${or}${num:0}=${num:0:10} ${obfuscated:un$}i${spaces}on} $$x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := NewParts()
+	expected.AddLiteral("'")
+	expected.AddOr()
+	expected.AddNumber(0, 1337)
+	expected.AddLiteral("=")
+	expected.AddNumber(0, 10)
+	expected.AddLiteral(" ")
+	expected.AddParts(PartTypeObfuscated, func(p *Parts) {
+		p.AddLiteral("un}i")
+		p.AddSpaces()
+		p.AddLiteral("on")
+	})
+	expected.AddLiteral(" $x")
+	if !reflect.DeepEqual(p, expected) {
+		t.Fatal(p.String(), expected.String())
+	}
+	if text := p.String(); text != "'${or}${num:0}=${num:0:10} ${obfuscated:un$}i${spaces}on} $$x" {
+		t.Fatal(text)
+	}
+
+	for _, text := range []string{
+		"${or", "${unknown}", "${literal}", "${num:x}", "${num:0:0}", "${num:0:1:2}",
+		"${name:0:1}", "${hex}", "${numlist:0}", "${obfuscated:or", "${or:1}",
+	} {
+		if _, err := ParseParts(text); err == nil {
+			t.Fatal("expected an error", text)
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	original := TrainingDataGenerator(rand.New(rand.NewSource(1)))
+	for _, format := range []string{GeneratorsJSON, GeneratorsYAML} {
+		buffer := bytes.Buffer{}
+		err := WriteGenerators(&buffer, format, original)
+		if err != nil {
+			t.Fatal(err)
+		}
+		generators, err := ReadGenerators(&buffer, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(generators) != len(original) {
+			t.Fatal("wrong number of generators", len(generators), len(original))
+		}
+		for i, generator := range generators {
+			if generator.Form != original[i].Form || generator.Case != original[i].Case ||
+				generator.SkipTrain != original[i].SkipTrain || generator.SkipMatch != original[i].SkipMatch ||
+				generator.Category != original[i].Category || (generator.Regex == nil) != (original[i].Regex == nil) {
+				t.Fatal("generator doesn't round trip", original[i].Form)
+			}
+			if generator.Regex == nil {
+				continue
+			}
+			a, b := NewParts(), NewParts()
+			generator.Regex(a)
+			original[i].Regex(b)
+			x, err := a.Regex()
+			if err != nil {
+				t.Fatal(err)
+			}
+			y, err := b.Regex()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if x != y {
+				t.Fatal("regex doesn't round trip", original[i].Form, x, y)
+			}
+		}
+		testRegex(t, generators)
+		testSample(t, generators, rand.New(rand.NewSource(1)))
+	}
+
+	generators, err := ReadGenerators(strings.NewReader(`generators:
+  - form: "' or 1=1 --"
+    regex: "'${or}${num:0}=${num:0}${spaces_opt}--"
+`), GeneratorsYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(generators) != 1 || generators[0].Category != CategoryTautology {
+		t.Fatal("generator isn't categorized", generators)
+	}
+	testRegex(t, generators)
+	for _, text := range []string{
+		"generators:\n  - regex: \"${or}\"\n",
+		"generators:\n  - form: x\n    regex: \"${or\"\n",
+		"generators:\n  - form: x\n    category: unknown\n",
+		"generators:\n  - form: x\n    unknown: true\n",
+	} {
+		if _, err := ReadGenerators(strings.NewReader(text), GeneratorsYAML); err == nil {
+			t.Fatal("expected an error", text)
+		}
+	}
+}
+
+func TestInduce(t *testing.T) {
+	payloads := []string{
+		"' or 1=1 --",
+		"' OR 2=2 --",
+		"'%20or%20'x'='x",
+		"'%20%20or%20'abc'='abc",
+		"uni/**/on sel/*x*/ect 1",
+		"",
+		"0x41 and 12345=12345%27",
+	}
+	proposals, err := Induce(payloads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{
+		"'${spaces}or${spaces}${num:0}=${num:0}${spaces}--":         2,
+		"'${hexspaces}or${hexspaces}'${name:0}'='${name:0}":         2,
+		"uni${comment}on${spaces}sel${comment}ect${spaces}${num:0}": 1,
+		"0x41${spaces}and${spaces}${num:0:12346}=${num:0:12346}%27": 1,
+	}
+	if len(proposals) != len(expected) {
+		t.Fatal("wrong number of proposals", len(proposals))
+	}
+	for i, proposal := range proposals {
+		text := proposal.Parts.String()
+		count, ok := expected[text]
+		if !ok || len(proposal.Payloads) != count {
+			t.Fatal("unexpected proposal", text, proposal.Payloads)
+		}
+		if proposal.Matches < count || proposal.Coverage != float64(proposal.Matches)/6 {
+			t.Fatal("wrong coverage", text, proposal.Matches, proposal.Coverage)
+		}
+		if i > 0 && proposal.Matches > proposals[i-1].Matches {
+			t.Fatal("proposals aren't ordered by coverage")
+		}
+		parsed, err := ParseParts(text)
+		if err != nil || !reflect.DeepEqual(parsed, proposal.Parts) {
+			t.Fatal("proposal doesn't round trip", text, err)
+		}
+	}
+
+	generators := make([]Generator, 0, len(proposals))
+	for _, proposal := range proposals {
+		generators = append(generators, proposal.Generator)
+		parts := NewParts()
+		proposal.Regex(parts)
+		exp, err := parts.Regex()
+		if err != nil {
+			t.Fatal(err)
+		}
+		regex := regexp.MustCompile(exp)
+		for _, payload := range proposal.Payloads {
+			if !regex.MatchString(strings.ToLower(payload)) {
+				t.Fatal(exp, payload)
+			}
+		}
+	}
+	testRegex(t, generators)
+	testSample(t, generators, rand.New(rand.NewSource(1)))
+	if proposals[0].Category != CategoryTautology {
+		t.Fatal("proposal isn't categorized", proposals[0].Category)
+	}
+
+	code := bytes.Buffer{}
+	err = WriteGoGenerators(&code, generators[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(code.String(), "\t\t\tp.AddNumber(0, 1337)\n\t\t\tp.AddLiteral(\"=\")\n") {
+		t.Fatal(code.String())
+	}
+}
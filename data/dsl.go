@@ -0,0 +1,191 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The text format of Parts is literal text with parts in ${...}:
+//
+//	'${or}${num:0}=${num:0}
+//
+// A part is ${type} or ${type:arguments}, where type is the name of a part
+// type (see PartType.String):
+//
+//	${num:variable} or ${num:variable:max}  a number; max is 1337 by default
+//	${name:variable}                        a name
+//	${hex:max} and ${numlist:max}           a hex string and a list of numbers
+//	${obfuscated:text}                      an obfuscated string of the parts in text
+//	${obfuscated_comments:text}             the same with comments
+//
+// Numbers and names with the same variable have the same value. $$ is a
+// literal $, and $} is a literal }, which is needed inside of the text of an
+// obfuscated part.
+
+// defaultMax is the default maximum of a number part
+const defaultMax = 1337
+
+// ParseParts parses the text format of parts
+func ParseParts(text string) (*Parts, error) {
+	p, _, err := parseParts(text, 0, false)
+	return p, err
+}
+
+// parseParts parses parts starting at position i of the text; a nested
+// text ends at the first } that isn't escaped
+func parseParts(text string, i int, nested bool) (*Parts, int, error) {
+	p, literal := NewParts(), strings.Builder{}
+	flush := func() {
+		if literal.Len() > 0 {
+			p.AddLiteral(literal.String())
+			literal.Reset()
+		}
+	}
+	for i < len(text) {
+		c := text[i]
+		switch {
+		case c == '$' && i+1 < len(text) && (text[i+1] == '$' || text[i+1] == '}'):
+			literal.WriteByte(text[i+1])
+			i += 2
+		case c == '$' && i+1 < len(text) && text[i+1] == '{':
+			flush()
+			part, next, err := parsePart(text, i+2)
+			if err != nil {
+				return nil, 0, err
+			}
+			p.Parts = append(p.Parts, part)
+			i = next
+		case c == '}' && nested:
+			flush()
+			return p, i, nil
+		default:
+			literal.WriteByte(c)
+			i++
+		}
+	}
+	if nested {
+		return nil, 0, fmt.Errorf("unterminated part at %d", i)
+	}
+	flush()
+	return p, i, nil
+}
+
+// parsePart parses the part starting after the ${ at position i of the text,
+// and returns the position after its }
+func parsePart(text string, i int) (Part, int, error) {
+	start := i
+	for i < len(text) && text[i] != ':' && text[i] != '}' {
+		i++
+	}
+	if i == len(text) {
+		return Part{}, 0, fmt.Errorf("unterminated part at %d", start-2)
+	}
+	partType, err := ParsePartType(text[start:i])
+	if err != nil || partType == PartTypeLiteral {
+		return Part{}, 0, fmt.Errorf("unknown part type %q at %d", text[start:i], start)
+	}
+	part := Part{
+		PartType: partType,
+	}
+
+	if partType == PartTypeObfuscated || partType == PartTypeObfuscatedWithComments {
+		if text[i] == ':' {
+			part.Parts, i, err = parseParts(text, i+1, true)
+			if err != nil {
+				return Part{}, 0, err
+			}
+		}
+		return part, i + 1, nil
+	}
+
+	var arguments []int
+	if text[i] == ':' {
+		end := strings.IndexByte(text[i:], '}')
+		if end < 0 {
+			return Part{}, 0, fmt.Errorf("unterminated part at %d", start-2)
+		}
+		for _, argument := range strings.Split(text[i+1:i+end], ":") {
+			value, err := strconv.Atoi(argument)
+			if err != nil {
+				return Part{}, 0, fmt.Errorf("invalid argument %q of %s at %d", argument, partType, start)
+			}
+			arguments = append(arguments, value)
+		}
+		i += end
+	}
+	maxArguments := 0
+	switch partType {
+	case PartTypeNumber:
+		part.Max, maxArguments = defaultMax, 2
+		if len(arguments) > 0 {
+			part.Variable = arguments[0]
+		}
+		if len(arguments) > 1 {
+			part.Max = arguments[1]
+		}
+		if part.Max < 1 {
+			return Part{}, 0, fmt.Errorf("invalid maximum %d of %s at %d", part.Max, partType, start)
+		}
+	case PartTypeName:
+		maxArguments = 1
+		if len(arguments) > 0 {
+			part.Variable = arguments[0]
+		}
+	case PartTypeHex, PartTypeNumberList:
+		maxArguments = 1
+		if len(arguments) != 1 || arguments[0] < 1 {
+			return Part{}, 0, fmt.Errorf("%s at %d needs a maximum", partType, start)
+		}
+		part.Max = arguments[0]
+	}
+	if len(arguments) > maxArguments {
+		return Part{}, 0, fmt.Errorf("too many arguments of %s at %d", partType, start)
+	}
+	return part, i + 1, nil
+}
+
+// String returns the parts in the text format, see ParseParts
+func (p *Parts) String() string {
+	out := strings.Builder{}
+	p.print(&out, false)
+	return out.String()
+}
+
+// print writes the parts in the text format; } is escaped in nested text
+func (p *Parts) print(out *strings.Builder, nested bool) {
+	for _, part := range p.Parts {
+		switch part.PartType {
+		case PartTypeLiteral:
+			for i := 0; i < len(part.Literal); i++ {
+				if c := part.Literal[i]; c == '$' || (c == '}' && nested) {
+					out.WriteByte('$')
+				}
+				out.WriteByte(part.Literal[i])
+			}
+			continue
+		case PartTypeNumber:
+			fmt.Fprintf(out, "${%s:%d", part.PartType, part.Variable)
+			if part.Max != defaultMax {
+				fmt.Fprintf(out, ":%d", part.Max)
+			}
+		case PartTypeName:
+			fmt.Fprintf(out, "${%s:%d", part.PartType, part.Variable)
+		case PartTypeHex, PartTypeNumberList:
+			fmt.Fprintf(out, "${%s:%d", part.PartType, part.Max)
+		case PartTypeObfuscated, PartTypeObfuscatedWithComments:
+			fmt.Fprintf(out, "${%s", part.PartType)
+			if part.Parts != nil {
+				out.WriteByte(':')
+				part.Parts.print(out, true)
+			}
+		default:
+			fmt.Fprintf(out, "${%s", part.PartType)
+		}
+		out.WriteByte('}')
+	}
+}
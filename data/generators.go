@@ -0,0 +1,164 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Generator file formats
+const (
+	// GeneratorsJSON is a JSON generator file
+	GeneratorsJSON = "json"
+	// GeneratorsYAML is a YAML generator file
+	GeneratorsYAML = "yaml"
+)
+
+// GeneratorSpec is a generator in a generator file; Regex is in the text
+// format of ParseParts
+type GeneratorSpec struct {
+	Form      string   `json:"form" yaml:"form"`
+	Case      string   `json:"case,omitempty" yaml:"case,omitempty"`
+	SkipTrain bool     `json:"skip_train,omitempty" yaml:"skip_train,omitempty"`
+	SkipMatch bool     `json:"skip_match,omitempty" yaml:"skip_match,omitempty"`
+	Category  Category `json:"category,omitempty" yaml:"category,omitempty"`
+	Regex     string   `json:"regex,omitempty" yaml:"regex,omitempty"`
+}
+
+// generatorFile is the layout of a generator file
+type generatorFile struct {
+	Generators []GeneratorSpec `json:"generators" yaml:"generators"`
+}
+
+// Generator converts the spec to a generator; the category is derived with
+// Categorize if it isn't set
+func (s GeneratorSpec) Generator() (Generator, error) {
+	generator := Generator{
+		Form:      s.Form,
+		Case:      s.Case,
+		SkipTrain: s.SkipTrain,
+		SkipMatch: s.SkipMatch,
+		Category:  s.Category,
+	}
+	if s.Form == "" {
+		return generator, fmt.Errorf("generator without a form")
+	}
+	if s.Regex != "" {
+		parts, err := ParseParts(s.Regex)
+		if err != nil {
+			return generator, fmt.Errorf("generator %q: %w", s.Form, err)
+		}
+		generator.Regex = func(p *Parts) {
+			p.Parts = append(p.Parts, parts.Parts...)
+		}
+	}
+	if generator.Category == CategoryNone {
+		generator.Category = Categorize(generator)
+	}
+	return generator, nil
+}
+
+// Spec converts the generator to a spec
+func (g Generator) Spec() GeneratorSpec {
+	spec := GeneratorSpec{
+		Form:      g.Form,
+		Case:      g.Case,
+		SkipTrain: g.SkipTrain,
+		SkipMatch: g.SkipMatch,
+		Category:  g.Category,
+	}
+	if g.Regex != nil {
+		parts := NewParts()
+		g.Regex(parts)
+		spec.Regex = parts.String()
+	}
+	return spec
+}
+
+// ReadGenerators reads the generators of a JSON or YAML generator file
+func ReadGenerators(in io.Reader, format string) ([]Generator, error) {
+	contents, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	var file generatorFile
+	switch format {
+	case GeneratorsJSON:
+		decoder := json.NewDecoder(bytes.NewReader(contents))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&file)
+	case GeneratorsYAML:
+		err = yaml.UnmarshalStrict(contents, &file)
+	default:
+		err = fmt.Errorf("unknown generator format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	generators := make([]Generator, 0, len(file.Generators))
+	for i, spec := range file.Generators {
+		generator, err := spec.Generator()
+		if err != nil {
+			return nil, fmt.Errorf("generator %d: %w", i, err)
+		}
+		generators = append(generators, generator)
+	}
+	return generators, nil
+}
+
+// WriteGenerators writes generators as a JSON or YAML generator file
+func WriteGenerators(out io.Writer, format string, generators []Generator) error {
+	var file generatorFile
+	for _, generator := range generators {
+		file.Generators = append(file.Generators, generator.Spec())
+	}
+	switch format {
+	case GeneratorsJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(file)
+	case GeneratorsYAML:
+		contents, err := yaml.Marshal(file)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(contents)
+		return err
+	}
+	return fmt.Errorf("unknown generator format %q", format)
+}
+
+// GeneratorsFormat returns the format of a generator file from its
+// extension; files that don't end in .json are YAML
+func GeneratorsFormat(path string) string {
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return GeneratorsJSON
+	}
+	return GeneratorsYAML
+}
+
+// LoadGenerators loads the generators of a JSON or YAML generator file
+func LoadGenerators(path string) ([]Generator, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	generators, err := ReadGenerators(in, GeneratorsFormat(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return generators, nil
+}
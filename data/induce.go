@@ -0,0 +1,340 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package data
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxNumberDigits is the most digits a number of a payload can have to become a number part
+const maxNumberDigits = 9
+
+// keywords are identifiers that stay literals even if they are repeated
+var keywords = map[string]bool{
+	"all": true, "and": true, "as": true, "benchmark": true, "by": true, "case": true,
+	"char": true, "concat": true, "declare": true, "delay": true, "delete": true, "drop": true,
+	"else": true, "end": true, "exec": true, "execute": true, "from": true, "group": true,
+	"having": true, "if": true, "in": true, "insert": true, "into": true, "is": true,
+	"like": true, "limit": true, "not": true, "null": true, "or": true, "order": true,
+	"select": true, "set": true, "sleep": true, "table": true, "then": true, "union": true,
+	"update": true, "values": true, "waitfor": true, "when": true, "where": true,
+}
+
+// Proposal is a generator induced from a cluster of payloads
+type Proposal struct {
+	Generator
+	// Parts are the parts of the regex of the generator
+	Parts *Parts
+	// Payloads are the payloads of the cluster; the first is the form of the generator
+	Payloads []string
+	// Matches is the number of input payloads the regex matches, including
+	// payloads of other clusters
+	Matches int
+	// Coverage is the fraction of the input payloads the regex matches
+	Coverage float64
+}
+
+// isLetter returns true if c can start an identifier
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+// isDigit returns true if c is a digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isSpace returns true if c is white space
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// isHexDigit returns true if c is a hex digit
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// induceParts proposes parts that match a payload: numbers become number
+// parts, identifiers that are repeated become name parts, runs of %20 become
+// hex spaces, runs of white space become spaces, and /*...*/ becomes a
+// comment; other URL escapes and hex numbers stay literals. Numbers with
+// the same value and names that are the same share a variable
+func induceParts(payload string) *Parts {
+	counts := make(map[string]int)
+	for i := 0; i < len(payload); {
+		if isLetter(payload[i]) {
+			j := i + 1
+			for j < len(payload) && (isLetter(payload[j]) || isDigit(payload[j])) {
+				j++
+			}
+			counts[strings.ToLower(payload[i:j])]++
+			i = j
+			continue
+		}
+		i++
+	}
+
+	p, literal, variables := NewParts(), strings.Builder{}, make(map[string]int)
+	flush := func() {
+		if literal.Len() > 0 {
+			p.AddLiteral(literal.String())
+			literal.Reset()
+		}
+	}
+	variable := func(key string) int {
+		v, ok := variables[key]
+		if !ok {
+			v = len(variables)
+			variables[key] = v
+		}
+		return v
+	}
+	for i := 0; i < len(payload); {
+		c := payload[i]
+		switch {
+		case strings.HasPrefix(payload[i:], "/*"):
+			end := strings.Index(payload[i+2:], "*/")
+			if end < 0 || !isCommentText(payload[i+2:i+2+end]) {
+				literal.WriteString("/*")
+				i += 2
+				break
+			}
+			flush()
+			p.AddComment()
+			i += end + 4
+		case strings.HasPrefix(payload[i:], "%20"):
+			for strings.HasPrefix(payload[i:], "%20") {
+				i += 3
+			}
+			flush()
+			p.AddHexSpaces()
+		case c == '%' && i+2 < len(payload) && isHexDigit(payload[i+1]) && isHexDigit(payload[i+2]):
+			// other URL escapes stay literals
+			literal.WriteString(payload[i : i+3])
+			i += 3
+		case isSpace(c):
+			for i < len(payload) && isSpace(payload[i]) {
+				i++
+			}
+			flush()
+			p.AddSpaces()
+		case isLetter(c):
+			j := i + 1
+			for j < len(payload) && (isLetter(payload[j]) || isDigit(payload[j])) {
+				j++
+			}
+			name := strings.ToLower(payload[i:j])
+			if counts[name] > 1 && !keywords[name] {
+				flush()
+				p.AddName(variable("name " + name))
+			} else {
+				literal.WriteString(payload[i:j])
+			}
+			i = j
+		case isDigit(c):
+			j := i + 1
+			if c == '0' && j < len(payload) && (payload[j] == 'x' || payload[j] == 'X') {
+				for j++; j < len(payload) && isHexDigit(payload[j]); j++ {
+				}
+				literal.WriteString(payload[i:j])
+				i = j
+				break
+			}
+			for j < len(payload) && isDigit(payload[j]) {
+				j++
+			}
+			if j-i > maxNumberDigits {
+				literal.WriteString(payload[i:j])
+				i = j
+				break
+			}
+			value, _ := strconv.Atoi(payload[i:j])
+			max := defaultMax
+			if value >= max {
+				max = value + 1
+			}
+			flush()
+			p.AddNumber(variable("number "+strconv.Itoa(value)), max)
+			i = j
+		default:
+			literal.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return p
+}
+
+// isCommentText returns true if the text can be in a comment part
+func isCommentText(text string) bool {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '_' || !(isLetter(c) || isDigit(c) || isSpace(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Induce clusters payloads by the parts proposed for each of them, and returns
+// a proposal for each cluster, ordered by coverage. Empty payloads are skipped
+func Induce(payloads []string) ([]Proposal, error) {
+	var proposals []Proposal
+	clusters := make(map[string]int)
+	for _, payload := range payloads {
+		if payload == "" {
+			continue
+		}
+		parts := induceParts(payload)
+		key := strings.ToLower(parts.String())
+		if i, ok := clusters[key]; ok {
+			proposals[i].Payloads = append(proposals[i].Payloads, payload)
+			continue
+		}
+		clusters[key] = len(proposals)
+		proposals = append(proposals, Proposal{
+			Parts:    parts,
+			Payloads: []string{payload},
+		})
+	}
+
+	lower := make([]string, 0, len(payloads))
+	for _, payload := range payloads {
+		if payload != "" {
+			lower = append(lower, strings.ToLower(payload))
+		}
+	}
+	for i := range proposals {
+		proposal := &proposals[i]
+		exp, err := proposal.Parts.Regex()
+		if err != nil {
+			return nil, err
+		}
+		regex, err := regexp.Compile(exp)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", proposal.Payloads[0], err)
+		}
+		for _, payload := range lower {
+			if regex.MatchString(payload) {
+				proposal.Matches++
+			}
+		}
+		proposal.Coverage = float64(proposal.Matches) / float64(len(lower))
+		parts := proposal.Parts
+		proposal.Generator = Generator{
+			Form: proposal.Payloads[0],
+			Regex: func(p *Parts) {
+				p.Parts = append(p.Parts, parts.Parts...)
+			},
+		}
+		proposal.Category = Categorize(proposal.Generator)
+	}
+	sort.SliceStable(proposals, func(i, j int) bool {
+		return proposals[i].Matches > proposals[j].Matches
+	})
+	return proposals, nil
+}
+
+// partTypeGoNames are the names of the part type constants
+var partTypeGoNames = [...]string{
+	PartTypeLiteral:                "PartTypeLiteral",
+	PartTypeNumber:                 "PartTypeNumber",
+	PartTypeName:                   "PartTypeName",
+	PartTypeOr:                     "PartTypeOr",
+	PartTypeHexOr:                  "PartTypeHexOr",
+	PartTypeAnd:                    "PartTypeAnd",
+	PartTypeSpaces:                 "PartTypeSpaces",
+	PartTypeSpacesOptional:         "PartTypeSpacesOptional",
+	PartTypeHexSpaces:              "PartTypeHexSpaces",
+	PartTypeHexSpacesOptional:      "PartTypeHexSpacesOptional",
+	PartTypeComment:                "PartTypeComment",
+	PartTypeObfuscated:             "PartTypeObfuscated",
+	PartTypeObfuscatedWithComments: "PartTypeObfuscatedWithComments",
+	PartTypeHex:                    "PartTypeHex",
+	PartTypeNumberList:             "PartTypeNumberList",
+	PartTypeScientificNumber:       "PartTypeScientificNumber",
+	PartTypeSQL:                    "PartTypeSQL",
+}
+
+// writeGo writes the Go statements that add the parts
+func (p *Parts) writeGo(out *strings.Builder, indent string) {
+	for _, part := range p.Parts {
+		out.WriteString(indent)
+		switch part.PartType {
+		case PartTypeLiteral:
+			fmt.Fprintf(out, "p.AddLiteral(%q)\n", part.Literal)
+		case PartTypeNumber:
+			fmt.Fprintf(out, "p.AddNumber(%d, %d)\n", part.Variable, part.Max)
+		case PartTypeName:
+			fmt.Fprintf(out, "p.AddName(%d)\n", part.Variable)
+		case PartTypeOr:
+			out.WriteString("p.AddOr()\n")
+		case PartTypeHexOr:
+			out.WriteString("p.AddHexOr()\n")
+		case PartTypeAnd:
+			out.WriteString("p.AddAnd()\n")
+		case PartTypeSpaces:
+			out.WriteString("p.AddSpaces()\n")
+		case PartTypeSpacesOptional:
+			out.WriteString("p.AddSpacesOptional()\n")
+		case PartTypeHexSpaces:
+			out.WriteString("p.AddHexSpaces()\n")
+		case PartTypeHexSpacesOptional:
+			out.WriteString("p.AddHexSpacesOptional()\n")
+		case PartTypeComment:
+			out.WriteString("p.AddComment()\n")
+		case PartTypeHex:
+			fmt.Fprintf(out, "p.AddHex(%d)\n", part.Max)
+		case PartTypeNumberList:
+			fmt.Fprintf(out, "p.AddNumberList(%d)\n", part.Max)
+		case PartTypeSQL:
+			out.WriteString("p.AddSQL()\n")
+		case PartTypeObfuscated, PartTypeObfuscatedWithComments:
+			if part.Parts == nil {
+				fmt.Fprintf(out, "p.AddType(%s)\n", partTypeGoNames[part.PartType])
+				break
+			}
+			fmt.Fprintf(out, "p.AddParts(%s, func(p *Parts) {\n", partTypeGoNames[part.PartType])
+			part.Parts.writeGo(out, indent+"\t")
+			out.WriteString(indent + "})\n")
+		default:
+			fmt.Fprintf(out, "p.AddType(%s)\n", partTypeGoNames[part.PartType])
+		}
+	}
+}
+
+// WriteGoGenerators writes generators as elements of a Go []Generator
+// literal, in the style of TrainingDataGenerator
+func WriteGoGenerators(out io.Writer, generators []Generator) error {
+	code := strings.Builder{}
+	for _, generator := range generators {
+		code.WriteString("\t{\n")
+		fmt.Fprintf(&code, "\t\tForm: %q,\n", generator.Form)
+		if generator.Case != "" {
+			fmt.Fprintf(&code, "\t\tCase: %q,\n", generator.Case)
+		}
+		if generator.SkipTrain {
+			code.WriteString("\t\tSkipTrain: true,\n")
+		}
+		if generator.SkipMatch {
+			code.WriteString("\t\tSkipMatch: true,\n")
+		}
+		if generator.Regex != nil {
+			parts := NewParts()
+			generator.Regex(parts)
+			code.WriteString("\t\tRegex: func(p *Parts) {\n")
+			parts.writeGo(&code, "\t\t\t")
+			code.WriteString("\t\t},\n")
+		}
+		code.WriteString("\t},\n")
+	}
+	_, err := io.WriteString(out, code.String())
+	return err
+}
@@ -57,6 +57,44 @@ const (
 	PartTypeSQL
 )
 
+var partTypeNames = [...]string{
+	PartTypeLiteral:                "literal",
+	PartTypeNumber:                 "num",
+	PartTypeName:                   "name",
+	PartTypeOr:                     "or",
+	PartTypeHexOr:                  "hexor",
+	PartTypeAnd:                    "and",
+	PartTypeSpaces:                 "spaces",
+	PartTypeSpacesOptional:         "spaces_opt",
+	PartTypeHexSpaces:              "hexspaces",
+	PartTypeHexSpacesOptional:      "hexspaces_opt",
+	PartTypeComment:                "comment",
+	PartTypeObfuscated:             "obfuscated",
+	PartTypeObfuscatedWithComments: "obfuscated_comments",
+	PartTypeHex:                    "hex",
+	PartTypeNumberList:             "numlist",
+	PartTypeScientificNumber:       "scinum",
+	PartTypeSQL:                    "sql",
+}
+
+// String returns the name of the part type
+func (p PartType) String() string {
+	if p < 0 || int(p) >= len(partTypeNames) {
+		return "unknown"
+	}
+	return partTypeNames[p]
+}
+
+// ParsePartType parses the name of a part type
+func ParsePartType(name string) (PartType, error) {
+	for i, n := range partTypeNames {
+		if n == name {
+			return PartType(i), nil
+		}
+	}
+	return PartTypeLiteral, fmt.Errorf("%w: %q", ErrorNotSupported, name)
+}
+
 // Part is part of a regex
 type Part struct {
 	PartType
@@ -326,7 +364,7 @@ func (p *Parts) Sample(rnd *rand.Rand) (string, error) {
 				sample += value
 				break
 			}
-			s := strconv.Itoa(rand.Intn(part.Max))
+			s := strconv.Itoa(rnd.Intn(part.Max))
 			state[part.Variable] = s
 			sample += s
 		case PartTypeName:
@@ -334,7 +372,7 @@ func (p *Parts) Sample(rnd *rand.Rand) (string, error) {
 				sample += value
 				break
 			}
-			s, count := "", rand.Intn(8)+1
+			s, count := "", rnd.Intn(8)+1
 			for i := 0; i < count; i++ {
 				s += string(rune(int('a') + rnd.Intn(int('z'-'a'))))
 			}
@@ -470,7 +508,7 @@ func (p *Parts) Sample(rnd *rand.Rand) (string, error) {
 			}
 		case PartTypeComment:
 			sample += "/*"
-			count := rand.Intn(8) + 1
+			count := rnd.Intn(8) + 1
 			for i := 0; i < count; i++ {
 				sample += string(rune(int('a') + rnd.Intn(int('z'-'a'))))
 			}
@@ -509,23 +547,23 @@ func (p *Parts) Sample(rnd *rand.Rand) (string, error) {
 			sample += fmt.Sprintf("%#x", rnd.Intn(part.Max))
 		case PartTypeNumberList:
 			for i := 0; i < 7; i++ {
-				sample += strconv.Itoa(rand.Intn(part.Max))
+				sample += strconv.Itoa(rnd.Intn(part.Max))
 				sample += ","
 			}
-			sample += strconv.Itoa(rand.Intn(part.Max))
+			sample += strconv.Itoa(rnd.Intn(part.Max))
 		case PartTypeScientificNumber:
 			const factor = 1337 * 1337
 			sample += fmt.Sprintf("%E", rnd.Float64()*factor-factor/2)
 		case PartTypeSQL:
-			a, count := "", rand.Intn(8)+1
+			a, count := "", rnd.Intn(8)+1
 			for i := 0; i < count; i++ {
 				a += string(rune(int('a') + rnd.Intn(int('z'-'a'))))
 			}
-			b, count := "", rand.Intn(8)+1
+			b, count := "", rnd.Intn(8)+1
 			for i := 0; i < count; i++ {
 				b += string(rune(int('a') + rnd.Intn(int('z'-'a'))))
 			}
-			n := strconv.Itoa(rand.Intn(1337))
+			n := strconv.Itoa(rnd.Intn(1337))
 
 			sample += "select " + a + " from " + b + " where " + n + "=" + n
 		}
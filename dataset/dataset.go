@@ -0,0 +1,460 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dataset loads labeled SQL injection examples from CSV, TSV and
+// JSONL files
+package dataset
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pointlander/injectsec/data"
+)
+
+// Example is a training example
+type Example struct {
+	Data   []byte
+	Attack bool
+	// Category is the attack category of the example, if it is known
+	Category data.Category
+	// Line is the line of the example in its data file, or 0
+	Line int
+}
+
+// Examples are a set of examples
+type Examples []Example
+
+// Permute puts the examples into random order using rnd
+func (e Examples) Permute(rnd *rand.Rand) {
+	length := len(e)
+	for i := range e {
+		j := i + rnd.Intn(length-i)
+		e[i], e[j] = e[j], e[i]
+	}
+}
+
+// Split splits the examples into a training set with ratio of the examples
+// and a validation set with the rest
+func (e Examples) Split(ratio float64) (training, validation Examples) {
+	cutoff := int(ratio * float64(len(e)))
+	if cutoff < 0 {
+		cutoff = 0
+	} else if cutoff > len(e) {
+		cutoff = len(e)
+	}
+	return e[:cutoff], e[cutoff:]
+}
+
+// Dedupe removes repeated examples keeping the first occurrence. Examples
+// with the same text and conflicting labels are all removed, and a row error
+// is returned for each example whose label conflicts with the first
+func (e Examples) Dedupe() (Examples, []*RowError) {
+	first := make(map[string]Example, len(e))
+	conflicts := make(map[string]bool)
+	var rowErrors []*RowError
+	for _, example := range e {
+		seen, ok := first[string(example.Data)]
+		if !ok {
+			first[string(example.Data)] = example
+			continue
+		}
+		if seen.Attack != example.Attack {
+			conflicts[string(example.Data)] = true
+			rowErrors = append(rowErrors, &RowError{
+				Line: example.Line,
+				Err:  fmt.Errorf("label of %q conflicts with line %d", example.Data, seen.Line),
+			})
+		}
+	}
+	deduped := make(Examples, 0, len(first))
+	for _, example := range e {
+		text := string(example.Data)
+		if conflicts[text] {
+			continue
+		}
+		if _, ok := first[text]; ok {
+			deduped = append(deduped, example)
+			delete(first, text)
+		}
+	}
+	return deduped, rowErrors
+}
+
+// Counts returns the number of attacks and non attacks
+func (e Examples) Counts() (attacks, notAttacks int) {
+	for _, example := range e {
+		if example.Attack {
+			attacks++
+		} else {
+			notAttacks++
+		}
+	}
+	return attacks, notAttacks
+}
+
+// Oversample returns a copy of the examples with the minority class sampled with
+// replacement using rnd until it is at least ratio times the size of the
+// majority class; the added examples are appended
+func (e Examples) Oversample(rnd *rand.Rand, ratio float64) Examples {
+	var attacks, notAttacks Examples
+	for _, example := range e {
+		if example.Attack {
+			attacks = append(attacks, example)
+		} else {
+			notAttacks = append(notAttacks, example)
+		}
+	}
+	minority, majority := attacks, notAttacks
+	if len(minority) > len(majority) {
+		minority, majority = majority, minority
+	}
+	oversampled := append(Examples(nil), e...)
+	if len(minority) == 0 {
+		return oversampled
+	}
+	target := int(math.Ceil(ratio * float64(len(majority))))
+	if target > len(majority) {
+		target = len(majority)
+	}
+	for i := len(minority); i < target; i++ {
+		oversampled = append(oversampled, minority[rnd.Intn(len(minority))])
+	}
+	return oversampled
+}
+
+// Hash returns the hex encoded SHA-256 of the examples and their labels and
+// categories in order
+func (e Examples) Hash() string {
+	hash := sha256.New()
+	for _, example := range e {
+		var prefix [9]byte
+		prefix[0] = byte(example.Category) << 1
+		if example.Attack {
+			prefix[0] |= 1
+		}
+		binary.LittleEndian.PutUint64(prefix[1:], uint64(len(example.Data)))
+		hash.Write(prefix[:])
+		hash.Write(example.Data)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Format is the format of a data file
+type Format int
+
+const (
+	// FormatCSV is comma separated values
+	FormatCSV Format = iota
+	// FormatTSV is tab separated values
+	FormatTSV
+	// FormatJSONL is one JSON object per line
+	FormatJSONL
+)
+
+// FormatFromPath returns the format for the extension of a path; CSV is the default
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tsv", ".tab":
+		return FormatTSV
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	}
+	return FormatCSV
+}
+
+// Config configures how a data file is read
+type Config struct {
+	// Format is the format of the file
+	Format Format
+	// Header means the first row of a CSV or TSV file names the columns
+	Header bool
+	// TextColumn is the name or index of the text column; the default is "text" or 0
+	TextColumn string
+	// LabelColumn is the name or index of the label column; the default is "label" or 1
+	LabelColumn string
+	// CategoryColumn is the name or index of the optional attack category
+	// column; the default is "category" if the header or the JSON object has
+	// it. Empty categories are data.CategoryNone
+	CategoryColumn string
+	// AttackLabels are the labels for attacks; the default is "attack"
+	AttackLabels []string
+	// NotAttackLabels are the labels for non attacks; the default is "not_attack"
+	NotAttackLabels []string
+}
+
+// RowError is a malformed row
+type RowError struct {
+	Line int
+	Err  error
+}
+
+// Error implements error
+func (r *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", r.Line, r.Err)
+}
+
+type labeler struct {
+	attack, notAttack map[string]bool
+}
+
+func newLabeler(config Config) labeler {
+	l := labeler{
+		attack:    make(map[string]bool),
+		notAttack: make(map[string]bool),
+	}
+	attack, notAttack := config.AttackLabels, config.NotAttackLabels
+	if len(attack) == 0 {
+		attack = []string{"attack"}
+	}
+	if len(notAttack) == 0 {
+		notAttack = []string{"not_attack"}
+	}
+	for _, label := range attack {
+		l.attack[strings.ToLower(strings.TrimSpace(label))] = true
+	}
+	for _, label := range notAttack {
+		l.notAttack[strings.ToLower(strings.TrimSpace(label))] = true
+	}
+	return l
+}
+
+func (l labeler) label(label string) (bool, error) {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if l.attack[label] {
+		return true, nil
+	} else if l.notAttack[label] {
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown label %q", label)
+}
+
+// category parses the category of an example; only attacks have categories
+func category(name string, attack bool) (data.Category, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return data.CategoryNone, nil
+	}
+	category, ok := data.ParseCategory(name)
+	if !ok {
+		return data.CategoryNone, fmt.Errorf("unknown category %q", name)
+	}
+	if !attack && category != data.CategoryNone {
+		return data.CategoryNone, fmt.Errorf("category %q of a non attack", name)
+	}
+	return category, nil
+}
+
+// Load loads examples from a reader; malformed rows are skipped and returned as row errors
+func Load(in io.Reader, config Config) (Examples, []*RowError, error) {
+	if config.Format == FormatJSONL {
+		return loadJSONL(in, config)
+	}
+	return loadSV(in, config)
+}
+
+// LoadFile loads examples from a file
+func LoadFile(path string, config Config) (Examples, []*RowError, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer in.Close()
+	return Load(in, config)
+}
+
+func loadSV(in io.Reader, config Config) (Examples, []*RowError, error) {
+	reader := csv.NewReader(in)
+	if config.Format == FormatTSV {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	column := func(name string, header []string, fallback int) (int, error) {
+		if name == "" {
+			if header == nil {
+				return fallback, nil
+			}
+			name = []string{"text", "label"}[fallback]
+		}
+		for i, v := range header {
+			if strings.TrimSpace(v) == name {
+				return i, nil
+			}
+		}
+		index, err := strconv.Atoi(name)
+		if err != nil || index < 0 {
+			return 0, fmt.Errorf("column %q not found", name)
+		}
+		return index, nil
+	}
+
+	var header []string
+	if config.Header {
+		row, err := reader.Read()
+		if err != nil {
+			return nil, nil, err
+		}
+		header = row
+	}
+	text, err := column(config.TextColumn, header, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	label, err := column(config.LabelColumn, header, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	categoryColumn := -1
+	if config.CategoryColumn != "" {
+		categoryColumn, err = column(config.CategoryColumn, header, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		for i, v := range header {
+			if strings.TrimSpace(v) == "category" {
+				categoryColumn = i
+				break
+			}
+		}
+	}
+
+	fields := text + 1
+	if label >= fields {
+		fields = label + 1
+	}
+	if categoryColumn >= fields {
+		fields = categoryColumn + 1
+	}
+	labels := newLabeler(config)
+	var examples Examples
+	var rowErrors []*RowError
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if parseError, ok := err.(*csv.ParseError); ok {
+			rowErrors = append(rowErrors, &RowError{Line: parseError.Line, Err: parseError.Err})
+			continue
+		} else if err != nil {
+			return examples, rowErrors, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(row) == 1 && strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		if fields > len(row) {
+			rowErrors = append(rowErrors, &RowError{Line: line, Err: fmt.Errorf("expected at least %d fields, got %d", fields, len(row))})
+			continue
+		}
+		attack, err := labels.label(row[label])
+		if err != nil {
+			rowErrors = append(rowErrors, &RowError{Line: line, Err: err})
+			continue
+		}
+		if row[text] == "" {
+			rowErrors = append(rowErrors, &RowError{Line: line, Err: fmt.Errorf("empty text")})
+			continue
+		}
+		example := Example{
+			Data:   []byte(row[text]),
+			Attack: attack,
+			Line:   line,
+		}
+		if categoryColumn >= 0 {
+			example.Category, err = category(row[categoryColumn], attack)
+			if err != nil {
+				rowErrors = append(rowErrors, &RowError{Line: line, Err: err})
+				continue
+			}
+		}
+		examples = append(examples, example)
+	}
+	return examples, rowErrors, nil
+}
+
+func loadJSONL(in io.Reader, config Config) (Examples, []*RowError, error) {
+	text, label := config.TextColumn, config.LabelColumn
+	if text == "" {
+		text = "text"
+	}
+	if label == "" {
+		label = "label"
+	}
+	categoryKey := config.CategoryColumn
+	if categoryKey == "" {
+		categoryKey = "category"
+	}
+
+	labels := newLabeler(config)
+	var examples Examples
+	var rowErrors []*RowError
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var row map[string]interface{}
+		err := json.Unmarshal(scanner.Bytes(), &row)
+		if err != nil {
+			rowErrors = append(rowErrors, &RowError{Line: line, Err: err})
+			continue
+		}
+		value, ok := row[text].(string)
+		if !ok || value == "" {
+			rowErrors = append(rowErrors, &RowError{Line: line, Err: fmt.Errorf("missing text %q", text)})
+			continue
+		}
+		var attack bool
+		switch v := row[label].(type) {
+		case bool:
+			attack = v
+		case string:
+			attack, err = labels.label(v)
+		default:
+			err = fmt.Errorf("missing label %q", label)
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, &RowError{Line: line, Err: err})
+			continue
+		}
+		example := Example{
+			Data:   []byte(value),
+			Attack: attack,
+			Line:   line,
+		}
+		switch v := row[categoryKey].(type) {
+		case nil:
+			if config.CategoryColumn != "" {
+				err = fmt.Errorf("missing category %q", categoryKey)
+			}
+		case string:
+			example.Category, err = category(v, attack)
+		default:
+			err = fmt.Errorf("category %q isn't a string", categoryKey)
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, &RowError{Line: line, Err: err})
+			continue
+		}
+		examples = append(examples, example)
+	}
+	return examples, rowErrors, scanner.Err()
+}
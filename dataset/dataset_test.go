@@ -0,0 +1,156 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dataset
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pointlander/injectsec/data"
+)
+
+func TestLoadFile(t *testing.T) {
+	examples, rowErrors, err := LoadFile("../training_data_example.csv", Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rowErrors) != 0 {
+		t.Fatal(rowErrors)
+	}
+	if len(examples) != 10 {
+		t.Fatal("expected 10 examples", len(examples))
+	}
+	if string(examples[0].Data) != " or 1=1 " || !examples[0].Attack {
+		t.Fatal("invalid first example", examples[0])
+	}
+	if string(examples[1].Data) != "computer" || examples[1].Attack {
+		t.Fatal("invalid second example", examples[1])
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		config   Config
+		examples int
+		lines    []int
+	}{
+		{"header", "label,query\nbad,' or 1=1 --\ngood,hello\ngood,hello\nugly,what\n",
+			Config{Header: true, TextColumn: "query", AttackLabels: []string{"bad"}, NotAttackLabels: []string{"good"}},
+			3, []int{5}},
+		{"tsv", "' or 1=1 --\tattack\nhello\tnot_attack\nmissing\n",
+			Config{Format: FormatTSV}, 2, []int{3}},
+		{"jsonl", `{"text": "' or 1=1 --", "label": true}` + "\n" + `{"text": "hello", "label": "not_attack"}` + "\n" +
+			"{broken\n" + `{"text": "", "label": "attack"}` + "\n",
+			Config{Format: FormatJSONL}, 2, []int{3, 4}},
+		{"category", "text,label,category\n' or 1=1 --,attack,tautology\n1 and sleep(5),attack,\nhello,not_attack,union\nx,attack,unknown\n",
+			Config{Header: true}, 2, []int{4, 5}},
+		{"category column", "' or 1=1 --\tattack\ttautology\nhello\tnot_attack\n",
+			Config{Format: FormatTSV, CategoryColumn: "2"}, 1, []int{2}},
+		{"jsonl category", `{"text": "' or 1=1 --", "label": "attack", "category": "tautology"}` + "\n" +
+			`{"text": "hello", "label": false}` + "\n" + `{"text": "x", "label": true, "category": 1}` + "\n",
+			Config{Format: FormatJSONL}, 2, []int{3}},
+	}
+	for _, test := range tests {
+		examples, rowErrors, err := Load(strings.NewReader(test.input), test.config)
+		if err != nil {
+			t.Fatal(test.name, err)
+		}
+		if strings.Contains(test.name, "category") && examples[0].Category != data.CategoryTautology {
+			t.Fatal(test.name, "expected a tautology, got", examples[0].Category)
+		}
+		for _, example := range examples[1:] {
+			if example.Category != data.CategoryNone {
+				t.Fatal(test.name, "unexpected category", example.Category)
+			}
+		}
+		if len(examples) != test.examples {
+			t.Fatal(test.name, "expected", test.examples, "examples, got", len(examples))
+		}
+		if len(rowErrors) != len(test.lines) {
+			t.Fatal(test.name, "expected row errors on lines", test.lines, "got", rowErrors)
+		}
+		for i, line := range test.lines {
+			if rowErrors[i].Line != line {
+				t.Fatal(test.name, "expected a row error on line", line, "got", rowErrors[i])
+			}
+		}
+	}
+}
+
+func TestDedupeSplit(t *testing.T) {
+	examples := Examples{
+		{Data: []byte("a"), Line: 1},
+		{Data: []byte("b"), Attack: true, Line: 2},
+		{Data: []byte("a"), Line: 3},
+		{Data: []byte("c"), Line: 4},
+		{Data: []byte("e"), Line: 5},
+		{Data: []byte("d"), Line: 6},
+		{Data: []byte("e"), Attack: true, Line: 7},
+	}
+	examples, rowErrors := examples.Dedupe()
+	if len(examples) != 4 || string(examples[3].Data) != "d" {
+		t.Fatal("expected 4 examples without the conflicting text", examples)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Line != 7 {
+		t.Fatal("expected a label conflict on line 7", rowErrors)
+	}
+	training, validation := examples.Split(.75)
+	if len(training) != 3 || len(validation) != 1 {
+		t.Fatal("invalid split", len(training), len(validation))
+	}
+}
+
+func TestPermuteHash(t *testing.T) {
+	examples := func() Examples {
+		var e Examples
+		for i := 0; i < 100; i++ {
+			e = append(e, Example{Data: []byte(strconv.Itoa(i)), Attack: i%2 == 0})
+		}
+		return e
+	}
+	a, b := examples(), examples()
+	a.Permute(rand.New(rand.NewSource(1)))
+	b.Permute(rand.New(rand.NewSource(1)))
+	if a.Hash() != b.Hash() {
+		t.Fatal("permutations with the same seed differ")
+	}
+	if a.Hash() == examples().Hash() {
+		t.Fatal("the examples were not permuted")
+	}
+	b[0].Attack = !b[0].Attack
+	if a.Hash() == b.Hash() {
+		t.Fatal("the hash should include the labels")
+	}
+	b[0].Attack = !b[0].Attack
+	b[0].Category = data.CategoryTautology
+	if a.Hash() == b.Hash() {
+		t.Fatal("the hash should include the categories")
+	}
+}
+
+func TestOversample(t *testing.T) {
+	var examples Examples
+	for i := 0; i < 100; i++ {
+		examples = append(examples, Example{Data: []byte(strconv.Itoa(i)), Attack: i < 10})
+	}
+	oversampled := examples.Oversample(rand.New(rand.NewSource(1)), .5)
+	attacks, notAttacks := oversampled.Counts()
+	if attacks != 45 || notAttacks != 90 {
+		t.Fatal("invalid counts", attacks, notAttacks)
+	}
+	for _, example := range oversampled[100:] {
+		if !example.Attack {
+			t.Fatal("only attacks should be added")
+		}
+	}
+	attacks, notAttacks = examples.Oversample(rand.New(rand.NewSource(1)), 2).Counts()
+	if attacks != 90 || notAttacks != 90 {
+		t.Fatal("the minority class should not outgrow the majority class", attacks, notAttacks)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package eval computes detection metrics for labeled data sets
+package eval
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/pointlander/injectsec/dataset"
+	"github.com/pointlander/injectsec/gru"
+)
+
+// Sample is an example scored by a detector
+type Sample struct {
+	Probability float32
+	// Raw is the probability from the neural network before calibration
+	Raw    float32
+	Attack bool
+	Source gru.Source
+}
+
+// Score runs the examples through a detector
+func Score(detector *gru.Detector, examples dataset.Examples) ([]Sample, error) {
+	samples := make([]Sample, len(examples))
+	for i, example := range examples {
+		result, err := detector.DetectDetailed(string(example.Data))
+		if err != nil {
+			return nil, err
+		}
+		samples[i] = Sample{
+			Probability: result.Probability,
+			Raw:         result.Raw,
+			Attack:      example.Attack,
+			Source:      result.Source,
+		}
+	}
+	return samples, nil
+}
+
+// Fit fits a calibration to the samples decided by the neural network
+func Fit(samples []Sample, method string) (*gru.Calibration, error) {
+	var probabilities []float32
+	var attacks []bool
+	for _, sample := range samples {
+		if sample.Source != gru.SourceGRU {
+			continue
+		}
+		probabilities = append(probabilities, sample.Raw)
+		attacks = append(attacks, sample.Attack)
+	}
+	if len(probabilities) == 0 {
+		return nil, fmt.Errorf("no samples were decided by the neural network")
+	}
+	switch method {
+	case gru.CalibrationPlatt:
+		return gru.FitPlatt(probabilities, attacks), nil
+	case gru.CalibrationIsotonic:
+		return gru.FitIsotonic(probabilities, attacks), nil
+	}
+	return nil, fmt.Errorf("unknown calibration method %q", method)
+}
+
+// Calibrate applies a calibration to the samples decided by the neural network
+func Calibrate(samples []Sample, calibration *gru.Calibration) []Sample {
+	calibrated := make([]Sample, len(samples))
+	for i, sample := range samples {
+		if sample.Source == gru.SourceGRU {
+			sample.Probability = calibration.Apply(sample.Raw)
+		}
+		calibrated[i] = sample
+	}
+	return calibrated
+}
+
+// ThresholdForFPR returns the lowest threshold with a false positive rate of
+// at most fpr, which must be between 0 and 1
+func ThresholdForFPR(samples []Sample, fpr float64) (float32, error) {
+	probabilities, attacks := make([]float32, len(samples)), make([]bool, len(samples))
+	for i, sample := range samples {
+		probabilities[i], attacks[i] = sample.Probability, sample.Attack
+	}
+	return gru.ThresholdForFPR(probabilities, attacks, fpr)
+}
+
+// Confusion is a confusion matrix
+type Confusion struct {
+	TP, FP, TN, FN int
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// Precision is the fraction of detections that are attacks
+func (c Confusion) Precision() float64 {
+	return ratio(c.TP, c.TP+c.FP)
+}
+
+// Recall is the fraction of attacks that are detected
+func (c Confusion) Recall() float64 {
+	return ratio(c.TP, c.TP+c.FN)
+}
+
+// F1 is the harmonic mean of precision and recall
+func (c Confusion) F1() float64 {
+	p, r := c.Precision(), c.Recall()
+	if p+r == 0 {
+		return 0
+	}
+	return 2 * p * r / (p + r)
+}
+
+// FPR is the fraction of non attacks that are detected
+func (c Confusion) FPR() float64 {
+	return ratio(c.FP, c.FP+c.TN)
+}
+
+// Threshold are the metrics at a threshold; a sample is a detection if its
+// probability is greater than the threshold
+type Threshold struct {
+	Threshold float32
+	Confusion
+	Precision float64
+	Recall    float64
+	F1        float64
+	FPR       float64
+}
+
+// Point is a point on a curve
+type Point struct {
+	X, Y      float64
+	Threshold float32
+}
+
+// Report are the metrics for a set of samples
+type Report struct {
+	Samples          int
+	Attacks          int
+	Thresholds       []Threshold
+	ROC              []Point
+	AUC              float64
+	PR               []Point
+	AveragePrecision float64
+	// Sources are the reports for each decision source
+	Sources map[string]*Report `json:",omitempty"`
+}
+
+// Evaluate computes the metrics for the samples at the thresholds
+func Evaluate(samples []Sample, thresholds []float32) *Report {
+	report := evaluate(samples, thresholds)
+	bySource := make(map[gru.Source][]Sample)
+	for _, sample := range samples {
+		bySource[sample.Source] = append(bySource[sample.Source], sample)
+	}
+	report.Sources = make(map[string]*Report, len(bySource))
+	for source, s := range bySource {
+		report.Sources[source.String()] = evaluate(s, thresholds)
+	}
+	return report
+}
+
+func evaluate(samples []Sample, thresholds []float32) *Report {
+	report := &Report{
+		Samples: len(samples),
+	}
+	for _, sample := range samples {
+		if sample.Attack {
+			report.Attacks++
+		}
+	}
+
+	for _, threshold := range thresholds {
+		var c Confusion
+		for _, sample := range samples {
+			detected := sample.Probability > threshold
+			switch {
+			case detected && sample.Attack:
+				c.TP++
+			case detected && !sample.Attack:
+				c.FP++
+			case !detected && sample.Attack:
+				c.FN++
+			default:
+				c.TN++
+			}
+		}
+		report.Thresholds = append(report.Thresholds, Threshold{
+			Threshold: threshold,
+			Confusion: c,
+			Precision: c.Precision(),
+			Recall:    c.Recall(),
+			F1:        c.F1(),
+			FPR:       c.FPR(),
+		})
+	}
+
+	sorted := make([]Sample, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Probability > sorted[j].Probability
+	})
+	positives, negatives := report.Attacks, len(samples)-report.Attacks
+	report.ROC = append(report.ROC, Point{X: 0, Y: 0, Threshold: 100})
+	tp, fp, previousRecall := 0, 0, 0.0
+	for i := 0; i < len(sorted); {
+		threshold := sorted[i].Probability
+		for i < len(sorted) && sorted[i].Probability == threshold {
+			if sorted[i].Attack {
+				tp++
+			} else {
+				fp++
+			}
+			i++
+		}
+		tpr, fpr := ratio(tp, positives), ratio(fp, negatives)
+		last := report.ROC[len(report.ROC)-1]
+		report.AUC += (fpr - last.X) * (tpr + last.Y) / 2
+		report.ROC = append(report.ROC, Point{X: fpr, Y: tpr, Threshold: threshold})
+
+		precision := ratio(tp, tp+fp)
+		report.AveragePrecision += (tpr - previousRecall) * precision
+		previousRecall = tpr
+		report.PR = append(report.PR, Point{X: tpr, Y: precision, Threshold: threshold})
+	}
+	return report
+}
+
+// WriteText writes the report as text
+func (r *Report) WriteText(out io.Writer) error {
+	write := func(name string, r *Report) error {
+		_, err := fmt.Fprintf(out, "%s: %d samples, %d attacks, AUC %.4f, AP %.4f\n",
+			name, r.Samples, r.Attacks, r.AUC, r.AveragePrecision)
+		if err != nil {
+			return err
+		}
+		for _, t := range r.Thresholds {
+			_, err = fmt.Fprintf(out, "  threshold %6.2f: precision %.4f recall %.4f f1 %.4f fpr %.4f [tp %d fp %d tn %d fn %d]\n",
+				t.Threshold, t.Precision, t.Recall, t.F1, t.FPR, t.TP, t.FP, t.TN, t.FN)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := write("all", r)
+	if err != nil {
+		return err
+	}
+	for _, source := range r.sources() {
+		err = write(source, r.Sources[source])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as JSON
+func (r *Report) WriteJSON(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteCSV writes the metrics at each threshold as CSV
+func (r *Report) WriteCSV(out io.Writer) error {
+	writer := csv.NewWriter(out)
+	err := writer.Write([]string{"source", "threshold", "tp", "fp", "tn", "fn",
+		"precision", "recall", "f1", "fpr", "auc"})
+	if err != nil {
+		return err
+	}
+	write := func(name string, r *Report) error {
+		for _, t := range r.Thresholds {
+			err := writer.Write([]string{
+				name,
+				strconv.FormatFloat(float64(t.Threshold), 'f', -1, 32),
+				strconv.Itoa(t.TP),
+				strconv.Itoa(t.FP),
+				strconv.Itoa(t.TN),
+				strconv.Itoa(t.FN),
+				strconv.FormatFloat(t.Precision, 'f', 6, 64),
+				strconv.FormatFloat(t.Recall, 'f', 6, 64),
+				strconv.FormatFloat(t.F1, 'f', 6, 64),
+				strconv.FormatFloat(t.FPR, 'f', 6, 64),
+				strconv.FormatFloat(r.AUC, 'f', 6, 64),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = write("all", r)
+	if err != nil {
+		return err
+	}
+	for _, source := range r.sources() {
+		err = write(source, r.Sources[source])
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (r *Report) sources() []string {
+	sources := make([]string, 0, len(r.Sources))
+	for source := range r.Sources {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
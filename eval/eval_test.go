@@ -0,0 +1,107 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eval
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/pointlander/injectsec/gru"
+)
+
+func TestEvaluate(t *testing.T) {
+	samples := []Sample{
+		{Probability: 100, Attack: true, Source: gru.SourceFilter},
+		{Probability: 90, Attack: true, Source: gru.SourceGRU},
+		{Probability: 60, Attack: false, Source: gru.SourceGRU},
+		{Probability: 40, Attack: true, Source: gru.SourceGRU},
+		{Probability: 10, Attack: false, Source: gru.SourceGRU},
+		{Probability: 0, Attack: false, Source: gru.SourceNotFilter},
+	}
+	report := Evaluate(samples, []float32{50})
+	if report.Samples != 6 || report.Attacks != 3 {
+		t.Fatal("invalid counts", report.Samples, report.Attacks)
+	}
+	c := report.Thresholds[0].Confusion
+	if c.TP != 2 || c.FP != 1 || c.TN != 2 || c.FN != 1 {
+		t.Fatal("invalid confusion matrix", c)
+	}
+	if math.Abs(report.Thresholds[0].Precision-2.0/3) > 1e-9 || math.Abs(report.Thresholds[0].FPR-1.0/3) > 1e-9 {
+		t.Fatal("invalid metrics", report.Thresholds[0])
+	}
+	if math.Abs(report.AUC-8.0/9) > 1e-9 {
+		t.Fatal("invalid AUC", report.AUC)
+	}
+	if len(report.Sources) != 3 || report.Sources["gru"].Samples != 4 {
+		t.Fatal("invalid sources", report.Sources)
+	}
+
+	separated := Evaluate([]Sample{{Probability: 90, Attack: true}, {Probability: 10}}, nil)
+	if separated.AUC != 1 || separated.AveragePrecision != 1 {
+		t.Fatal("perfect separation should have an AUC of 1", separated.AUC, separated.AveragePrecision)
+	}
+
+	buffer := &bytes.Buffer{}
+	err := report.WriteJSON(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	err = json.Unmarshal(buffer.Bytes(), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.AUC != report.AUC {
+		t.Fatal("invalid JSON", decoded.AUC)
+	}
+
+	buffer.Reset()
+	err = report.WriteCSV(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(buffer).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 {
+		t.Fatal("expected a header and 4 rows", len(rows))
+	}
+
+	buffer.Reset()
+	err = report.WriteText(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	samples := []Sample{
+		{Probability: 100, Attack: true, Source: gru.SourceFilter},
+		{Raw: 90, Attack: true, Source: gru.SourceGRU},
+		{Raw: 70, Attack: false, Source: gru.SourceGRU},
+		{Raw: 60, Attack: true, Source: gru.SourceGRU},
+		{Raw: 10, Attack: false, Source: gru.SourceGRU},
+	}
+	calibration, err := Fit(samples, gru.CalibrationIsotonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calibrated := Calibrate(samples, calibration)
+	if calibrated[0].Probability != 100 || calibrated[1].Probability != 100 || calibrated[4].Probability != 0 ||
+		calibrated[3].Probability >= calibrated[2].Probability || calibrated[3].Probability <= 0 {
+		t.Fatal("invalid calibration", calibrated)
+	}
+	if threshold, err := ThresholdForFPR(calibrated, 0); err != nil || threshold != calibrated[2].Probability {
+		t.Fatal("invalid threshold", threshold, err)
+	}
+	_, err = Fit(samples, "unknown")
+	if err == nil {
+		t.Fatal("expected an error for an unknown method")
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	G "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+// DefaultBatchSize is the default number of inputs run through the graph together
+const DefaultBatchSize = 32
+
+// batchRNN is a GRU that runs a batch of inputs through the graph together;
+// the columns of each matrix are the members of the batch
+type batchRNN struct {
+	*Model
+	size int
+
+	inputs   []*tensor.Dense
+	mask     *tensor.Dense
+	previous []*tensor.Dense
+	hiddens  G.Nodes
+	logits   *G.Node
+	machine  G.VM
+}
+
+// newBatchRNN creates a GRU for batches of size inputs
+func newBatchRNN(model *Model, size int) (*batchRNN, error) {
+	g := G.NewGraph()
+	r := &batchRNN{
+		Model: model,
+		size:  size,
+	}
+
+	onesBatch := G.NodeFromAny(g, tensor.Ones(tensor.Float32, size), G.WithName("ones_batch"))
+	bias := func(b *G.Node) (*G.Node, error) {
+		return G.OuterProd(b, onesBatch)
+	}
+
+	r.mask = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(size))
+	mask := G.NewVector(g, G.Float32, G.WithName("mask"), G.WithShape(size), G.WithValue(r.mask))
+
+	we := G.NodeFromAny(g, model.we, G.WithName("we"))
+	be, err := bias(G.NodeFromAny(g, model.be, G.WithName("be")))
+	if err != nil {
+		return nil, err
+	}
+	var x *G.Node
+	for i := 0; i < model.inputs; i++ {
+		input := tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(model.inputSize, size))
+		r.inputs = append(r.inputs, input)
+		node := G.NewMatrix(g, G.Float32, G.WithName("input_"+strconv.Itoa(i)),
+			G.WithShape(model.inputSize, size), G.WithValue(input))
+		embedding := G.Must(G.Add(G.Must(G.Mul(we, node)), be))
+		if x == nil {
+			x = embedding
+		} else {
+			x = G.Must(G.Concat(0, x, embedding))
+		}
+	}
+
+	for i, s := range model.layerSizes {
+		name := strconv.Itoa(i)
+		layer := model.layers[i].NewGRULayer(g, name)
+
+		previous := tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(s, size))
+		r.previous = append(r.previous, previous)
+		h := G.NewMatrix(g, G.Float32, G.WithName("previous_"+name),
+			G.WithShape(s, size), G.WithValue(previous))
+		ones := G.NodeFromAny(g, tensor.Ones(tensor.Float32, s, size), G.WithName("ones_matrix_"+name))
+
+		bf, err := bias(layer.bf)
+		if err != nil {
+			return nil, err
+		}
+		bh, err := bias(layer.bh)
+		if err != nil {
+			return nil, err
+		}
+		f := G.Must(G.Sigmoid(G.Must(G.Add(G.Must(G.Add(G.Must(G.Mul(layer.wf, x)),
+			G.Must(G.Mul(layer.uf, h)))), bf))))
+		z := G.Must(G.Tanh(G.Must(G.Add(G.Must(G.Add(G.Must(G.Mul(layer.wh, x)),
+			G.Must(G.Mul(layer.uh, G.Must(G.HadamardProd(f, h)))))), bh))))
+		hidden := G.Must(G.Add(G.Must(G.HadamardProd(G.Must(G.Sub(ones, f)), z)),
+			G.Must(G.HadamardProd(f, h))))
+
+		// members of the batch past the end of their input keep their hidden state
+		m := G.Must(G.OuterProd(G.NodeFromAny(g, tensor.Ones(tensor.Float32, s), G.WithName("ones_"+name)), mask))
+		hidden = G.Must(G.Add(G.Must(G.HadamardProd(m, hidden)),
+			G.Must(G.HadamardProd(G.Must(G.Sub(ones, m)), h))))
+		r.hiddens = append(r.hiddens, hidden)
+		x = hidden
+	}
+
+	bo, err := bias(G.NodeFromAny(g, model.bo, G.WithName("bo")))
+	if err != nil {
+		return nil, err
+	}
+	r.logits = G.Must(G.Add(G.Must(G.Mul(G.NodeFromAny(g, model.wo, G.WithName("wo")), x)), bo))
+	r.machine = G.NewTapeMachine(g)
+	return r, nil
+}
+
+// attackProbabilities returns the probability each input is an attack
+func (r *batchRNN) attackProbabilities(inputs [][]int) ([]float32, error) {
+	if len(inputs) > r.size {
+		return nil, fmt.Errorf("batch of %d is larger than %d", len(inputs), r.size)
+	}
+	length := 0
+	for _, input := range inputs {
+		if len(input) > length {
+			length = len(input)
+		}
+	}
+
+	for _, previous := range r.previous {
+		previous.Zero()
+	}
+	for i := 0; i < length; i++ {
+		for _, input := range r.inputs {
+			input.Zero()
+		}
+		r.mask.Zero()
+		for j, input := range inputs {
+			if i >= len(input) {
+				continue
+			}
+			end := len(input) - 1
+			r.mask.SetF32(j, 1)
+			r.inputs[0].SetF32(input[i]*r.size+j, 1)
+			if len(r.inputs) > 1 {
+				r.inputs[1].SetF32(input[end-i]*r.size+j, 1)
+			}
+		}
+		err := r.machine.RunAll()
+		if err != nil {
+			r.machine.Reset()
+			return nil, err
+		}
+		for j, hidden := range r.hiddens {
+			err = hidden.Value().(*tensor.Dense).CopyTo(r.previous[j])
+			if err != nil {
+				return nil, err
+			}
+		}
+		if i < length-1 {
+			r.machine.Reset()
+		}
+	}
+
+	probabilities := make([]float32, len(inputs))
+	if length == 0 {
+		return probabilities, nil
+	}
+	defer r.machine.Reset()
+	logits, ok := r.logits.Value().(*tensor.Dense)
+	if !ok {
+		return nil, fmt.Errorf("not a tensor")
+	}
+	values := logits.Data().([]float32)
+	for j := range inputs {
+		max := float32(math.Inf(-1))
+		for k := 0; k < r.outputSize; k++ {
+			if v := values[k*r.size+j]; v > max {
+				max = v
+			}
+		}
+		sum, attack := 0.0, 0.0
+		for k := 0; k < r.outputSize; k++ {
+			e := math.Exp(float64(values[k*r.size+j] - max))
+			if k == 0 {
+				attack = e
+			}
+			sum += e
+		}
+		probabilities[j] = float32(100 * attack / sum)
+	}
+	return probabilities, nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	// CalibrationPlatt is Platt scaling
+	CalibrationPlatt = "platt"
+	// CalibrationIsotonic is isotonic regression
+	CalibrationIsotonic = "isotonic"
+)
+
+// Calibration maps the attack probabilities of the neural network, which
+// are in percent, to calibrated probabilities
+type Calibration struct {
+	Method string
+	// A and B are the Platt scaling parameters: 1/(1+exp(A*logit(p)+B))
+	A, B float64
+	// X and Y are the points of the isotonic regression, X is increasing
+	X, Y []float64
+}
+
+// logit is the log odds of a probability in percent
+func logit(probability float32) float64 {
+	p := float64(probability) / 100
+	const epsilon = 1e-7
+	if p < epsilon {
+		p = epsilon
+	} else if p > 1-epsilon {
+		p = 1 - epsilon
+	}
+	return math.Log(p / (1 - p))
+}
+
+// FitPlatt fits Platt scaling to probabilities in percent and their labels
+func FitPlatt(probabilities []float32, attacks []bool) *Calibration {
+	positives, negatives := 0.0, 0.0
+	for _, attack := range attacks {
+		if attack {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	// regularized targets from Platt's paper
+	high, low := (positives+1)/(positives+2), 1/(negatives+2)
+	x, t := make([]float64, len(probabilities)), make([]float64, len(probabilities))
+	for i, probability := range probabilities {
+		x[i] = logit(probability)
+		if attacks[i] {
+			t[i] = high
+		} else {
+			t[i] = low
+		}
+	}
+
+	loss := func(a, b float64) float64 {
+		sum := 0.0
+		for i := range x {
+			f := a*x[i] + b
+			if f >= 0 {
+				sum += t[i]*f + math.Log(1+math.Exp(-f))
+			} else {
+				sum += (t[i]-1)*f + math.Log(1+math.Exp(f))
+			}
+		}
+		return sum
+	}
+
+	// Newton's method with backtracking, see Lin, Lin and Weng 2007
+	a, b := 0.0, math.Log((negatives+1)/(positives+1))
+	current := loss(a, b)
+	for iteration := 0; iteration < 100; iteration++ {
+		h11, h22, h21, g1, g2 := 1e-12, 1e-12, 0.0, 0.0, 0.0
+		for i := range x {
+			f := a*x[i] + b
+			var p, q float64
+			if f >= 0 {
+				p = math.Exp(-f) / (1 + math.Exp(-f))
+				q = 1 / (1 + math.Exp(-f))
+			} else {
+				p = 1 / (1 + math.Exp(f))
+				q = math.Exp(f) / (1 + math.Exp(f))
+			}
+			d2 := p * q
+			h11 += x[i] * x[i] * d2
+			h22 += d2
+			h21 += x[i] * d2
+			d1 := t[i] - p
+			g1 += x[i] * d1
+			g2 += d1
+		}
+		if math.Abs(g1) < 1e-5 && math.Abs(g2) < 1e-5 {
+			break
+		}
+		det := h11*h22 - h21*h21
+		da, db := -(h22*g1-h21*g2)/det, -(-h21*g1+h11*g2)/det
+		gd := g1*da + g2*db
+		step := 1.0
+		for step >= 1e-10 {
+			next := loss(a+step*da, b+step*db)
+			if next < current+1e-4*step*gd {
+				a, b, current = a+step*da, b+step*db, next
+				break
+			}
+			step /= 2
+		}
+		if step < 1e-10 {
+			break
+		}
+	}
+
+	return &Calibration{
+		Method: CalibrationPlatt,
+		A:      a,
+		B:      b,
+	}
+}
+
+// FitIsotonic fits isotonic regression to probabilities in percent and their labels
+func FitIsotonic(probabilities []float32, attacks []bool) *Calibration {
+	type block struct {
+		x, y, weight float64
+	}
+	indexes := make([]int, len(probabilities))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return probabilities[indexes[i]] < probabilities[indexes[j]]
+	})
+
+	// pool adjacent violators
+	blocks := make([]block, 0, len(indexes))
+	for _, i := range indexes {
+		y := 0.0
+		if attacks[i] {
+			y = 1
+		}
+		blocks = append(blocks, block{x: float64(probabilities[i]), y: y, weight: 1})
+		for len(blocks) > 1 {
+			last, previous := blocks[len(blocks)-1], blocks[len(blocks)-2]
+			if previous.y < last.y && previous.x != last.x {
+				break
+			}
+			weight := previous.weight + last.weight
+			blocks = blocks[:len(blocks)-2]
+			blocks = append(blocks, block{
+				x:      (previous.x*previous.weight + last.x*last.weight) / weight,
+				y:      (previous.y*previous.weight + last.y*last.weight) / weight,
+				weight: weight,
+			})
+		}
+	}
+
+	calibration := &Calibration{
+		Method: CalibrationIsotonic,
+	}
+	for _, b := range blocks {
+		calibration.X = append(calibration.X, b.x)
+		calibration.Y = append(calibration.Y, 100*b.y)
+	}
+	return calibration
+}
+
+// Apply calibrates a probability in percent
+func (c *Calibration) Apply(probability float32) float32 {
+	if c == nil {
+		return probability
+	}
+	switch c.Method {
+	case CalibrationPlatt:
+		return float32(100 / (1 + math.Exp(c.A*logit(probability)+c.B)))
+	case CalibrationIsotonic:
+		x, n := float64(probability), len(c.X)
+		if n == 0 {
+			return probability
+		}
+		if x <= c.X[0] {
+			return float32(c.Y[0])
+		} else if x >= c.X[n-1] {
+			return float32(c.Y[n-1])
+		}
+		i := sort.SearchFloat64s(c.X, x)
+		x0, x1, y0, y1 := c.X[i-1], c.X[i], c.Y[i-1], c.Y[i]
+		return float32(y0 + (y1-y0)*(x-x0)/(x1-x0))
+	}
+	return probability
+}
+
+// ThresholdForFPR returns the lowest threshold for which the fraction of non
+// attacks with a probability greater than the threshold is at most fpr; fpr
+// must be between 0 and 1, and there must be a label for each probability
+func ThresholdForFPR(probabilities []float32, attacks []bool, fpr float64) (float32, error) {
+	if !(fpr >= 0 && fpr <= 1) {
+		return 0, fmt.Errorf("false positive rate %v is not between 0 and 1", fpr)
+	}
+	if len(probabilities) != len(attacks) {
+		return 0, fmt.Errorf("%d probabilities but %d labels", len(probabilities), len(attacks))
+	}
+	var negatives []float32
+	for i, probability := range probabilities {
+		if !attacks[i] {
+			negatives = append(negatives, probability)
+		}
+	}
+	if len(negatives) == 0 {
+		return 0, nil
+	}
+	sort.Slice(negatives, func(i, j int) bool {
+		return negatives[i] > negatives[j]
+	})
+	allowed := int(math.Floor(fpr * float64(len(negatives))))
+	if allowed >= len(negatives) {
+		return 0, nil
+	}
+	// ties with the threshold are not detections
+	return negatives[allowed], nil
+}
+
+// Calibration returns the calibration of the model or nil
+func (m *Model) Calibration() *Calibration {
+	return m.calibration
+}
+
+// SetCalibration sets the calibration that is applied by detectors of the
+// model and stored with its weights
+func (m *Model) SetCalibration(calibration *Calibration) {
+	m.calibration = calibration
+}
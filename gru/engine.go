@@ -0,0 +1,182 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"fmt"
+	"math"
+)
+
+// Engine is a pure Go implementation of the GRU forward pass that reads the
+// model weights directly; it doesn't build a graph, keeps no state between
+// calls, and is safe for concurrent use
+type Engine struct {
+	*Model
+}
+
+// NewEngine creates a new inference engine for a model
+func NewEngine(model *Model) *Engine {
+	return &Engine{
+		Model: model,
+	}
+}
+
+// Engine creates a new inference engine that shares the model of the detector maker
+func (d *DetectorMaker) Engine() *Engine {
+	return NewEngine(d.Model)
+}
+
+// MakeEngine makes a detector that uses the pure Go engine instead of a graph;
+// Detect and DetectDetailed of the detector are safe for concurrent use
+func (d *DetectorMaker) MakeEngine() *Detector {
+	return &Detector{
+		Engine: d.Engine(),
+	}
+}
+
+// mulAdd computes out += w x, where w is a rows by len(x) matrix
+func mulAdd(out, w, x []float32) {
+	cols := len(x)
+	for i := range out {
+		row, sum := w[i*cols:(i+1)*cols], float32(0)
+		for j, v := range row {
+			sum += v * x[j]
+		}
+		out[i] += sum
+	}
+}
+
+func sigmoid(x float32) float32 {
+	return float32(1 / (1 + math.Exp(-float64(x))))
+}
+
+func tanh(x float32) float32 {
+	return float32(math.Tanh(float64(x)))
+}
+
+// newHiddens creates zeroed hidden states for each layer
+func (e *Engine) newHiddens() [][]float32 {
+	hiddens := make([][]float32, len(e.layerSizes))
+	for i, size := range e.layerSizes {
+		hiddens[i] = make([]float32, size)
+	}
+	return hiddens
+}
+
+// step runs one step of the recurrence with a token for each input
+func (e *Engine) step(hiddens [][]float32, tokens ...int) error {
+	x := make([]float32, e.inputs*e.embeddingSize)
+	we, be := e.we.Data().([]float32), e.be.Data().([]float32)
+	for i, token := range tokens {
+		if token < 0 || token >= e.inputSize {
+			return fmt.Errorf("token %d is out of range", token)
+		}
+		embedding := x[i*e.embeddingSize : (i+1)*e.embeddingSize]
+		for j := range embedding {
+			embedding[j] = we[j*e.inputSize+token] + be[j]
+		}
+	}
+
+	for i, l := range e.layers {
+		previous, size := hiddens[i], e.layerSizes[i]
+		f := make([]float32, size)
+		copy(f, l.bf.Data().([]float32))
+		mulAdd(f, l.wf.Data().([]float32), x)
+		mulAdd(f, l.uf.Data().([]float32), previous)
+		reset := make([]float32, size)
+		for j := range f {
+			f[j] = sigmoid(f[j])
+			reset[j] = f[j] * previous[j]
+		}
+
+		z := make([]float32, size)
+		copy(z, l.bh.Data().([]float32))
+		mulAdd(z, l.wh.Data().([]float32), x)
+		mulAdd(z, l.uh.Data().([]float32), reset)
+
+		hidden := make([]float32, size)
+		for j := range hidden {
+			hidden[j] = (1-f[j])*tanh(z[j]) + f[j]*previous[j]
+		}
+		hiddens[i], x = hidden, hidden
+	}
+	return nil
+}
+
+// softmax computes the softmax of the layer with weights w and biases b for
+// the last hidden state
+func (e *Engine) softmax(w, b []float32, hiddens [][]float32) []float32 {
+	output := make([]float32, len(b))
+	copy(output, b)
+	mulAdd(output, w, hiddens[len(hiddens)-1])
+
+	max := float32(math.Inf(-1))
+	for _, v := range output {
+		if v > max {
+			max = v
+		}
+	}
+	sum := float32(0)
+	for i, v := range output {
+		output[i] = float32(math.Exp(float64(v - max)))
+		sum += output[i]
+	}
+	for i := range output {
+		output[i] /= sum
+	}
+	return output
+}
+
+// Probabilities returns the output probabilities for the input
+func (e *Engine) Probabilities(input []int) ([]float32, error) {
+	probabilities, _, err := e.Outputs(input)
+	return probabilities, err
+}
+
+// Outputs returns the output probabilities and the category probabilities
+// for the input; categories is nil if the model doesn't have a category head
+func (e *Engine) Outputs(input []int) (probabilities, categories []float32, err error) {
+	hiddens, end := e.newHiddens(), len(input)-1
+	tokens := make([]int, e.inputs)
+	for i := range input {
+		tokens[0] = input[i]
+		if e.inputs > 1 {
+			tokens[1] = input[end-i]
+		}
+		err = e.step(hiddens, tokens...)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	probabilities = e.softmax(e.wo.Data().([]float32), e.bo.Data().([]float32), hiddens)
+	if e.categories > 0 {
+		categories = e.softmax(e.wc.Data().([]float32), e.bc.Data().([]float32), hiddens)
+	}
+	return probabilities, categories, nil
+}
+
+// AttackProbability return the probability the input is an attack
+func (e *Engine) AttackProbability(input []int) (float32, error) {
+	probabilities, err := e.Probabilities(input)
+	if err != nil {
+		return 0, err
+	}
+	return 100 * probabilities[0], nil
+}
+
+// IsAttack determines if an input is an attack
+func (e *Engine) IsAttack(input []int) bool {
+	probabilities, err := e.Probabilities(input)
+	if err != nil {
+		panic(err)
+	}
+	max := 0
+	for i, v := range probabilities {
+		if v > probabilities[max] {
+			max = i
+		}
+	}
+	return max == 0
+}
@@ -0,0 +1,147 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// highlight is the fraction of the largest saliency a token needs to be highlighted
+const highlight = .25
+
+// Attribution is the saliency of a token of the normalized input. The span
+// of the token is mapped back through the normalizers and lowercasing, so it
+// is a byte span of the original input
+type Attribution struct {
+	// Start and End are the byte span of the token in the original input
+	Start int
+	End   int
+	// Text is the text of the original input the token came from
+	Text string
+	// Token is the token the text was converted to
+	Token int
+	// Saliency is how much the token raises the attack probability of the
+	// neural network; it is negative if the token lowers it
+	Saliency float32
+}
+
+// Explanation is a detection result with the saliency of each token
+type Explanation struct {
+	Result
+	// Input is the original input the spans are into
+	Input string
+	// Network is the attack probability of the neural network for the whole
+	// input before calibration; it is computed even if a filter decided the result
+	Network float32
+	// Attributions are the saliencies of the tokens in order
+	Attributions []Attribution
+}
+
+// Explain detects an attack and explains which tokens of the input drove the
+// decision of the neural network. The saliency of a token is found by
+// occlusion: the token is replaced with the token of a space, and the drop of
+// the attack probability is its saliency. The spans are byte offsets into the
+// original input
+func (d *Detector) Explain(a string) (Explanation, error) {
+	result, err := d.DetectDetailed(a)
+	explanation := Explanation{
+		Result: result,
+		Input:  a,
+	}
+	if err != nil || result.Normalized == "" {
+		return explanation, err
+	}
+
+	normalized, offsets := normalizeOffsets(a, d.Normalizers...)
+	lower, lowered := runeOffsets(normalized, strings.ToLower)
+	offsets = composeOffsets(offsets, lowered)
+	t := d.tokenizer()
+	tokens, starts := tokenize(t, []byte(lower))
+	explanation.Network, _, err = d.attackProbability(tokens)
+	if err != nil {
+		return explanation, err
+	}
+
+	space, _ := t.Match([]byte{' '}, 0)
+	occluded := make([]int, len(tokens))
+	for i, token := range tokens {
+		end := len(lower)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		copy(occluded, tokens)
+		occluded[i] = space
+		probability, _, err := d.attackProbability(occluded)
+		if err != nil {
+			return explanation, err
+		}
+		start, end := offsets[starts[i]], offsets[end]
+		explanation.Attributions = append(explanation.Attributions, Attribution{
+			Start:    start,
+			End:      end,
+			Text:     a[start:end],
+			Token:    token,
+			Saliency: explanation.Network - probability,
+		})
+	}
+	return explanation, nil
+}
+
+// Highlighted returns the original input with the most salient tokens in
+// brackets; a token is highlighted if it raises the attack probability by at
+// least a quarter of the largest saliency
+func (e Explanation) Highlighted() string {
+	max := float32(0)
+	for _, a := range e.Attributions {
+		if a.Saliency > max {
+			max = a.Saliency
+		}
+	}
+	var out strings.Builder
+	open, end := false, 0
+	for _, a := range e.Attributions {
+		out.WriteString(e.Input[end:a.Start])
+		salient := max > 0 && a.Saliency >= highlight*max
+		if salient && !open {
+			out.WriteByte('[')
+		} else if !salient && open {
+			out.WriteByte(']')
+		}
+		open, end = salient, a.End
+		out.WriteString(a.Text)
+	}
+	if open {
+		out.WriteByte(']')
+	}
+	out.WriteString(e.Input[end:])
+	return out.String()
+}
+
+// WriteText writes the explanation as text: the result, the highlighted input,
+// and the saliency of each token
+func (e Explanation) WriteText(out io.Writer) error {
+	_, err := fmt.Fprintf(out, "probability %.2f source %s network %.2f\n%s\n",
+		e.Probability, e.Source, e.Network, e.Highlighted())
+	if err != nil {
+		return err
+	}
+	for _, a := range e.Attributions {
+		_, err = fmt.Fprintf(out, "  %+8.3f [%d:%d] %q\n", a.Saliency, a.Start, a.End, a.Text)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the explanation as JSON
+func (e Explanation) WriteJSON(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(e)
+}
@@ -2,12 +2,13 @@ package gru
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
 	"regexp"
-	"sort"
 	"strings"
 
 	"github.com/pointlander/injectsec/data"
+	"github.com/pointlander/injectsec/lexer"
 	G "gorgonia.org/gorgonia"
 )
 
@@ -17,7 +18,8 @@ const (
 	hiddenSize    = 5
 )
 
-// Chunks are SQL chunks
+// Chunks are the SQL chunks of DefaultTokenizer; they are sorted by init like
+// the chunks of a ChunkTokenizer, so chunk i is token 256+i
 var Chunks = []string{
 	"0x",
 	"/*",
@@ -70,24 +72,22 @@ var Chunks = []string{
 	"version",
 }
 
-func init() {
-	sort.Slice(Chunks, func(i, j int) bool {
-		a, b := Chunks[i], Chunks[j]
-		if la, lb := len(a), len(b); la > lb {
-			return true
-		} else if la == lb {
-			return a < b
-		}
-		return false
-	})
+var filter, notFilter *regexp.Regexp
+
+// matcher is the regex for a single generator
+type matcher struct {
+	index    int
+	form     string
+	category data.Category
+	regex    *regexp.Regexp
 }
 
-var filter, notFilter *regexp.Regexp
+var matchers []matcher
 
 func init() {
 	rnd := rand.New(rand.NewSource(1))
 	generators, expression, sep := data.TrainingDataGenerator(rnd), "", "("
-	for _, generator := range generators {
+	for i, generator := range generators {
 		if generator.SkipMatch {
 			continue
 		}
@@ -100,6 +100,12 @@ func init() {
 			}
 			expression += sep + exp + ")"
 			sep = "|("
+			matchers = append(matchers, matcher{
+				index:    i,
+				form:     generator.Form,
+				category: generator.Category,
+				regex:    regexp.MustCompile("^(" + exp + ")$"),
+			})
 		}
 	}
 	filter = regexp.MustCompile("^(" + expression + ")$")
@@ -111,19 +117,36 @@ type GRU struct {
 	*Model
 	learner   []*RNN
 	inference *RNN
-	solver    G.Solver
+	solver    *RMSProp
 	steps     int
+	// weights are the class weights of the cost for attacks and non attacks
+	weights     [2]float32
+	accumulator accumulator
 }
 
 // NewGRU creates a new GRU anomaly detection engine
 func NewGRU(rnd *rand.Rand) *GRU {
-	steps := 3
-	inputSize := 256 + len(Chunks)
-	embeddingSize := embeddingSize
-	outputSize := outputSize
-	hiddenSizes := []int{hiddenSize}
-	gru := NewModel(rnd, 2, inputSize, embeddingSize, outputSize, hiddenSizes)
+	return NewGRUWithOptions(rnd, DefaultOptions())
+}
+
+// NewGRUWithOptions creates a new GRU anomaly detection engine with options
+func NewGRUWithOptions(rnd *rand.Rand, options Options) *GRU {
+	err := options.Validate()
+	if err != nil {
+		panic(err)
+	}
+	tokenizer := options.tokenizer()
+	gru := newModel(rnd, options.Inputs(), tokenizer, options.EmbeddingSize, outputSize, options.HiddenSizes)
+	gru.options = options
+	gru.addCategories(rnd, options.Categories)
+	return NewGRUFromModel(gru)
+}
 
+// NewGRUFromModel creates a new GRU anomaly detection engine that continues
+// training an existing model with the options of the model
+func NewGRUFromModel(gru *Model) *GRU {
+	options := gru.options
+	steps := options.Steps
 	learner := make([]*RNN, steps)
 	for i := range learner {
 		learner[i] = NewRNN(gru)
@@ -139,56 +162,111 @@ func NewGRU(rnd *rand.Rand) *GRU {
 		panic(err)
 	}
 
-	learnrate := 0.001
-	l2reg := 0.000001
-	clipVal := 5.0
-	solver := G.NewRMSPropSolver(G.WithLearnRate(learnrate), G.WithL2Reg(l2reg), G.WithClip(clipVal))
-
 	return &GRU{
 		Model:     gru,
 		learner:   learner,
 		inference: inference,
-		solver:    solver,
+		solver:    NewRMSProp(options),
 		steps:     steps,
+		weights:   [2]float32{1, 1},
 	}
 }
 
-func convert(input []byte) []int {
-	length, i := len(input), 0
-	data := make([]int, 0, length)
-conversion:
-	for i < length {
-	search:
-		for j, v := range Chunks {
-			chunk := []byte(v)
-			for k, c := range chunk {
-				index := i + k
-				if index >= len(input) {
-					continue search
-				}
-				if c != input[index] {
-					continue search
-				}
+// Train trains the GRU
+func (g *GRU) Train(input []byte, attack bool) float32 {
+	return g.TrainCategory(input, attack, data.CategoryNone)
+}
+
+// TrainCategory trains the GRU and its category head on the category of an
+// attack; the head isn't trained for data.CategoryNone
+func (g *GRU) TrainCategory(input []byte, attack bool, category data.Category) float32 {
+	return train(g.learner, input, attack, category, g.ClassWeight(attack), g.solver)
+}
+
+// TrainBatch trains the GRU on a mini batch of examples; the gradient of each
+// example is averaged over its unrolled windows, the gradients of the
+// examples are averaged over the batch, and the solver takes one step, so
+// long inputs don't outweigh short ones. Empty inputs are skipped. It
+// returns the average cost of the examples
+func (g *GRU) TrainBatch(inputs [][]byte, attacks []bool) float32 {
+	return g.TrainBatchCategories(inputs, attacks, nil)
+}
+
+// TrainBatchCategories is TrainBatch that also trains the category head on
+// the categories of the attacks; categories may be nil
+func (g *GRU) TrainBatchCategories(inputs [][]byte, attacks []bool, categories []data.Category) float32 {
+	if len(inputs) == 0 {
+		return 0
+	}
+	g.accumulator.reset()
+	total := float32(0)
+	for i, input := range inputs {
+		total += train(g.learner, input, attacks[i], category(categories, i), g.ClassWeight(attacks[i]), &g.accumulator)
+		g.accumulator.finish()
+	}
+	examples := g.accumulator.examples
+	if examples == 0 {
+		return 0
+	}
+	g.step(g.accumulator.grads, examples)
+	return total / float32(examples)
+}
+
+// step applies the sum of the mean gradients of examples examples
+func (g *GRU) step(grads [][]float32, examples int) {
+	var weights [][]float32
+	for _, t := range g.Model.tensors() {
+		weights = append(weights, t.Data().([]float32))
+	}
+	if examples > 0 {
+		scale := 1 / float32(examples)
+		for _, grad := range grads {
+			for i := range grad {
+				grad[i] *= scale
 			}
-			data = append(data, 256+j)
-			i += len(chunk)
-			continue conversion
 		}
-		data = append(data, int(input[i]))
-		i++
 	}
+	err := g.solver.apply(weights, grads)
+	if err != nil {
+		panic(err)
+	}
+}
 
-	return data
+// SetClassWeights sets the weights of the cost for attacks and non attacks;
+// both are one by default
+func (g *GRU) SetClassWeights(attack, notAttack float32) {
+	g.weights = [2]float32{attack, notAttack}
 }
 
-// Train trains the GRU
-func (g *GRU) Train(input []byte, attack bool) float32 {
-	data := convert(input)
-	learner := g.learner[len(g.learner)-1]
-	if len(data) < len(g.learner) {
-		learner = g.learner[len(data)-1]
+// ClassWeight returns the weight of the cost for a class
+func (g *GRU) ClassWeight(attack bool) float32 {
+	if attack {
+		return g.weights[0]
+	}
+	return g.weights[1]
+}
+
+// category returns the category of example i or data.CategoryNone
+func category(categories []data.Category, i int) data.Category {
+	if categories == nil {
+		return data.CategoryNone
+	}
+	return categories[i]
+}
+
+// train runs the input through the learner for its length and steps the solver
+// after each unrolled window; it returns the average cost. Empty inputs are
+// skipped and cost nothing
+func train(learners []*RNN, input []byte, attack bool, category data.Category, weight float32, solver G.Solver) float32 {
+	tokens := convert(learners[0].tokenizer, input)
+	if len(tokens) == 0 {
+		return 0
 	}
-	cost, _, err := learner.Learn(data, attack, g.solver)
+	learner := learners[len(learners)-1]
+	if len(tokens) < len(learners) {
+		learner = learners[len(tokens)-1]
+	}
+	cost, _, err := learner.LearnCategory(tokens, attack, category, weight, solver)
 	if err != nil {
 		panic(fmt.Sprintf("%+v", err))
 	}
@@ -202,10 +280,37 @@ func (g *GRU) Train(input []byte, attack bool) float32 {
 
 // Test tests a string
 func (g *GRU) Test(input []byte) bool {
-	data := convert(input)
+	data := convert(g.tokenizer, input)
 	return g.inference.IsAttack(data)
 }
 
+// Loss returns the cross entropy of the model for a labeled input
+func (g *GRU) Loss(input []byte, attack bool) float32 {
+	probabilities, err := NewEngine(g.Model).Probabilities(convert(g.tokenizer, input))
+	if err != nil {
+		panic(err)
+	}
+	probability := probabilities[1]
+	if attack {
+		probability = probabilities[0]
+	}
+	if probability < 1e-7 {
+		probability = 1e-7
+	}
+	return -float32(math.Log(float64(probability)))
+}
+
+// Solver returns the solver of the GRU; its state can be saved and restored
+// to resume training
+func (g *GRU) Solver() *RMSProp {
+	return g.solver
+}
+
+// SetSolver replaces the solver of the GRU
+func (g *GRU) SetSolver(solver *RMSProp) {
+	g.solver = solver
+}
+
 // DetectorMaker makes SQL injection attack detectors
 type DetectorMaker struct {
 	*Model
@@ -213,12 +318,16 @@ type DetectorMaker struct {
 
 // NewDetectorMaker creates a new detector maker
 func NewDetectorMaker() *DetectorMaker {
-	inputSize := 256 + len(Chunks)
-	embeddingSize := embeddingSize
-	outputSize := outputSize
-	hiddenSizes := []int{hiddenSize}
+	return NewDetectorMakerWithOptions(DefaultOptions())
+}
+
+// NewDetectorMakerWithOptions creates a new detector maker with options
+func NewDetectorMakerWithOptions(options Options) *DetectorMaker {
+	tokenizer := options.tokenizer()
 	rnd := rand.New(rand.NewSource(1))
-	gru := NewModel(rnd, 2, inputSize, embeddingSize, outputSize, hiddenSizes)
+	gru := newModel(rnd, options.Inputs(), tokenizer, options.EmbeddingSize, outputSize, options.HiddenSizes)
+	gru.options = options
+	gru.addCategories(rnd, options.Categories)
 	return &DetectorMaker{
 		Model: gru,
 	}
@@ -228,6 +337,17 @@ func NewDetectorMaker() *DetectorMaker {
 type Detector struct {
 	*RNN
 	SkipRegex bool
+	// Normalizers are applied to the input before detection, see DefaultNormalizers
+	Normalizers []Normalizer
+	// BatchSize is the batch size used by DetectBatch, see DefaultBatchSize
+	BatchSize int
+	// Engine is used instead of the graph if it is set, see MakeEngine
+	Engine *Engine
+	// Rules are applied before the regex filters and the neural network if
+	// they are set; they can be shared by detectors and replaced while in use
+	Rules *Rules
+
+	batch *batchRNN
 }
 
 // Make makes a new detector
@@ -242,22 +362,280 @@ func (d *DetectorMaker) Make() *Detector {
 	}
 }
 
+// Source is what decided the result of a detection
+type Source int
+
+const (
+	// SourceEmpty means the input was empty
+	SourceEmpty Source = iota
+	// SourceNotFilter means the input is only letters or only numbers
+	SourceNotFilter
+	// SourceFilter means the input matched a generator regex
+	SourceFilter
+	// SourceGRU means the neural network was run
+	SourceGRU
+	// SourceRule means an allow or deny rule matched, see Rules
+	SourceRule
+)
+
+// String returns the name of the source
+func (s Source) String() string {
+	switch s {
+	case SourceEmpty:
+		return "empty"
+	case SourceNotFilter:
+		return "notfilter"
+	case SourceFilter:
+		return "filter"
+	case SourceGRU:
+		return "gru"
+	case SourceRule:
+		return "rule"
+	}
+	return "unknown"
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s Source) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Result is the result of a detection
+type Result struct {
+	// Probability is the probability the input is an attack
+	Probability float32
+	// Raw is the probability from the neural network before calibration
+	Raw float32
+	// Source is what decided the result
+	Source Source
+	// Generator is the index of the generator that matched or -1
+	Generator int
+	// Form is the form of the generator that matched
+	Form string
+	// Normalized is the input after normalization
+	Normalized string
+	// Tokens are the tokens the input was converted to
+	Tokens []int
+	// Escaped is true if the input escapes the context given to DetectContext;
+	// the probability of an input that doesn't escape it is lowered
+	Escaped bool
+	// Categories are the probabilities of the attack categories given the
+	// input is an attack, indexed by data.Category and data.Categories long;
+	// they are nil if the model doesn't have a category head, and one hot if
+	// a generator matched
+	Categories []float32
+	// Rules are the rules that matched the input
+	Rules []RuleMatch
+}
+
+// Category returns the most probable attack category, or data.CategoryNone
+// if there aren't any category probabilities
+func (r Result) Category() data.Category {
+	category, max := data.CategoryNone, float32(0)
+	for i, probability := range r.Categories {
+		if probability > max {
+			category, max = data.Category(i), probability
+		}
+	}
+	return category
+}
+
+// byCategory converts the output of a category head to the probabilities
+// indexed by data.Category; categories past the end of the head are zero
+func byCategory(head []float32) []float32 {
+	if head == nil {
+		return nil
+	}
+	categories := make([]float32, data.Categories)
+	copy(categories[1:], head)
+	return categories
+}
+
 // Detect returns true if the input is a SQL injection attack
 func (d *Detector) Detect(a string) (float32, error) {
+	result, err := d.DetectDetailed(a)
+	return result.Probability, err
+}
+
+// DetectDetailed returns the probability the input is a SQL injection attack and why
+func (d *Detector) DetectDetailed(a string) (Result, error) {
+	result, done := d.prefilter(a)
+	if !done {
+		probability, categories, err := d.attackProbability(result.Tokens)
+		if err != nil {
+			return result, err
+		}
+		result.Raw, result.Source = probability, SourceGRU
+		result.Categories = byCategory(categories)
+		result.Probability = d.calibration().Apply(probability)
+	}
+	result.adjust()
+	return result, nil
+}
+
+// contextDiscount scales the probability of an input that doesn't escape the
+// context given to DetectContext
+const contextDiscount = 0.5
+
+// DetectContext returns the probability the input is a SQL injection attack
+// given where it is interpolated into the query. The input is always run
+// through the detector; the normalized input is placed in the context, and if
+// it doesn't escape the context the probability from the regex filters or the
+// neural network is lowered. Rules aren't affected by the context
+func (d *Detector) DetectContext(a string, hint lexer.Context) (Result, error) {
+	result, err := d.DetectDetailed(a)
+	if err != nil || hint == lexer.ContextNone {
+		return result, err
+	}
+	result.Escaped = d.lexer().Escapes([]byte(strings.ToLower(result.Normalized)), hint)
+	if !result.Escaped && (result.Source == SourceFilter || result.Source == SourceGRU) {
+		result.Probability *= contextDiscount
+	}
+	return result, nil
+}
+
+// lexer returns the lexer of the SQL tokenizer of the model of the detector,
+// or a lexer with the keywords of all of the dialects
+func (d *Detector) lexer() *lexer.Lexer {
+	if tokenizer, ok := d.tokenizer().(*SQLTokenizer); ok {
+		return tokenizer.Lexer()
+	}
+	return lexer.Default
+}
+
+// calibration returns the calibration of the model of the detector or nil
+func (d *Detector) calibration() *Calibration {
+	if d.Engine != nil {
+		return d.Engine.calibration
+	}
+	return d.Model.calibration
+}
+
+// tokenizer returns the tokenizer of the model of the detector
+func (d *Detector) tokenizer() Tokenizer {
+	if d.Engine != nil {
+		return d.Engine.tokenizer
+	}
+	return d.Model.tokenizer
+}
+
+// attackProbability runs the neural network; it returns the attack
+// probability and the output of the category head, if any
+func (d *Detector) attackProbability(input []int) (float32, []float32, error) {
+	if d.Engine != nil {
+		probabilities, categories, err := d.Engine.Outputs(input)
+		if err != nil {
+			return 0, nil, err
+		}
+		return 100 * probabilities[0], categories, nil
+	}
+	probability, err := d.AttackProbability(input)
+	if err != nil {
+		return 0, nil, err
+	}
+	categories, err := d.categoryProbabilities()
+	return probability, categories, err
+}
+
+// prefilter normalizes and converts the input and applies the regexes;
+// done is false if the neural network needs to be run
+func (d *Detector) prefilter(a string) (result Result, done bool) {
+	result.Generator = -1
+	a = Normalize(a, d.Normalizers...)
+	result.Normalized = a
 	if a == "" {
-		return 0, nil
+		result.Source = SourceEmpty
+		return result, true
 	}
 
+	result.Tokens = convert(d.tokenizer(), []byte(strings.ToLower(a)))
+	if d.Rules != nil && result.apply(d.Rules.Match(a)) {
+		return result, true
+	}
 	if !d.SkipRegex {
 		if notFilter.MatchString(a) {
-			return 0, nil
+			result.Source = SourceNotFilter
+			return result, true
 		}
 
 		if filter.MatchString(a) {
-			return 100.0, nil
+			result.Probability, result.Source = 100.0, SourceFilter
+			for _, m := range matchers {
+				if m.regex.MatchString(a) {
+					result.Generator, result.Form = m.index, m.form
+					result.Categories = make([]float32, data.Categories)
+					result.Categories[m.category] = 1
+					break
+				}
+			}
+			return result, true
 		}
 	}
 
-	data := convert([]byte(strings.ToLower(a)))
-	return d.AttackProbability(data)
+	return result, false
+}
+
+// DetectBatch returns the probability each input is a SQL injection attack;
+// the inputs that need the neural network are run through it in batches.
+// Use DetectDetailed for the category probabilities
+func (d *Detector) DetectBatch(inputs []string) ([]float32, error) {
+	if d.Engine != nil {
+		probabilities := make([]float32, len(inputs))
+		for i, a := range inputs {
+			result, err := d.DetectDetailed(a)
+			if err != nil {
+				return nil, err
+			}
+			probabilities[i] = result.Probability
+		}
+		return probabilities, nil
+	}
+
+	size := d.BatchSize
+	if size < 1 {
+		size = DefaultBatchSize
+	}
+	if d.batch == nil || d.batch.size != size {
+		batch, err := newBatchRNN(d.Model, size)
+		if err != nil {
+			return nil, err
+		}
+		d.batch = batch
+	}
+
+	probabilities := make([]float32, len(inputs))
+	var pending []int
+	var tokens [][]int
+	var results []Result
+	for i, a := range inputs {
+		result, done := d.prefilter(a)
+		if done {
+			result.adjust()
+			probabilities[i] = result.Probability
+			continue
+		}
+		pending = append(pending, i)
+		tokens = append(tokens, result.Tokens)
+		results = append(results, result)
+	}
+
+	for len(tokens) > 0 {
+		n := size
+		if len(tokens) < n {
+			n = len(tokens)
+		}
+		batch, err := d.batch.attackProbabilities(tokens[:n])
+		if err != nil {
+			return nil, err
+		}
+		calibration := d.calibration()
+		for j, probability := range batch {
+			result := results[j]
+			result.Probability, result.Source = calibration.Apply(probability), SourceGRU
+			result.adjust()
+			probabilities[pending[j]] = result.Probability
+		}
+		pending, tokens, results = pending[n:], tokens[n:], results[n:]
+	}
+	return probabilities, nil
 }
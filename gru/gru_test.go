@@ -2,8 +2,25 @@ package gru
 
 import (
 	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
 	"math/rand"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/pointlander/injectsec/data"
+	"github.com/pointlander/injectsec/lexer"
+	"github.com/pointlander/injectsec/onnx"
+	G "gorgonia.org/gorgonia"
 )
 
 func TestSerialize(t *testing.T) {
@@ -28,3 +45,1112 @@ func TestSerialize(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input, output string
+	}{
+		{"%20or%201=1", " or 1=1"},
+		{"%2520or%25201=1", " or 1=1"},
+		{"100%", "100%"},
+		{"&#39; or &amp;#39;a&#39;=&#39;a", "' or 'a'='a"},
+		{"＇　ｏｒ　１＝１", "' or 1=1"},
+		{"/**/or/**/1337=1337", " or 1337=1337"},
+		{"/*!50000union*/ select", " union select"},
+		{"a \t\n b", "a b"},
+	}
+	for _, test := range tests {
+		output := Normalize(test.input, DefaultNormalizers...)
+		if output != test.output {
+			t.Fatalf("%q should normalize to %q not %q", test.input, test.output, output)
+		}
+	}
+
+	for _, normalizer := range []Normalizer{URLDecode, HTMLUnescape, Fullwidth} {
+		output := Normalize("%27ｏｒ&#39;", normalizer)
+		if output == "%27ｏｒ&#39;" {
+			t.Fatal("normalizer should change the input")
+		}
+	}
+
+	inputs := []string{"%2527 OR&#x31;=&amplifier&#", "&#&#39;ﬁ\xff /*!union*/ A\u0301/**/%"}
+	for _, test := range tests {
+		inputs = append(inputs, test.input)
+	}
+	trim := Normalizer(strings.TrimSpace)
+	for _, input := range inputs {
+		for _, normalizers := range [][]Normalizer{DefaultNormalizers, {trim, URLDecode, trim}} {
+			output, offsets := normalizeOffsets(input, normalizers...)
+			if output != Normalize(input, normalizers...) || len(offsets) != len(output)+1 ||
+				offsets[len(output)] != len(input) {
+				t.Fatalf("invalid offsets of %q: %q %v", input, output, offsets)
+			}
+			for i := 1; i < len(offsets); i++ {
+				if offsets[i] < offsets[i-1] {
+					t.Fatalf("the offsets of %q decrease: %v", input, offsets)
+				}
+			}
+		}
+	}
+	output, offsets := normalizeOffsets("a%2527b/**/c", DefaultNormalizers...)
+	if output != "a'b c" || !reflect.DeepEqual(offsets, []int{0, 1, 6, 7, 11, 12}) {
+		t.Fatal("invalid offsets", output, offsets)
+	}
+	output, offsets = normalizeOffsets(" a&amp;b ", trim, HTMLUnescape)
+	if output != "a&b" || !reflect.DeepEqual(offsets, []int{1, 2, 7, 9}) {
+		t.Fatal("invalid offsets", output, offsets)
+	}
+}
+
+func TestSerializeHeader(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	inputSize := 256 + len(Chunks)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("a model with an input size that doesn't match the tokenizer should panic")
+			}
+		}()
+		NewModel(rnd, 1, inputSize+1, 7, 2, []int{4, 3})
+	}()
+	a := NewModel(rnd, 1, inputSize, 7, 2, []int{4, 3})
+	buffer := &bytes.Buffer{}
+	err := a.Write(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+
+	b, err := ReadModel(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.inputs != 1 || b.embeddingSize != 7 || len(b.layerSizes) != 2 {
+		t.Fatal("architecture was not read from the header")
+	}
+	err = a.compare(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewModel(rnd, 2, inputSize, embeddingSize, outputSize, []int{hiddenSize})
+	err = c.Read(bytes.NewReader(data))
+	if !errors.Is(err, ErrorMismatch) {
+		t.Fatal("expected a mismatch error", err)
+	}
+
+	corrupt := append([]byte{}, data...)
+	corrupt[len(corrupt)/2]++
+	_, err = ReadModel(bytes.NewReader(corrupt))
+	if err != ErrorChecksum {
+		t.Fatal("expected a checksum error", err)
+	}
+
+	// the payload length follows the magic and the version
+	hostile := append([]byte{}, data[:len(magic)+4]...)
+	hostile = append(hostile, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	_, err = ReadModel(bytes.NewReader(hostile))
+	if !errors.Is(err, ErrorPayload) {
+		t.Fatal("expected a payload error", err)
+	}
+	truncated := append([]byte{}, data[:len(magic)+4]...)
+	truncated = append(truncated, 0, 0, 0, 0x20, 0, 0, 0, 0)
+	_, err = ReadModel(bytes.NewReader(truncated))
+	if err != io.ErrUnexpectedEOF {
+		t.Fatal("expected an unexpected EOF", err)
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	for i, chunk := range Chunks {
+		if token, length := DefaultTokenizer.Match([]byte(chunk), 0); token != 256+i || length != len(chunk) {
+			t.Fatal("chunk isn't its token", chunk, token, 256+i)
+		}
+	}
+	index := func(chunk string) int {
+		for i, c := range DefaultTokenizer.Vocabulary() {
+			if c == chunk {
+				return 256 + i
+			}
+		}
+		t.Fatal("chunk not found", chunk)
+		return 0
+	}
+	tokens := convert(DefaultTokenizer, []byte("' or 1=1 --"))
+	expected := []int{'\'', ' ', index("or"), ' ', '1', '=', '1', ' ', index("--")}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Fatal("invalid default tokens", tokens, expected)
+	}
+
+	inputs := [][]byte{
+		[]byte("' or 1=1 --"),
+		[]byte("\" or ''=''"),
+		[]byte("1 or sleep(5) --"),
+		[]byte("admin' or 'a'='a"),
+	}
+	counts := CountChunks(inputs)
+	if counts["or"] != 4 || counts["--"] != 2 || counts["''=''"] != 1 || counts["sleep"] != 1 {
+		t.Fatal("invalid chunk counts", counts)
+	}
+	learned, err := LearnTokenizer(inputs, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(learned.Vocabulary(), []string{"--", "or"}) || learned.Size() != 258 {
+		t.Fatal("invalid learned vocabulary", learned.Vocabulary())
+	}
+	tokens = convert(learned, []byte("' or sleep(5)--"))
+	expected = []int{'\'', ' ', 257, ' ', 's', 'l', 'e', 'e', 'p', '(', '5', ')', 256}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Fatal("invalid learned tokens", tokens, expected)
+	}
+
+	options := DefaultOptions()
+	options.Tokenizer = learned
+	maker := NewDetectorMakerWithOptions(options)
+	buffer := &bytes.Buffer{}
+	err = maker.Write(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+	model, err := ReadModel(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.inputSize != 258 || !reflect.DeepEqual(model.Tokenizer().Vocabulary(), learned.Vocabulary()) {
+		t.Fatal("tokenizer was not read from the header", model.inputSize)
+	}
+	err = maker.compare(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewDetectorMaker().Read(bytes.NewReader(data))
+	if !errors.Is(err, ErrorMismatch) {
+		t.Fatal("expected a mismatch error", err)
+	}
+	_, err = NewChunkTokenizer([]string{"or", "or"})
+	if err == nil {
+		t.Fatal("duplicate chunks should fail")
+	}
+}
+
+func TestSQLTokenizer(t *testing.T) {
+	tokenizer, err := NewSQLTokenizer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if convert(DefaultTokenizer, []byte("orcat1"))[0] < 256 {
+		t.Fatal("the chunk tokenizer should match or inside orcat1")
+	}
+	tokens := convert(tokenizer, []byte("orcat1 or 'x'"))
+	if len(tokens) != 5 || tokens[0] != tokenizer.classes[lexer.KindIdentifier] ||
+		tokens[1] != tokenizer.classes[lexer.KindSpace] || tokens[2] < 256+len(sqlClasses)+len(lexer.Operators) ||
+		tokens[4] != tokenizer.classes[lexer.KindString] {
+		t.Fatal("invalid sql tokens", tokens)
+	}
+	for _, token := range tokens {
+		if token < 0 || token >= tokenizer.Size() {
+			t.Fatal("token out of range", token)
+		}
+	}
+	input := []byte("admin' or 'a'='a")
+	tokens, starts := tokenize(tokenizer, input)
+	index, _ := tokenizer.Lexer().Keyword([]byte("or"), lexer.Token{Kind: lexer.KindKeyword, End: 2})
+	if len(tokens) != 8 || tokens[0] != tokenizer.classes[lexer.KindString] ||
+		tokens[2] != 256+len(sqlClasses)+len(lexer.Operators)+index || starts[2] != 7 {
+		t.Fatal("the keyword of an input that closes a quote should be a token", tokens, starts)
+	}
+
+	mysql, err := NewSQLTokenizer(lexer.MySQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := DefaultOptions()
+	options.Tokenizer = mysql
+	maker := NewDetectorMakerWithOptions(options)
+	buffer := &bytes.Buffer{}
+	err = maker.Write(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := ReadModel(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.Tokenizer().Kind() != TokenizerSQL || !reflect.DeepEqual(model.Tokenizer().Vocabulary(), mysql.Vocabulary()) {
+		t.Fatal("sql tokenizer was not read from the header")
+	}
+	input = []byte("1 union select sleep(5)")
+	if !reflect.DeepEqual(convert(model.Tokenizer(), input), convert(mysql, input)) {
+		t.Fatal("read tokenizer differs")
+	}
+	_, err = NewTokenizer(TokenizerSQL, mysql.Vocabulary()[1:])
+	if err == nil {
+		t.Fatal("invalid sql vocabulary should fail")
+	}
+}
+
+func TestCategories(t *testing.T) {
+	options := DefaultOptions()
+	options.Categories = int(data.Categories) - 1
+	maker := NewDetectorMakerWithOptions(options)
+	buffer := &bytes.Buffer{}
+	err := maker.Write(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := ReadModel(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.Categories() != options.Categories || model.Options().Categories != options.Categories {
+		t.Fatal("the category head was not read from the header", model.Categories())
+	}
+	err = maker.compare(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewDetectorMaker().Read(bytes.NewReader(buffer.Bytes()))
+	if !errors.Is(err, ErrorMismatch) {
+		t.Fatal("expected a mismatch error", err)
+	}
+
+	detector := maker.MakeEngine()
+	result, err := detector.DetectDetailed("1 union select password from users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != SourceGRU || len(result.Categories) != int(data.Categories) {
+		t.Fatal("expected category probabilities from the engine", result)
+	}
+	sum := float32(0)
+	for _, probability := range result.Categories {
+		sum += probability
+	}
+	if result.Categories[data.CategoryNone] != 0 || math.Abs(float64(sum-1)) > 1e-5 {
+		t.Fatal("invalid category probabilities", result.Categories)
+	}
+
+	result, err = detector.DetectDetailed("' or 3=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != SourceFilter || result.Category() != data.CategoryTautology {
+		t.Fatal("expected the category of the matched generator", result)
+	}
+
+	result, err = NewDetectorMaker().MakeEngine().DetectDetailed("1 union select password from users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Categories != nil || result.Category() != data.CategoryNone {
+		t.Fatal("a model without a category head has no categories", result.Categories)
+	}
+
+	options.Categories = 2
+	result, err = NewDetectorMakerWithOptions(options).MakeEngine().DetectDetailed("1 union select password from users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Categories) != int(data.Categories) || result.Categories[options.Categories+1] != 0 {
+		t.Fatal("the categories of a smaller category head are indexed by data.Category", result.Categories)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	maker := NewDetectorMaker()
+	detector := maker.MakeEngine()
+	detector.SkipRegex = true
+	input := "' OR 1=1 --"
+	explanation, err := detector.Explain(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explanation.Source != SourceGRU || explanation.Network != explanation.Raw {
+		t.Fatal("expected the network probability", explanation.Result)
+	}
+	engine, text, end := maker.Engine(), "", 0
+	for i, a := range explanation.Attributions {
+		if a.Start != end || a.Text != input[a.Start:a.End] {
+			t.Fatal("invalid span", a)
+		}
+		end, text = a.End, text+a.Text
+		tokens := convert(maker.tokenizer, []byte(strings.ToLower(input)))
+		tokens[i] = ' '
+		probability, err := engine.AttackProbability(tokens)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(float64(explanation.Network-probability-a.Saliency)) > 1e-4 {
+			t.Fatal("the saliency isn't the occluded difference", a, explanation.Network, probability)
+		}
+	}
+	if text != input || len(explanation.Attributions) != len(explanation.Tokens) {
+		t.Fatal("the spans don't cover the input", text)
+	}
+	highlighted := explanation.Highlighted()
+	if strings.Replace(strings.Replace(highlighted, "[", "", -1), "]", "", -1) != input || !strings.Contains(highlighted, "[") {
+		t.Fatal("invalid highlighting", highlighted)
+	}
+
+	buffer := &bytes.Buffer{}
+	err = explanation.WriteJSON(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Source       string
+		Attributions []Attribution
+	}
+	err = json.Unmarshal(buffer.Bytes(), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Source != "gru" || !reflect.DeepEqual(decoded.Attributions, explanation.Attributions) {
+		t.Fatal("invalid json", buffer.String())
+	}
+	buffer.Reset()
+	err = explanation.WriteText(buffer)
+	if err != nil || !strings.Contains(buffer.String(), highlighted) {
+		t.Fatal("invalid text", buffer.String(), err)
+	}
+
+	// the spans are into the original input
+	detector.Normalizers = DefaultNormalizers
+	input = "%27%20OR/**/1=1%20--"
+	explanation, err = detector.Explain(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, end = "", 0
+	for _, a := range explanation.Attributions {
+		if a.Start != end || a.Text != input[a.Start:a.End] {
+			t.Fatal("invalid span", a)
+		}
+		end, text = a.End, text+a.Text
+	}
+	if text != input || explanation.Input != input {
+		t.Fatal("the spans don't cover the original input", text)
+	}
+	if stripped := strings.Replace(strings.Replace(explanation.Highlighted(), "[", "", -1), "]", "", -1); stripped != input {
+		t.Fatal("the original input isn't highlighted", explanation.Highlighted())
+	}
+}
+
+func TestRules(t *testing.T) {
+	rules, err := NewRules(
+		Rule{Name: "customer", Action: RuleAllow, Literal: "OR 3=3"},
+		Rule{Name: "dirtree", Action: RuleDeny, Regex: `xp_dirtree\b`},
+		Rule{Name: "tautology", Action: RuleAdjust, Score: -40, Parts: func(p *data.Parts) {
+			p.AddLiteral("'")
+			p.AddOr()
+			p.AddNumber(0, 1337)
+			p.AddLiteral("=")
+			p.AddNumber(0, 1337)
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	detector := NewDetectorMaker().MakeEngine()
+	detector.Rules = rules
+	for _, test := range []struct {
+		input       string
+		source      Source
+		probability float32
+		rules       []string
+	}{
+		{"' or 3=3", SourceRule, 0, []string{"customer", "tautology"}},
+		{"exec master..xp_dirtree '\\\\x'", SourceRule, 100, []string{"dirtree"}},
+		{"' or 1=1", SourceFilter, 60, []string{"tautology"}},
+		{"hello", SourceNotFilter, 0, nil},
+	} {
+		result, err := detector.DetectDetailed(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, match := range result.Rules {
+			names = append(names, match.Name)
+		}
+		if result.Source != test.source || result.Probability != test.probability || !reflect.DeepEqual(names, test.rules) {
+			t.Fatal("unexpected result", test.input, result)
+		}
+	}
+
+	err = rules.Set(Rule{Name: "invalid", Action: RuleDeny, Literal: "a", Regex: "b"})
+	if err == nil || len(rules.Rules()) != 3 {
+		t.Fatal("invalid rules shouldn't replace the rules", err)
+	}
+
+	yamlRules := `rules:
+  - name: search
+    action: adjust
+    score: 25
+    grammar:
+      - literal: "'"
+      - type: or
+      - type: name
+  - name: fixed
+    action: allow
+    regex: ^o'reilly$
+`
+	read, err := ReadRules(strings.NewReader(yamlRules), RulesYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 2 || read[0].Action != RuleAdjust || read[0].Score != 25 || len(read[0].Grammar) != 3 ||
+		read[0].Grammar[1].Type != "or" || read[1].Regex != "^o'reilly$" {
+		t.Fatal("invalid yaml rules", read)
+	}
+	buffer := &bytes.Buffer{}
+	err = WriteRules(buffer, RulesJSON, read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := ReadRules(buffer, RulesJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, again) {
+		t.Fatal("json rules don't round trip", read, again)
+	}
+	_, err = ReadRules(strings.NewReader("rules:\n  - name: a\n    action: block\n    literal: a\n"), RulesYAML)
+	if err == nil {
+		t.Fatal("expected an invalid action error")
+	}
+
+	file := t.TempDir() + "/rules.yaml"
+	err = ioutil.WriteFile(file, []byte(yamlRules), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rules.LoadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matches := rules.Match("' OR abc"); len(matches) != 1 || matches[0].Name != "search" {
+		t.Fatal("the grammar rule should match", matches)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := rules.Watch(ctx, file, 0, nil); err != ErrorInterval {
+		t.Fatal("expected an interval error", err)
+	}
+	err = ioutil.WriteFile(file, []byte("rules:\n  - name: reloaded\n    action: deny\n    literal: x\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 16)
+	go rules.Watch(ctx, file, time.Millisecond, func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+	reloaded := func(name string) bool {
+		for i := 0; i < 1000; i++ {
+			time.Sleep(time.Millisecond)
+			if loaded := rules.Rules(); len(loaded) == 1 && loaded[0].Name == name {
+				return true
+			}
+		}
+		return false
+	}
+	if !reloaded("reloaded") {
+		t.Fatal("the rules file was not loaded when the watch started")
+	}
+
+	// a file that fails to load is reported once and retried, even if it is
+	// replaced with a file with the same modification time and size
+	replace := func(contents string, modified time.Time) {
+		temporary := file + ".tmp"
+		err := ioutil.WriteFile(temporary, []byte(contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chtimes(temporary, modified, modified)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Rename(temporary, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	modified := time.Now().Add(time.Hour)
+	replace("rules:\n  - name: reloaded\n    action: dent\n    literal: x\n", modified)
+	select {
+	case <-errs:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the invalid rules file was not reported")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(errs) != 0 {
+		t.Fatal("the same error was reported again", <-errs)
+	}
+	replace("rules:\n  - name: retried!\n    action: deny\n    literal: x\n", modified)
+	if !reloaded("retried!") {
+		t.Fatal("the rules file was not retried")
+	}
+	replace("rules:\n  - name: changed\n    action: deny\n    literal: x\n", modified.Add(time.Hour))
+	if !reloaded("changed") {
+		t.Fatal("the changed rules file was not reloaded")
+	}
+	if len(errs) != 0 {
+		t.Fatal("unexpected error", <-errs)
+	}
+}
+
+func TestGeneratorRules(t *testing.T) {
+	generators, err := data.ReadGenerators(strings.NewReader(`generators:
+  - form: "' or 1=1 --"
+    regex: "'${or}${num:0}=${num:0}${spaces_opt}--"
+  - form: "skipped"
+    regex: "skipped"
+    skip_match: true
+  - form: "no regex"
+`), data.GeneratorsYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := NewRules(GeneratorRules(generators)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded := rules.Rules(); len(loaded) != 1 || loaded[0].Name != "' or 1=1 --" || loaded[0].Action != RuleDeny {
+		t.Fatal("expected a deny rule for the generator with a regex", loaded)
+	}
+	if matches := rules.Match("' OR 7=7 --"); len(matches) != 1 {
+		t.Fatal("the generator rule should match a sample", matches)
+	}
+	if matches := rules.Match("skipped"); len(matches) != 0 {
+		t.Fatal("skip match generators shouldn't be rules", matches)
+	}
+}
+
+func TestDetectContext(t *testing.T) {
+	detector := NewDetectorMaker().MakeEngine()
+	detailed, err := detector.DetectDetailed("O'Brien")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := detector.DetectContext("O'Brien", lexer.ContextSingleQuote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != SourceGRU || result.Escaped || result.Probability != contextDiscount*detailed.Probability {
+		t.Fatal("o'brien shouldn't escape single quotes", result)
+	}
+	result, err = detector.DetectContext("' or 1=1 --", lexer.ContextSingleQuote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Escaped || result.Probability != 100 {
+		t.Fatal("the attack should escape single quotes", result)
+	}
+	result, err = detector.DetectContext("' or 1=1 --", lexer.ContextDoubleQuote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != SourceFilter || result.Escaped || result.Probability != 100*contextDiscount {
+		t.Fatal("the attack shouldn't escape double quotes", result)
+	}
+	result, err = detector.DetectContext("\\' or 1=1 --", lexer.ContextSingleQuote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Escaped {
+		t.Fatal("a backslash doesn't escape the quote outside of mysql", result)
+	}
+	result, err = detector.DetectContext("%27%20or%201%3D1%20--", lexer.ContextSingleQuote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Escaped {
+		t.Fatal("inputs aren't normalized without normalizers", result)
+	}
+	detector.Normalizers = DefaultNormalizers
+	result, err = detector.DetectContext("%27%20or%201%3D1%20--", lexer.ContextSingleQuote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Escaped {
+		t.Fatal("the normalized attack should escape single quotes", result)
+	}
+
+	detector.Rules, err = NewRules(Rule{Name: "name", Action: RuleDeny, Literal: "o'brien"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = detector.DetectContext("O'Brien", lexer.ContextSingleQuote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != SourceRule || result.Escaped || result.Probability != 100 {
+		t.Fatal("rules should apply to inputs that don't escape", result)
+	}
+}
+
+func TestSerializeLegacy(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	a := NewModel(rnd, 2, 256+len(Chunks), embeddingSize, outputSize, []int{hiddenSize})
+	buffer := &bytes.Buffer{}
+	encoder := gob.NewEncoder(buffer)
+	for _, t := range a.tensors() {
+		encoder.Encode(t.Data())
+	}
+	b, err := ReadModel(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.compare(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSerializeOptions(t *testing.T) {
+	options := DefaultOptions()
+	options.EmbeddingSize = 4
+	options.HiddenSizes = []int{3, 2}
+	options.Bidirectional = false
+	options.Steps = 5
+	options.LearnRate = 0.01
+	maker := NewDetectorMakerWithOptions(options)
+	buffer := &bytes.Buffer{}
+	err := maker.Write(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := ReadModel(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := model.Options()
+	if read.EmbeddingSize != 4 || len(read.HiddenSizes) != 2 || read.Bidirectional ||
+		read.Steps != 5 || read.LearnRate != 0.01 {
+		t.Fatal("options were not persisted", read)
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	var probabilities []float32
+	var attacks []bool
+	for i := 0; i < 10000; i++ {
+		probability := float32(rnd.Float64() * 100)
+		calibrated := 1 / (1 + math.Exp(-0.5*logit(probability)+1))
+		probabilities = append(probabilities, probability)
+		attacks = append(attacks, rnd.Float64() < calibrated)
+	}
+
+	platt := FitPlatt(probabilities, attacks)
+	if math.Abs(platt.A+0.5) > 0.1 || math.Abs(platt.B-1) > 0.1 {
+		t.Fatal("invalid platt scaling", platt.A, platt.B)
+	}
+	isotonic := FitIsotonic(probabilities, attacks)
+	for i := 1; i < len(isotonic.X); i++ {
+		if isotonic.X[i] <= isotonic.X[i-1] || isotonic.Y[i] <= isotonic.Y[i-1] {
+			t.Fatal("isotonic regression should be increasing", i)
+		}
+	}
+	for _, probability := range []float32{10, 50, 90} {
+		expected := 100 / (1 + math.Exp(-0.5*logit(probability)+1))
+		if math.Abs(float64(platt.Apply(probability))-expected) > 2 {
+			t.Fatal("invalid platt probability", probability, platt.Apply(probability), expected)
+		}
+		if math.Abs(float64(isotonic.Apply(probability))-expected) > 10 {
+			t.Fatal("invalid isotonic probability", probability, isotonic.Apply(probability), expected)
+		}
+	}
+
+	labels := []bool{false, false, false, false, false, true}
+	for fpr, expected := range map[float64]float32{0: 90, 0.2: 80, 0.4: 80, 0.6: 40, 1: 0} {
+		threshold, err := ThresholdForFPR([]float32{90, 80, 80, 40, 30, 95}, labels, fpr)
+		if err != nil || threshold != expected {
+			t.Fatal("invalid threshold", fpr, threshold, expected, err)
+		}
+	}
+	for _, fpr := range []float64{-0.1, 1.1, math.NaN()} {
+		if _, err := ThresholdForFPR([]float32{90, 80, 80, 40, 30, 95}, labels, fpr); err == nil {
+			t.Fatal("expected an error for the false positive rate", fpr)
+		}
+	}
+	if _, err := ThresholdForFPR([]float32{90}, labels, 0); err == nil {
+		t.Fatal("expected an error for missing labels")
+	}
+
+	maker := NewDetectorMaker()
+	maker.SetCalibration(&Calibration{Method: CalibrationPlatt})
+	buffer := &bytes.Buffer{}
+	err := maker.Write(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := ReadModel(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.Calibration() == nil || model.Calibration().Method != CalibrationPlatt {
+		t.Fatal("calibration was not persisted")
+	}
+	detector := (&DetectorMaker{Model: model}).MakeEngine()
+	detector.SkipRegex = true
+	result, err := detector.DetectDetailed("select 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Probability != 50 || result.Raw == 50 {
+		t.Fatal("calibration was not applied", result.Probability, result.Raw)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	rates := []struct {
+		schedule Schedule
+		epoch    int
+		rate     float64
+	}{
+		{Schedule{Kind: "constant"}, 7, 0.1},
+		{Schedule{Kind: "step", Decay: 0.5, Epochs: 2}, 5, 0.025},
+		{Schedule{Kind: "exponential", Decay: 0.5}, 3, 0.0125},
+		{Schedule{Kind: "cosine"}, 0, 0.1},
+		{Schedule{Kind: "cosine"}, 5, 0.05},
+	}
+	for _, r := range rates {
+		rate, err := r.schedule.Rate(0.1, r.epoch, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(rate-r.rate) > 1e-9 {
+			t.Fatal("invalid rate", r.schedule.Kind, rate, r.rate)
+		}
+	}
+	_, err := Schedule{Kind: "unknown"}.Rate(0.1, 0, 10)
+	if err == nil {
+		t.Fatal("expected an error for an unknown schedule")
+	}
+
+	file := t.TempDir() + "/w0.w.state"
+	solver := NewRMSProp(DefaultOptions())
+	solver.Cache = [][]float32{{1, 2}, {3}}
+	err = WriteCheckpointFile(file, &Checkpoint{Epoch: 3, Best: 0.5, BestEpoch: 2, Waited: 1, Solver: solver})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, err := ReadCheckpointFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Epoch != 3 || checkpoint.BestEpoch != 2 || checkpoint.Waited != 1 ||
+		checkpoint.Solver.LearnRate != solver.LearnRate || checkpoint.Solver.Cache[1][0] != 3 {
+		t.Fatal("invalid checkpoint", checkpoint)
+	}
+}
+
+func TestRMSPropParity(t *testing.T) {
+	options := DefaultOptions()
+	models := [2]*Model{}
+	learners := [2]*RNN{}
+	for i := range models {
+		models[i] = NewModel(rand.New(rand.NewSource(1)), 2, 256+len(Chunks), embeddingSize, outputSize, []int{hiddenSize})
+		learners[i] = NewRNN(models[i])
+		err := learners[i].ModeLearn(options.Steps)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	solvers := [2]G.Solver{
+		G.NewRMSPropSolver(G.WithLearnRate(options.LearnRate), G.WithL2Reg(options.L2Reg), G.WithClip(options.ClipVal)),
+		NewRMSProp(options),
+	}
+	inputs := []string{"' or 1=1 --", "hello world", "1 union select password from users"}
+	for step := 0; step < 16; step++ {
+		input := inputs[step%len(inputs)]
+		for i, learner := range learners {
+			_, _, err := learner.Learn(convert(DefaultTokenizer, []byte(input)), step%len(inputs) != 1, solvers[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		a, b := models[0].tensors(), models[1].tensors()
+		for i := range a {
+			x, y := a[i].Data().([]float32), b[i].Data().([]float32)
+			for j := range x {
+				if math.Abs(float64(x[j]-y[j])) > 1e-5 {
+					t.Fatal("solvers differ at step", step, "tensor", i, x[j], y[j])
+				}
+			}
+		}
+	}
+}
+
+func TestEngine(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	model := NewModel(rnd, 1, 256+len(Chunks), 1, 2, []int{1})
+	engine := NewEngine(model)
+	input := []int{2, 0, 1}
+
+	we, be := model.we.Data().([]float32), model.be.Data().([]float32)
+	l := model.layers[0]
+	wf, uf, bf := l.wf.Data().([]float32)[0], l.uf.Data().([]float32)[0], l.bf.Data().([]float32)[0]
+	wh, uh, bh := l.wh.Data().([]float32)[0], l.uh.Data().([]float32)[0], l.bh.Data().([]float32)[0]
+	wo, bo := model.wo.Data().([]float32), model.bo.Data().([]float32)
+	h := 0.0
+	for _, token := range input {
+		x := float64(we[token] + be[0])
+		f := 1 / (1 + math.Exp(-(float64(wf)*x + float64(uf)*h + float64(bf))))
+		z := math.Tanh(float64(wh)*x + float64(uh)*f*h + float64(bh))
+		h = (1-f)*z + f*h
+	}
+	a, b := math.Exp(float64(wo[0])*h+float64(bo[0])), math.Exp(float64(wo[1])*h+float64(bo[1]))
+	expected := 100 * a / (a + b)
+
+	probability, err := engine.AttackProbability(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(float64(probability)-expected) > 1e-3 {
+		t.Fatal("engine doesn't match reference", probability, expected)
+	}
+
+	_, err = engine.AttackProbability([]int{256 + len(Chunks)})
+	if err == nil {
+		t.Fatal("token should be out of range")
+	}
+}
+
+func TestONNX(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	models := []*Model{
+		NewDetectorMaker().Model,
+		NewModel(rnd, 1, 256+len(Chunks), 4, 2, []int{3, 2}),
+	}
+	models[1].addCategories(rnd, 3)
+	for _, model := range models {
+		for _, t := range model.tensors() {
+			data := t.Data().([]float32)
+			for i := range data {
+				data[i] = float32(rnd.NormFloat64())
+			}
+		}
+		buffer := &bytes.Buffer{}
+		err := model.WriteONNX(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exported, err := onnx.Read(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		engine := NewEngine(model)
+		for _, input := range []string{"' or 1=1 --", "hello world", "x", "SELECT * FROM users"} {
+			tokens := model.Tokens(input)
+			outputs, err := exported.Run(map[string]*onnx.Tensor{
+				ONNXInput: onnx.NewInt64(ONNXInput, tokens, int64(len(tokens))),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			probabilities, categories, err := engine.Outputs(convert(model.tokenizer, []byte(strings.ToLower(input))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if output := outputs[ONNXCategories]; (output == nil) != (categories == nil) {
+				t.Fatal("onnx and engine category heads differ", input)
+			} else if output != nil {
+				for i, p := range categories {
+					if math.Abs(float64(p-output.Float[i])) > 1e-5 {
+						t.Fatal("onnx and engine categories differ", input, output.Float, categories)
+					}
+				}
+			}
+			output := outputs[ONNXOutput]
+			if len(output.Dims) != 2 || output.Dims[0] != 1 || output.Dims[1] != 2 {
+				t.Fatal("invalid output shape", output.Dims)
+			}
+			for i, p := range probabilities {
+				if math.Abs(float64(p-output.Float[i])) > 1e-5 {
+					t.Fatal("onnx and engine differ", input, output.Float, probabilities)
+				}
+			}
+		}
+	}
+}
+
+func TestScanner(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	model := NewModel(rnd, 2, 256+len(Chunks), embeddingSize, outputSize, []int{hiddenSize})
+	input := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20) +
+		"' OR 1=1 UNION SELECT password FROM users --" +
+		strings.Repeat(" Lorem ipsum dolor sit amet.", 20)
+	scanner := NewScanner(NewEngine(model), 16, 4)
+
+	tokens := convert(DefaultTokenizer, []byte(strings.ToLower(input)))
+	expected, windows, start := float32(0), 0, 0
+	for ; start+16 <= len(tokens); start += 4 {
+		probability, err := scanner.AttackProbability(tokens[start : start+16])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if windows == 0 || probability > expected {
+			expected = probability
+		}
+		windows++
+	}
+	if start+12 < len(tokens) {
+		probability, err := scanner.AttackProbability(tokens[start:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if probability > expected {
+			expected = probability
+		}
+		windows++
+	}
+
+	result, err := scanner.ScanString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Probability != expected || result.Windows != windows {
+		t.Fatal("scan doesn't match windows", result, expected, windows)
+	}
+	if result.Bytes != int64(len(input)) || result.Offset+result.Length > result.Bytes {
+		t.Fatal("invalid offsets", result)
+	}
+
+	streamed, err := scanner.Scan(iotest.OneByteReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streamed != result {
+		t.Fatal("streamed scan doesn't match", streamed, result)
+	}
+
+	model.calibration = &Calibration{
+		Method: CalibrationPlatt,
+		A:      -2,
+		B:      1,
+	}
+	calibrated, err := scanner.ScanString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calibrated.Probability != model.calibration.Apply(expected) || calibrated.Offset != result.Offset {
+		t.Fatal("scan isn't calibrated", calibrated, model.calibration.Apply(expected))
+	}
+}
+
+func TestAccumulator(t *testing.T) {
+	a := accumulator{example: [][]float32{{0, 0}}}
+	// a long example with four windows and a short one with one window
+	for _, windows := range [][]float32{{1, 1, 1, 1}, {3}} {
+		for _, d := range windows {
+			a.example[0][0] += d
+			a.example[0][1] += 2 * d
+			a.windows++
+		}
+		a.finish()
+	}
+	a.finish()
+	if a.examples != 2 || a.grads[0][0] != 4 || a.grads[0][1] != 8 {
+		t.Fatal("each example should add its mean gradient", a.examples, a.grads)
+	}
+	a.reset()
+	if a.examples != 0 || a.windows != 0 || a.grads[0][0] != 0 || a.grads[0][1] != 0 {
+		t.Fatal("the sums should be zeroed", a)
+	}
+}
+
+func TestCombine(t *testing.T) {
+	// windows are the gradients of the unrolled windows of the examples
+	windows := [][]float32{{1, 1, 1, 1}, {3}, {2, 4}, {5}}
+	accumulate := func(a *accumulator, examples [][]float32) {
+		for _, example := range examples {
+			for _, d := range example {
+				a.example[0][0] += d
+				a.windows++
+			}
+			a.finish()
+		}
+	}
+	serial := &accumulator{example: [][]float32{{0}}}
+	accumulate(serial, windows)
+	for _, shards := range [][][][]float32{
+		{windows[:1], windows[1:]},
+		{windows[:3], windows[3:]},
+		{nil, windows[:2], windows[2:]},
+	} {
+		accumulators := make([]*accumulator, len(shards))
+		for i, shard := range shards {
+			accumulators[i] = &accumulator{example: [][]float32{{0}}}
+			accumulate(accumulators[i], shard)
+		}
+		grads, examples := combine(accumulators)
+		if examples != serial.examples || grads[0][0] != serial.grads[0][0] {
+			t.Fatal("sharding shouldn't change the gradients", examples, grads, serial.grads)
+		}
+	}
+}
+
+func TestTrainBatchEmpty(t *testing.T) {
+	g := NewGRU(rand.New(rand.NewSource(1)))
+	if cost := g.TrainBatch([][]byte{{}}, []bool{true}); cost != 0 {
+		t.Fatal("an empty example shouldn't cost anything", cost)
+	}
+	if cost := g.Train(nil, false); cost != 0 {
+		t.Fatal("an empty input shouldn't cost anything", cost)
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	pool := NewDetectorMaker().NewPool(1)
+	detector, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error)
+	go func() {
+		_, err := pool.Get(context.Background())
+		errs <- err
+	}()
+	for pool.Stats().Waits == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	pool.Close()
+	select {
+	case err := <-errs:
+		if err != ErrorPoolClosed {
+			t.Fatal("expected the pool to be closed", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("waiting get didn't wake up when the pool closed")
+	}
+	pool.Put(detector)
+	if stats := pool.Stats(); stats.Idle != 0 || stats.Created != 0 {
+		t.Fatal("detector returned after close should be dropped", stats)
+	}
+	if _, err := pool.Get(context.Background()); err != ErrorPoolClosed {
+		t.Fatal("expected the pool to be closed", err)
+	}
+
+	pool = NewDetectorMaker().NewPool(1)
+	detector, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Put(detector)
+	pool.Put(detector)
+	pool.Put(NewDetectorMaker().Make())
+	if stats := pool.Stats(); stats.Idle != 1 || stats.Created != 1 {
+		t.Fatal("foreign and repeated puts should be ignored", stats)
+	}
+	pool.Close()
+	if stats := pool.Stats(); stats.Idle != 0 || stats.Created != 0 || stats.InUse != 0 {
+		t.Fatal("idle detectors should be dropped when the pool closes", stats)
+	}
+}
@@ -1,8 +1,12 @@
 package gru
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
 	"encoding/gob"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"io/ioutil"
 	"math"
@@ -10,6 +14,7 @@ import (
 	"os"
 	"strconv"
 
+	"github.com/pointlander/injectsec/data"
 	G "gorgonia.org/gorgonia"
 	"gorgonia.org/tensor"
 )
@@ -33,29 +38,58 @@ type Model struct {
 	be     *tensor.Dense
 	wo     *tensor.Dense
 	bo     *tensor.Dense
+	// wc and bc are the weights of the category head, if any
+	wc *tensor.Dense
+	bc *tensor.Dense
 
 	inputs                               int
 	inputSize, embeddingSize, outputSize int
 	layerSizes                           []int
+	categories                           int
+	options                              Options
+	calibration                          *Calibration
+	tokenizer                            Tokenizer
 }
 
-// NewModel creates a new GRU model
+// gaussian32 returns He initialized weights for a tensor of shape s
+func gaussian32(rnd *rand.Rand, s ...int) []float32 {
+	size := tensor.Shape(s).TotalSize()
+	weights, stdev := make([]float32, size), math.Sqrt(2/float64(s[len(s)-1]))
+	for i := range weights {
+		weights[i] = float32(rnd.NormFloat64() * stdev)
+	}
+	return weights
+}
+
+// NewModel creates a new GRU model that uses DefaultTokenizer; it panics if
+// inputSize isn't the size of DefaultTokenizer, see NewGRUWithOptions for
+// models with other tokenizers
 func NewModel(rnd *rand.Rand, inputs, inputSize, embeddingSize, outputSize int, layerSizes []int) *Model {
+	if size := DefaultTokenizer.Size(); inputSize != size {
+		panic(fmt.Errorf("input size %d is not the size %d of the default tokenizer", inputSize, size))
+	}
+	return newModel(rnd, inputs, DefaultTokenizer, embeddingSize, outputSize, layerSizes)
+}
+
+// newModel creates a new GRU model whose input size is the size of the tokenizer
+func newModel(rnd *rand.Rand, inputs int, tokenizer Tokenizer, embeddingSize, outputSize int, layerSizes []int) *Model {
 	gaussian32 := func(s ...int) []float32 {
-		size := tensor.Shape(s).TotalSize()
-		weights, stdev := make([]float32, size), math.Sqrt(2/float64(s[len(s)-1]))
-		for i := range weights {
-			weights[i] = float32(rnd.NormFloat64() * stdev)
-		}
-		return weights
+		return gaussian32(rnd, s...)
 	}
 
+	options := DefaultOptions()
+	options.EmbeddingSize = embeddingSize
+	options.HiddenSizes = layerSizes
+	options.Bidirectional = inputs > 1
+	inputSize := tokenizer.Size()
 	model := &Model{
 		inputs:        inputs,
 		inputSize:     inputSize,
 		embeddingSize: embeddingSize,
 		outputSize:    outputSize,
 		layerSizes:    layerSizes,
+		options:       options,
+		tokenizer:     tokenizer,
 	}
 	model.we = tensor.New(tensor.WithShape(embeddingSize, inputSize),
 		tensor.WithBacking(gaussian32(embeddingSize, inputSize)))
@@ -90,6 +124,36 @@ func NewModel(rnd *rand.Rand, inputs, inputSize, embeddingSize, outputSize int,
 	return model
 }
 
+// addCategories adds a category head with n outputs to the model; output i
+// is the probability of data.Category(i+1) given an attack
+func (m *Model) addCategories(rnd *rand.Rand, n int) {
+	if n < 1 {
+		return
+	}
+	last := m.layerSizes[len(m.layerSizes)-1]
+	m.categories = n
+	m.wc = tensor.New(tensor.WithShape(n, last),
+		tensor.WithBacking(gaussian32(rnd, n, last)))
+	m.bc = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(n))
+	m.options.Categories = n
+}
+
+// Categories returns the number of outputs of the category head, or zero if
+// the model doesn't have one
+func (m *Model) Categories() int {
+	return m.categories
+}
+
+// Options returns the options the model was created with
+func (m *Model) Options() Options {
+	return m.options
+}
+
+// Tokenizer returns the tokenizer of the model
+func (m *Model) Tokenizer() Tokenizer {
+	return m.tokenizer
+}
+
 // WriteFile writes the weights to a file
 func (m *Model) WriteFile(file string) error {
 	out, err := os.Create(file)
@@ -100,120 +164,237 @@ func (m *Model) WriteFile(file string) error {
 	return m.Write(out)
 }
 
-// Write writes the weights to a Writer
-func (m *Model) Write(out io.Writer) error {
-	encoder := gob.NewEncoder(out)
-	write := func(t *tensor.Dense) error {
-		return encoder.Encode(t.Data())
+// tensors returns the weights in the order they are serialized
+func (m *Model) tensors() []*tensor.Dense {
+	var tensors []*tensor.Dense
+	for _, layer := range m.layers {
+		tensors = append(tensors, layer.wf, layer.uf, layer.bf, layer.wh, layer.uh, layer.bh)
+	}
+	tensors = append(tensors, m.we, m.be, m.wo, m.bo)
+	if m.categories > 0 {
+		tensors = append(tensors, m.wc, m.bc)
 	}
+	return tensors
+}
 
-	for _, layer := range m.layers {
-		err := write(layer.wf)
-		if err != nil {
-			return err
-		}
-		err = write(layer.uf)
-		if err != nil {
-			return err
-		}
-		err = write(layer.bf)
-		if err != nil {
-			return err
-		}
+// header returns the header describing the model
+func (m *Model) header() header {
+	return header{
+		Inputs:        m.inputs,
+		InputSize:     m.inputSize,
+		EmbeddingSize: m.embeddingSize,
+		OutputSize:    m.outputSize,
+		LayerSizes:    m.layerSizes,
+		Categories:    m.categories,
+		Tokenizer:     m.tokenizer.Kind(),
+		Chunks:        m.tokenizer.Vocabulary(),
+		Steps:         m.options.Steps,
+		LearnRate:     m.options.LearnRate,
+		L2Reg:         m.options.L2Reg,
+		ClipVal:       m.options.ClipVal,
+		Calibration:   m.calibration,
+	}
+}
 
-		err = write(layer.wh)
-		if err != nil {
-			return err
-		}
-		err = write(layer.uh)
-		if err != nil {
-			return err
-		}
-		err = write(layer.bh)
+// Write writes the weights to a Writer
+func (m *Model) Write(out io.Writer) error {
+	payload := bytes.Buffer{}
+	encoder := gob.NewEncoder(&payload)
+	err := encoder.Encode(m.header())
+	if err != nil {
+		return err
+	}
+	for _, t := range m.tensors() {
+		err = encoder.Encode(t.Data())
 		if err != nil {
 			return err
 		}
 	}
-	err := write(m.we)
+
+	_, err = out.Write([]byte(magic))
 	if err != nil {
 		return err
 	}
-	err = write(m.be)
+	err = binary.Write(out, binary.LittleEndian, uint32(version))
 	if err != nil {
 		return err
 	}
-	err = write(m.wo)
+	err = binary.Write(out, binary.LittleEndian, uint64(payload.Len()))
 	if err != nil {
 		return err
 	}
-	err = write(m.bo)
+	checksum := crc32.ChecksumIEEE(payload.Bytes())
+	_, err = payload.WriteTo(out)
 	if err != nil {
 		return err
 	}
+	return binary.Write(out, binary.LittleEndian, checksum)
+}
 
+// Read reads the weights from a Reader; the weights must match the model
+func (m *Model) Read(in io.Reader) error {
+	reader := bufio.NewReader(in)
+	prefix, err := reader.Peek(len(magic))
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return err
+	}
+	if string(prefix) != magic {
+		return m.readLegacy(gob.NewDecoder(reader))
+	}
+
+	h, decoder, err := readHeader(reader)
+	if err != nil {
+		return err
+	}
+	err = m.header().check(h)
+	if err != nil {
+		return err
+	}
+	err = m.readTensors(decoder)
+	if err != nil {
+		return err
+	}
+	m.calibration = h.Calibration
 	return nil
 }
 
-// Read reads the weights from a Reader
-func (m *Model) Read(in io.Reader) error {
-	decoder := gob.NewDecoder(in)
-	read := func(t *tensor.Dense) error {
-		data := t.Data().([]float32)
-		return decoder.Decode(&data)
+// readHeader reads the header of a versioned weights file
+func readHeader(reader *bufio.Reader) (h header, decoder *gob.Decoder, err error) {
+	_, err = reader.Discard(len(magic))
+	if err != nil {
+		return
+	}
+	var v uint32
+	err = binary.Read(reader, binary.LittleEndian, &v)
+	if err != nil {
+		return
+	}
+	if v != version {
+		err = fmt.Errorf("%w: %d", ErrorVersion, v)
+		return
+	}
+	var length uint64
+	err = binary.Read(reader, binary.LittleEndian, &length)
+	if err != nil {
+		return
+	}
+	if length > maxPayload {
+		err = fmt.Errorf("%w: %d bytes", ErrorPayload, length)
+		return
+	}
+	// the buffer grows as the payload is read, so a truncated file with a
+	// large length doesn't allocate it up front
+	buffer := bytes.Buffer{}
+	_, err = io.CopyN(&buffer, reader, int64(length))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return
+	}
+	payload := buffer.Bytes()
+	var checksum uint32
+	err = binary.Read(reader, binary.LittleEndian, &checksum)
+	if err != nil {
+		return
+	}
+	if crc32.ChecksumIEEE(payload) != checksum {
+		err = ErrorChecksum
+		return
 	}
 
-	for _, layer := range m.layers {
-		err := read(layer.wf)
-		if err != nil {
-			return err
-		}
-		err = read(layer.uf)
+	decoder = gob.NewDecoder(bytes.NewReader(payload))
+	err = decoder.Decode(&h)
+	return
+}
+
+// readTensors reads the weights following the header
+func (m *Model) readTensors(decoder *gob.Decoder) error {
+	for i, t := range m.tensors() {
+		var data []float32
+		err := decoder.Decode(&data)
 		if err != nil {
 			return err
 		}
-		err = read(layer.bf)
-		if err != nil {
-			return err
+		weights := t.Data().([]float32)
+		if len(data) != len(weights) {
+			return fmt.Errorf("tensor %d has %d weights, expected %d", i, len(data), len(weights))
 		}
+		copy(weights, data)
+	}
+	return nil
+}
 
-		err = read(layer.wh)
-		if err != nil {
-			return err
-		}
-		err = read(layer.uh)
+// readLegacy reads the weights from a headerless weights file
+func (m *Model) readLegacy(decoder *gob.Decoder) error {
+	for _, t := range m.tensors() {
+		data := t.Data().([]float32)
+		err := decoder.Decode(&data)
 		if err != nil {
 			return err
 		}
-		err = read(layer.bh)
+	}
+	return nil
+}
+
+// ReadModel reads a model from a Reader; headerless weights files are read
+// using the default architecture
+func ReadModel(in io.Reader) (*Model, error) {
+	reader := bufio.NewReader(in)
+	prefix, err := reader.Peek(len(magic))
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	rnd := rand.New(rand.NewSource(1))
+	if string(prefix) != magic {
+		model := NewModel(rnd, 2, 256+len(Chunks), embeddingSize, outputSize, []int{hiddenSize})
+		err := model.readLegacy(gob.NewDecoder(reader))
 		if err != nil {
-			return err
+			return nil, err
 		}
+		return model, nil
 	}
-	err := read(m.we)
+
+	h, decoder, err := readHeader(reader)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = read(m.be)
+	tokenizer, err := h.validate()
 	if err != nil {
-		return err
-	}
-	err = read(m.wo)
+		return nil, err
+	}
+	model := newModel(rnd, h.Inputs, tokenizer, h.EmbeddingSize, h.OutputSize, h.LayerSizes)
+	if h.Steps > 0 {
+		model.options.Steps = h.Steps
+		model.options.LearnRate = h.LearnRate
+		model.options.L2Reg = h.L2Reg
+		model.options.ClipVal = h.ClipVal
+	}
+	model.options.Tokenizer, model.tokenizer = tokenizer, tokenizer
+	model.addCategories(rnd, h.Categories)
+	model.calibration = h.Calibration
+	err = model.readTensors(decoder)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = read(m.bo)
+	return model, nil
+}
+
+// ReadModelFile reads a model from a file
+func ReadModelFile(file string) (*Model, error) {
+	in, err := os.Open(file)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	return nil
+	defer in.Close()
+	return ReadModel(in)
 }
 
 // ReadFile reads the weights from a file
 func (m *Model) ReadFile(file string) error {
 	in, err := os.Open(file)
 	if err != nil {
-		return nil
+		return err
 	}
 	defer in.Close()
 	return m.Read(in)
@@ -231,27 +412,27 @@ func (m *Model) compare(b *Model) error {
 		return nil
 	}
 	for i, layer := range m.layers {
-		err := compare(layer.wf, b.layers[i].wf, "wf"+string(i))
+		err := compare(layer.wf, b.layers[i].wf, "wf"+strconv.Itoa(i))
 		if err != nil {
 			return err
 		}
-		err = compare(layer.uf, b.layers[i].uf, "uf"+string(i))
+		err = compare(layer.uf, b.layers[i].uf, "uf"+strconv.Itoa(i))
 		if err != nil {
 			return err
 		}
-		err = compare(layer.bf, b.layers[i].bf, "bf"+string(i))
+		err = compare(layer.bf, b.layers[i].bf, "bf"+strconv.Itoa(i))
 		if err != nil {
 			return err
 		}
-		err = compare(layer.wh, b.layers[i].wh, "wh"+string(i))
+		err = compare(layer.wh, b.layers[i].wh, "wh"+strconv.Itoa(i))
 		if err != nil {
 			return err
 		}
-		err = compare(layer.uh, b.layers[i].uh, "uh"+string(i))
+		err = compare(layer.uh, b.layers[i].uh, "uh"+strconv.Itoa(i))
 		if err != nil {
 			return err
 		}
-		err = compare(layer.bh, b.layers[i].bh, "bh"+string(i))
+		err = compare(layer.bh, b.layers[i].bh, "bh"+strconv.Itoa(i))
 		if err != nil {
 			return err
 		}
@@ -272,6 +453,16 @@ func (m *Model) compare(b *Model) error {
 	if err != nil {
 		return err
 	}
+	if m.categories > 0 {
+		err = compare(m.wc, b.wc, "wc")
+		if err != nil {
+			return err
+		}
+		err = compare(m.bc, b.bc, "bc")
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -327,6 +518,7 @@ func (g *gru) fwd(input, previous *G.Node) *G.Node {
 type gruOut struct {
 	hiddens       G.Nodes
 	probabilities *G.Node
+	categories    *G.Node
 }
 
 // RNN is a LSTM that takes characters as input
@@ -339,11 +531,14 @@ type RNN struct {
 	be      *G.Node
 	wo      *G.Node
 	bo      *G.Node
+	wc      *G.Node
+	bc      *G.Node
 	hiddens G.Nodes
 
 	steps    int
 	inputs   [][]*tensor.Dense
 	outputs  []*tensor.Dense
+	targets  []*tensor.Dense
 	previous []*gruOut
 	cost     *G.Node
 	machine  G.VM
@@ -368,7 +563,7 @@ func NewRNN(model *Model) *RNN {
 	be := G.NodeFromAny(g, model.be, G.WithName("be"))
 	wo := G.NodeFromAny(g, model.wo, G.WithName("wo"))
 	bo := G.NodeFromAny(g, model.bo, G.WithName("bo"))
-	return &RNN{
+	r := &RNN{
 		Model:   model,
 		layers:  layers,
 		g:       g,
@@ -378,6 +573,11 @@ func NewRNN(model *Model) *RNN {
 		bo:      bo,
 		hiddens: hiddens,
 	}
+	if model.categories > 0 {
+		r.wc = G.NodeFromAny(g, model.wc, G.WithName("wc"))
+		r.bc = G.NodeFromAny(g, model.bc, G.WithName("bc"))
+	}
+	return r
 }
 
 func (r *RNN) learnables() (value G.Nodes) {
@@ -397,6 +597,9 @@ func (r *RNN) learnables() (value G.Nodes) {
 	value = append(value, r.be)
 	value = append(value, r.wo)
 	value = append(value, r.bo)
+	if r.categories > 0 {
+		value = append(value, r.wc, r.bc)
+	}
 
 	return
 }
@@ -447,6 +650,10 @@ func (r *RNN) fwd(previous *gruOut) (inputs []*tensor.Dense, retVal *gruOut, err
 		hiddens:       hiddens,
 		probabilities: probs,
 	}
+	if r.categories > 0 {
+		categories := G.Must(G.Add(G.Must(G.Mul(r.wc, lastHidden)), r.bc))
+		retVal.categories = G.Must(G.SoftMax(categories))
+	}
 
 	return
 }
@@ -473,6 +680,10 @@ func (r *RNN) reset() {
 func (r *RNN) ModeLearn(steps int) (err error) {
 	inputs := make([][]*tensor.Dense, r.Model.inputs)
 	outputs := make([]*tensor.Dense, steps)
+	var targets []*tensor.Dense
+	if r.categories > 0 {
+		targets = make([]*tensor.Dense, steps)
+	}
 	previous := make([]*gruOut, steps)
 	var cost *G.Node
 
@@ -500,6 +711,13 @@ func (r *RNN) ModeLearn(steps int) (err error) {
 		outputs[i] = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(r.outputSize))
 		output := G.NewVector(r.g, tensor.Float32, G.WithShape(r.outputSize), G.WithValue(outputs[i]))
 		loss = G.Must(G.Mul(logprob, output))
+		if targets != nil {
+			// the target is zero for non attacks and attacks without a category
+			logprob = G.Must(G.Neg(G.Must(G.Log(previous[i].categories))))
+			targets[i] = tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(r.categories))
+			target := G.NewVector(r.g, tensor.Float32, G.WithShape(r.categories), G.WithValue(targets[i]))
+			loss = G.Must(G.Add(loss, G.Must(G.Mul(logprob, target))))
+		}
 
 		if cost == nil {
 			cost = loss
@@ -512,6 +730,7 @@ func (r *RNN) ModeLearn(steps int) (err error) {
 	r.steps = steps
 	r.inputs = inputs
 	r.outputs = outputs
+	r.targets = targets
 	r.previous = previous
 	r.cost = cost
 
@@ -587,6 +806,19 @@ func (r *RNN) AttackProbability(input []int) (float32, error) {
 	return 0, fmt.Errorf("not a tensor")
 }
 
+// categoryProbabilities returns the output of the category head for the
+// input that was last run through the graph, or nil if there isn't a head
+func (r *RNN) categoryProbabilities() ([]float32, error) {
+	if r.categories == 0 {
+		return nil, nil
+	}
+	t, ok := r.previous[0].categories.Value().(*tensor.Dense)
+	if !ok {
+		return nil, fmt.Errorf("not a tensor")
+	}
+	return append([]float32(nil), t.Data().([]float32)...), nil
+}
+
 // IsAttack determines if an input is an attack
 func (r *RNN) IsAttack(input []int) bool {
 	value := r.getProbabilities(input)
@@ -609,14 +841,27 @@ func (r *RNN) IsAttack(input []int) bool {
 }
 
 // Learn learns strings
-func (r *RNN) Learn(data []int, attack bool, solver G.Solver) (retCost, retPerp []float64, err error) {
-	end := len(data) - 1
+func (r *RNN) Learn(input []int, attack bool, solver G.Solver) (retCost, retPerp []float64, err error) {
+	return r.LearnWeighted(input, attack, 1, solver)
+}
+
+// LearnWeighted learns strings; the target of the cost built by ModeLearn is
+// set to weight instead of one, which scales the cost and its gradient
+func (r *RNN) LearnWeighted(input []int, attack bool, weight float32, solver G.Solver) (retCost, retPerp []float64, err error) {
+	return r.LearnCategory(input, attack, data.CategoryNone, weight, solver)
+}
+
+// LearnCategory is LearnWeighted that also trains the category head on the
+// category of an attack; nothing is learned by the head for
+// data.CategoryNone
+func (r *RNN) LearnCategory(input []int, attack bool, category data.Category, weight float32, solver G.Solver) (retCost, retPerp []float64, err error) {
+	end := len(input) - 1
 
 	r.reset()
-	for i := range data[:len(data)-r.steps+1] {
+	for i := range input[:len(input)-r.steps+1] {
 		for j := 0; j < r.steps; j++ {
 			index := i + j
-			source, rsource := data[index], data[end-index]
+			source, rsource := input[index], input[end-index]
 
 			r.inputs[0][j].Zero()
 			r.inputs[0][j].SetF32(source, 1.0)
@@ -627,9 +872,15 @@ func (r *RNN) Learn(data []int, attack bool, solver G.Solver) (retCost, retPerp
 			if r.outputs != nil {
 				r.outputs[j].Zero()
 				if attack {
-					r.outputs[j].SetF32(0, 1.0)
+					r.outputs[j].SetF32(0, weight)
 				} else {
-					r.outputs[j].SetF32(1, 1.0)
+					r.outputs[j].SetF32(1, weight)
+				}
+			}
+			if r.targets != nil {
+				r.targets[j].Zero()
+				if attack && category > data.CategoryNone && int(category) <= r.categories {
+					r.targets[j].SetF32(int(category)-1, weight)
 				}
 			}
 		}
@@ -0,0 +1,413 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"html"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizer canonicalizes an input before detection
+type Normalizer func(input string) string
+
+// DefaultNormalizers are the normalizers in the recommended order
+var DefaultNormalizers = []Normalizer{
+	URLDecode,
+	HTMLUnescape,
+	NFKC,
+	Fullwidth,
+	StripComments,
+	CollapseSpaces,
+}
+
+// maxDecodes is the maximum number of times an input is decoded
+const maxDecodes = 4
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+func urlDecode(input string) string {
+	if strings.IndexByte(input, '%') < 0 {
+		return input
+	}
+	output := make([]byte, 0, len(input))
+	for i := 0; i < len(input); i++ {
+		if input[i] == '%' && i+2 < len(input) {
+			a, okA := unhex(input[i+1])
+			b, okB := unhex(input[i+2])
+			if okA && okB {
+				output = append(output, a<<4|b)
+				i += 2
+				continue
+			}
+		}
+		output = append(output, input[i])
+	}
+	return string(output)
+}
+
+// URLDecode decodes percent encoded bytes, including double encoding;
+// invalid escapes are left as is and '+' is not treated as a space
+func URLDecode(input string) string {
+	for i := 0; i < maxDecodes; i++ {
+		decoded := urlDecode(input)
+		if decoded == input {
+			break
+		}
+		input = decoded
+	}
+	return input
+}
+
+// HTMLUnescape decodes HTML entities, including double encoding
+func HTMLUnescape(input string) string {
+	for i := 0; i < maxDecodes; i++ {
+		decoded := html.UnescapeString(input)
+		if decoded == input {
+			break
+		}
+		input = decoded
+	}
+	return input
+}
+
+// NFKC applies unicode NFKC normalization
+func NFKC(input string) string {
+	return norm.NFKC.String(input)
+}
+
+// Fullwidth maps fullwidth forms to ASCII
+func Fullwidth(input string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 0xFF01 && r <= 0xFF5E:
+			return r - 0xFEE0
+		case r == 0x3000:
+			return ' '
+		}
+		return r
+	}, input)
+}
+
+var comment = regexp.MustCompile(`/\*(!\d*)?((?s).*?)\*/`)
+
+// StripComments replaces /* */ comments with a space; the body of MySQL
+// executable comments, /*! */, is kept because it is executed
+func StripComments(input string) string {
+	if !strings.Contains(input, "/*") {
+		return input
+	}
+	return comment.ReplaceAllStringFunc(input, func(match string) string {
+		submatches := comment.FindStringSubmatch(match)
+		if submatches[1] != "" {
+			return " " + submatches[2] + " "
+		}
+		return " "
+	})
+}
+
+// CollapseSpaces replaces runs of white space with a single space
+func CollapseSpaces(input string) string {
+	output, space := make([]rune, 0, len(input)), false
+	for _, r := range input {
+		if unicode.IsSpace(r) {
+			if !space {
+				output = append(output, ' ')
+			}
+			space = true
+			continue
+		}
+		output = append(output, r)
+		space = false
+	}
+	return string(output)
+}
+
+// Normalize applies the normalizers to an input in order
+func Normalize(input string, normalizers ...Normalizer) string {
+	for _, normalizer := range normalizers {
+		input = normalizer(input)
+	}
+	return input
+}
+
+// offsetNormalizer is a normalizer that also returns the byte offset into its
+// input of each byte of its output, followed by the length of the input
+type offsetNormalizer func(input string) (string, []int)
+
+// offsetNormalizers are the offset forms of the normalizers of this package
+var offsetNormalizers = map[uintptr]offsetNormalizer{
+	reflect.ValueOf(URLDecode).Pointer(): func(input string) (string, []int) {
+		return repeatOffsets(input, urlDecodeOffsets)
+	},
+	reflect.ValueOf(HTMLUnescape).Pointer(): func(input string) (string, []int) {
+		return repeatOffsets(input, htmlUnescapeOffsets)
+	},
+	reflect.ValueOf(NFKC).Pointer(): nfkcOffsets,
+	reflect.ValueOf(Fullwidth).Pointer(): func(input string) (string, []int) {
+		return runeOffsets(input, Fullwidth)
+	},
+	reflect.ValueOf(StripComments).Pointer():  stripCommentsOffsets,
+	reflect.ValueOf(CollapseSpaces).Pointer(): collapseSpacesOffsets,
+}
+
+// normalizeOffsets is Normalize that also returns the byte offset into the
+// input of each byte of the output, followed by the length of the input. The
+// offsets of a normalizer that isn't from this package are aligned with
+// alignOffsets
+func normalizeOffsets(input string, normalizers ...Normalizer) (string, []int) {
+	offsets := identityOffsets(len(input))
+	for _, normalizer := range normalizers {
+		var (
+			output string
+			step   []int
+		)
+		if known, ok := offsetNormalizers[reflect.ValueOf(normalizer).Pointer()]; ok {
+			output, step = known(input)
+		} else {
+			output = normalizer(input)
+			step = alignOffsets(input, output)
+		}
+		input, offsets = output, composeOffsets(offsets, step)
+	}
+	return input, offsets
+}
+
+// identityOffsets returns the offsets of an unchanged input of length n
+func identityOffsets(n int) []int {
+	offsets := make([]int, n+1)
+	for i := range offsets {
+		offsets[i] = i
+	}
+	return offsets
+}
+
+// composeOffsets maps the offsets of a normalizer through the offsets of the
+// normalizers before it
+func composeOffsets(offsets, step []int) []int {
+	composed := make([]int, len(step))
+	for i, offset := range step {
+		composed[i] = offsets[offset]
+	}
+	return composed
+}
+
+// alignOffsets finds the offsets of a normalizer from its input and output:
+// the common prefix and suffix map byte for byte, and the rest of the output
+// maps byte for byte to where it is found in the rest of the input, or else
+// to the start of the rest of the input
+func alignOffsets(input, output string) []int {
+	prefix := 0
+	for prefix < len(input) && prefix < len(output) && input[prefix] == output[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(input)-prefix && suffix < len(output)-prefix &&
+		input[len(input)-1-suffix] == output[len(output)-1-suffix] {
+		suffix++
+	}
+	found := strings.Index(input[prefix:len(input)-suffix], output[prefix:len(output)-suffix])
+	offsets := make([]int, len(output)+1)
+	for i := range offsets {
+		switch {
+		case i < prefix:
+			offsets[i] = i
+		case i >= len(output)-suffix:
+			offsets[i] = len(input) - len(output) + i
+		case found >= 0:
+			offsets[i] = found + i
+		default:
+			offsets[i] = prefix
+		}
+	}
+	return offsets
+}
+
+// repeatOffsets applies a normalizer until the input stops changing, at most
+// maxDecodes times
+func repeatOffsets(input string, normalizer offsetNormalizer) (string, []int) {
+	offsets := identityOffsets(len(input))
+	for i := 0; i < maxDecodes; i++ {
+		decoded, step := normalizer(input)
+		if decoded == input {
+			break
+		}
+		input, offsets = decoded, composeOffsets(offsets, step)
+	}
+	return input, offsets
+}
+
+// runeOffsets applies a function to each rune of the input; the output of a
+// rune maps to the rune
+func runeOffsets(input string, f func(string) string) (string, []int) {
+	output, offsets := make([]byte, 0, len(input)), make([]int, 0, len(input)+1)
+	for i := 0; i < len(input); {
+		_, size := utf8.DecodeRuneInString(input[i:])
+		mapped := f(input[i : i+size])
+		for k := 0; k < len(mapped); k++ {
+			offsets = append(offsets, i)
+		}
+		output = append(output, mapped...)
+		i += size
+	}
+	return string(output), append(offsets, len(input))
+}
+
+func urlDecodeOffsets(input string) (string, []int) {
+	output, offsets := make([]byte, 0, len(input)), make([]int, 0, len(input)+1)
+	for i := 0; i < len(input); i++ {
+		offsets = append(offsets, i)
+		if input[i] == '%' && i+2 < len(input) {
+			a, okA := unhex(input[i+1])
+			b, okB := unhex(input[i+2])
+			if okA && okB {
+				output = append(output, a<<4|b)
+				i += 2
+				continue
+			}
+		}
+		output = append(output, input[i])
+	}
+	return string(output), append(offsets, len(input))
+}
+
+// entityEnd returns the end of the HTML entity that starts at i, as far as
+// html.UnescapeString reads it
+func entityEnd(input string, i int) int {
+	isDigit := func(c byte) bool {
+		return '0' <= c && c <= '9'
+	}
+	isHex := func(c byte) bool {
+		_, ok := unhex(c)
+		return ok
+	}
+	j := i + 1
+	if j < len(input) && input[j] == '#' {
+		j++
+		hex := j < len(input) && (input[j] == 'x' || input[j] == 'X')
+		if hex {
+			j++
+		}
+		for j < len(input) && (isDigit(input[j]) || hex && isHex(input[j])) {
+			j++
+		}
+	} else {
+		for j < len(input) && (isDigit(input[j]) || 'a' <= input[j]|0x20 && input[j]|0x20 <= 'z') {
+			j++
+		}
+	}
+	if j < len(input) && input[j] == ';' {
+		j++
+	}
+	return j
+}
+
+func htmlUnescapeOffsets(input string) (string, []int) {
+	output, offsets := make([]byte, 0, len(input)), make([]int, 0, len(input)+1)
+	for i := 0; i < len(input); {
+		if input[i] != '&' {
+			output, offsets = append(output, input[i]), append(offsets, i)
+			i++
+			continue
+		}
+		j := entityEnd(input, i)
+		entity := input[i:j]
+		decoded := html.UnescapeString(entity)
+		// the end of an entity that isn't decoded is kept as is
+		kept := 0
+		for kept < len(decoded) && kept < len(entity)-1 &&
+			decoded[len(decoded)-1-kept] == entity[len(entity)-1-kept] {
+			kept++
+		}
+		for k := 0; k < len(decoded); k++ {
+			if k < len(decoded)-kept {
+				offsets = append(offsets, i)
+			} else {
+				offsets = append(offsets, j-len(decoded)+k)
+			}
+		}
+		output = append(output, decoded...)
+		i = j
+	}
+	return string(output), append(offsets, len(input))
+}
+
+func nfkcOffsets(input string) (string, []int) {
+	output, offsets := make([]byte, 0, len(input)), make([]int, 0, len(input)+1)
+	var iter norm.Iter
+	iter.InitString(norm.NFKC, input)
+	for !iter.Done() {
+		start := iter.Pos()
+		segment := iter.Next()
+		same := string(segment) == input[start:iter.Pos()]
+		for k := range segment {
+			if same {
+				offsets = append(offsets, start+k)
+			} else {
+				offsets = append(offsets, start)
+			}
+		}
+		output = append(output, segment...)
+	}
+	return string(output), append(offsets, len(input))
+}
+
+func stripCommentsOffsets(input string) (string, []int) {
+	output, offsets := make([]byte, 0, len(input)), make([]int, 0, len(input)+1)
+	keep := func(start, end int) {
+		for k := start; k < end; k++ {
+			output, offsets = append(output, input[k]), append(offsets, k)
+		}
+	}
+	last := 0
+	for _, match := range comment.FindAllStringSubmatchIndex(input, -1) {
+		keep(last, match[0])
+		output, offsets = append(output, ' '), append(offsets, match[0])
+		if match[3] > match[2] {
+			keep(match[4], match[5])
+			output, offsets = append(output, ' '), append(offsets, match[5])
+		}
+		last = match[1]
+	}
+	keep(last, len(input))
+	return string(output), append(offsets, len(input))
+}
+
+func collapseSpacesOffsets(input string) (string, []int) {
+	output, offsets := make([]byte, 0, len(input)), make([]int, 0, len(input)+1)
+	space := false
+	for i, r := range input {
+		if unicode.IsSpace(r) {
+			if !space {
+				output, offsets = append(output, ' '), append(offsets, i)
+			}
+			space = true
+			continue
+		}
+		size := len(output)
+		output = utf8.AppendRune(output, r)
+		for size < len(output) {
+			offsets = append(offsets, i)
+			size++
+		}
+		space = false
+	}
+	return string(output), append(offsets, len(input))
+}
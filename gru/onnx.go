@@ -0,0 +1,198 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pointlander/injectsec/data"
+	"github.com/pointlander/injectsec/onnx"
+)
+
+const (
+	// ONNXOpset is the ONNX operator set version of exported models
+	ONNXOpset = 13
+	// ONNXInput is the name of the token input of exported models
+	ONNXInput = "tokens"
+	// ONNXOutput is the name of the probabilities output of exported models
+	ONNXOutput = "probabilities"
+	// ONNXCategories is the name of the category probabilities output of
+	// exported models with a category head
+	ONNXCategories = "categories"
+)
+
+// ONNX converts the model to an ONNX graph; the input is a sequence of int64
+// tokens as produced by Tokens, and the output are the probabilities of
+// attack and not attack with shape [1, 2]. Models with a category head have
+// a second output with the probabilities of the categories
+func (m *Model) ONNX() *onnx.Model {
+	graph := &onnx.Graph{
+		Name: "injectsec",
+		Doc:  "GRU SQL injection detector; see ONNX.md for the token vocabulary",
+		Inputs: []*onnx.ValueInfo{{
+			Name:     ONNXInput,
+			ElemType: onnx.DataTypeInt64,
+			Shape:    []onnx.Dim{{Param: "sequence"}},
+		}},
+		Outputs: []*onnx.ValueInfo{{
+			Name:     ONNXOutput,
+			ElemType: onnx.DataTypeFloat,
+			Shape:    []onnx.Dim{{Value: 1}, {Value: int64(m.outputSize)}},
+		}},
+	}
+	initializer := func(t *onnx.Tensor) string {
+		graph.Initializers = append(graph.Initializers, t)
+		return t.Name
+	}
+	node := func(op string, inputs []string, output string, attributes ...*onnx.Attribute) string {
+		graph.Nodes = append(graph.Nodes, &onnx.Node{
+			Name:       output,
+			OpType:     op,
+			Inputs:     inputs,
+			Outputs:    []string{output},
+			Attributes: attributes,
+		})
+		return output
+	}
+
+	// the product of the embedding weights and a one hot token is a column
+	// of the weights, so the embedding is a gather from their transpose
+	we := m.we.Data().([]float32)
+	embedding := make([]float32, len(we))
+	for i := 0; i < m.embeddingSize; i++ {
+		for j := 0; j < m.inputSize; j++ {
+			embedding[j*m.embeddingSize+i] = we[i*m.inputSize+j]
+		}
+	}
+	initializer(onnx.NewFloat("embedding", embedding, int64(m.inputSize), int64(m.embeddingSize)))
+	initializer(onnx.NewFloat("embedding_bias", copyFloats(m.be), int64(m.embeddingSize)))
+	embed := func(tokens, name string) string {
+		gathered := node("Gather", []string{"embedding", tokens}, name+"_gathered")
+		return node("Add", []string{gathered, "embedding_bias"}, name)
+	}
+	x := embed(ONNXInput, "embedded")
+	if m.inputs > 1 {
+		initializer(onnx.NewInt64("reverse_starts", []int64{-1}, 1))
+		initializer(onnx.NewInt64("reverse_ends", []int64{math.MinInt64}, 1))
+		initializer(onnx.NewInt64("reverse_axes", []int64{0}, 1))
+		initializer(onnx.NewInt64("reverse_steps", []int64{-1}, 1))
+		reversed := node("Slice", []string{ONNXInput, "reverse_starts", "reverse_ends", "reverse_axes", "reverse_steps"}, "reversed")
+		x = node("Concat", []string{x, embed(reversed, "embedded_reversed")}, "embedded_both", onnx.IntAttribute("axis", 1))
+	}
+	initializer(onnx.NewInt64("input_shape", []int64{-1, 1, int64(m.inputs * m.embeddingSize)}, 3))
+	x = node("Reshape", []string{x, "input_shape"}, "input")
+
+	// the reset and update gates of the ONNX GRU are both the single gate of
+	// this GRU: f = z = r
+	var hidden string
+	for i, l := range m.layers {
+		name, size := strconv.Itoa(i), m.layerSizes[i]
+		wf, uf, bf := l.wf.Data().([]float32), l.uf.Data().([]float32), l.bf.Data().([]float32)
+		wh, uh, bh := l.wh.Data().([]float32), l.uh.Data().([]float32), l.bh.Data().([]float32)
+		w := append(append(append([]float32(nil), wf...), wf...), wh...)
+		r := append(append(append([]float32(nil), uf...), uf...), uh...)
+		b := append(append(append([]float32(nil), bf...), bf...), bh...)
+		b = append(b, make([]float32, 3*size)...)
+		previous := len(wf) / size
+		initializer(onnx.NewFloat("w_"+name, w, 1, int64(3*size), int64(previous)))
+		initializer(onnx.NewFloat("r_"+name, r, 1, int64(3*size), int64(size)))
+		initializer(onnx.NewFloat("b_"+name, b, 1, int64(6*size)))
+		graph.Nodes = append(graph.Nodes, &onnx.Node{
+			Name:       "gru_" + name,
+			OpType:     "GRU",
+			Inputs:     []string{x, "w_" + name, "r_" + name, "b_" + name},
+			Outputs:    []string{"y_" + name, "h_" + name},
+			Attributes: []*onnx.Attribute{onnx.IntAttribute("hidden_size", int64(size))},
+		})
+		hidden = "h_" + name
+		if i < len(m.layers)-1 {
+			initializer(onnx.NewInt64("y_shape_"+name, []int64{-1, 1, int64(size)}, 3))
+			x = node("Reshape", []string{"y_" + name, "y_shape_" + name}, "input_"+strconv.Itoa(i+1))
+		}
+	}
+
+	last := m.layerSizes[len(m.layerSizes)-1]
+	initializer(onnx.NewInt64("hidden_shape", []int64{1, int64(last)}, 2))
+	hidden = node("Reshape", []string{hidden, "hidden_shape"}, "hidden")
+	initializer(onnx.NewFloat("output_weights", copyFloats(m.wo), int64(m.outputSize), int64(last)))
+	initializer(onnx.NewFloat("output_bias", copyFloats(m.bo), int64(m.outputSize)))
+	logits := node("Gemm", []string{hidden, "output_weights", "output_bias"}, "logits", onnx.IntAttribute("transB", 1))
+	node("Softmax", []string{logits}, ONNXOutput, onnx.IntAttribute("axis", 1))
+
+	metadata := map[string]string{
+		"injectsec.tokenizer":    m.tokenizer.Kind(),
+		"injectsec.attack_index": "0",
+	}
+	if m.categories > 0 {
+		graph.Outputs = append(graph.Outputs, &onnx.ValueInfo{
+			Name:     ONNXCategories,
+			ElemType: onnx.DataTypeFloat,
+			Shape:    []onnx.Dim{{Value: 1}, {Value: int64(m.categories)}},
+		})
+		initializer(onnx.NewFloat("category_weights", copyFloats(m.wc), int64(m.categories), int64(last)))
+		initializer(onnx.NewFloat("category_bias", copyFloats(m.bc), int64(m.categories)))
+		logits := node("Gemm", []string{hidden, "category_weights", "category_bias"}, "category_logits", onnx.IntAttribute("transB", 1))
+		node("Softmax", []string{logits}, ONNXCategories, onnx.IntAttribute("axis", 1))
+
+		names := make([]string, m.categories)
+		for i := range names {
+			names[i] = data.Category(i + 1).String()
+		}
+		categories, err := json.Marshal(names)
+		if err != nil {
+			panic(err)
+		}
+		metadata["injectsec.categories"] = string(categories)
+	}
+
+	chunks, err := json.Marshal(m.tokenizer.Vocabulary())
+	if err != nil {
+		panic(err)
+	}
+	metadata["injectsec.chunks"] = string(chunks)
+	return &onnx.Model{
+		IRVersion:    onnx.IRVersion,
+		ProducerName: "injectsec",
+		Opsets:       []onnx.Opset{{Version: ONNXOpset}},
+		Graph:        graph,
+		Metadata:     metadata,
+	}
+}
+
+// copyFloats copies the data of a tensor
+func copyFloats(t interface{ Data() interface{} }) []float32 {
+	return append([]float32(nil), t.Data().([]float32)...)
+}
+
+// WriteONNX writes the model as an ONNX graph
+func (m *Model) WriteONNX(out io.Writer) error {
+	return m.ONNX().Write(out)
+}
+
+// WriteONNXFile writes the model as an ONNX graph to a file
+func (m *Model) WriteONNXFile(file string) error {
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return m.WriteONNX(out)
+}
+
+// Tokens converts an input to the tokens the exported ONNX model expects;
+// the input is lowercased the same way the detector lowercases it
+func (m *Model) Tokens(input string) []int64 {
+	tokens := convert(m.tokenizer, []byte(strings.ToLower(input)))
+	converted := make([]int64, len(tokens))
+	for i, token := range tokens {
+		converted[i] = int64(token)
+	}
+	return converted
+}
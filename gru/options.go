@@ -0,0 +1,89 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"fmt"
+
+	"github.com/pointlander/injectsec/data"
+)
+
+// Options are the architecture and training options for a GRU model
+type Options struct {
+	// EmbeddingSize is the size of the token embedding
+	EmbeddingSize int
+	// HiddenSizes are the sizes of the stacked hidden layers
+	HiddenSizes []int
+	// Bidirectional feeds the reversed input along with the input
+	Bidirectional bool
+	// Categories is the number of outputs of the category head; output i is
+	// data.Category(i+1), and there is no head if it is zero
+	Categories int
+	// Steps is the number of unrolled learn steps
+	Steps int
+	// LearnRate is the learning rate of the solver
+	LearnRate float64
+	// L2Reg is the L2 regularization of the solver
+	L2Reg float64
+	// ClipVal is the gradient clip value of the solver
+	ClipVal float64
+	// Tokenizer converts the input into tokens; DefaultTokenizer is used if it
+	// is nil. It is saved with the weights
+	Tokenizer Tokenizer `json:"-"`
+}
+
+// DefaultOptions returns the options used to train the embedded weights
+func DefaultOptions() Options {
+	return Options{
+		EmbeddingSize: embeddingSize,
+		HiddenSizes:   []int{hiddenSize},
+		Bidirectional: true,
+		Steps:         3,
+		LearnRate:     0.001,
+		L2Reg:         0.000001,
+		ClipVal:       5.0,
+	}
+}
+
+// Inputs returns the number of inputs to the model
+func (o Options) Inputs() int {
+	if o.Bidirectional {
+		return 2
+	}
+	return 1
+}
+
+// tokenizer returns the tokenizer of the options
+func (o Options) tokenizer() Tokenizer {
+	if o.Tokenizer == nil {
+		return DefaultTokenizer
+	}
+	return o.Tokenizer
+}
+
+// Validate checks that the options are usable
+func (o Options) Validate() error {
+	if o.EmbeddingSize < 1 {
+		return fmt.Errorf("invalid embedding size %d", o.EmbeddingSize)
+	}
+	if len(o.HiddenSizes) == 0 {
+		return fmt.Errorf("at least one hidden layer is required")
+	}
+	for _, size := range o.HiddenSizes {
+		if size < 1 {
+			return fmt.Errorf("invalid hidden size %d", size)
+		}
+	}
+	if o.Categories < 0 || o.Categories >= int(data.Categories) {
+		return fmt.Errorf("invalid number of categories %d", o.Categories)
+	}
+	if o.Steps < 1 {
+		return fmt.Errorf("invalid number of steps %d", o.Steps)
+	}
+	if o.LearnRate <= 0 {
+		return fmt.Errorf("invalid learn rate %v", o.LearnRate)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/pointlander/injectsec/data"
+	G "gorgonia.org/gorgonia"
+)
+
+// accumulator is a solver that sums the gradients instead of applying them;
+// the gradients of the unrolled windows of an example are averaged by
+// finish, so each example adds its mean gradient to the sums whatever its length
+type accumulator struct {
+	grads [][]float32
+	// example is the sum of the gradients of the windows of the current example
+	example [][]float32
+	windows int
+	// examples is the number of examples in the sums
+	examples int
+}
+
+// Step implements G.Solver
+func (a *accumulator) Step(nodes G.Nodes) error {
+	if a.example == nil {
+		a.example = make([][]float32, len(nodes))
+	}
+	for i, node := range nodes {
+		g, err := node.Grad()
+		if err != nil {
+			return err
+		}
+		grad, ok := g.Data().([]float32)
+		if !ok {
+			return fmt.Errorf("node %d has an invalid gradient", i)
+		}
+		if a.example[i] == nil {
+			a.example[i] = make([]float32, len(grad))
+		}
+		sum := a.example[i]
+		for j, d := range grad {
+			sum[j] += d
+			grad[j] = 0
+		}
+	}
+	a.windows++
+	return nil
+}
+
+// finish adds the mean gradient of the windows of the current example to
+// the sums; examples without any windows are skipped
+func (a *accumulator) finish() {
+	if a.windows == 0 {
+		return
+	}
+	if a.grads == nil {
+		a.grads = make([][]float32, len(a.example))
+	}
+	scale := 1 / float32(a.windows)
+	for i, example := range a.example {
+		if a.grads[i] == nil {
+			a.grads[i] = make([]float32, len(example))
+		}
+		sum := a.grads[i]
+		for j, d := range example {
+			sum[j] += scale * d
+			example[j] = 0
+		}
+	}
+	a.windows = 0
+	a.examples++
+}
+
+// reset zeroes the sums
+func (a *accumulator) reset() {
+	for _, grads := range [][][]float32{a.grads, a.example} {
+		for _, grad := range grads {
+			for i := range grad {
+				grad[i] = 0
+			}
+		}
+	}
+	a.windows, a.examples = 0, 0
+}
+
+// clone makes a copy of the model with its own weights
+func (m *Model) clone() *Model {
+	rnd := rand.New(rand.NewSource(1))
+	model := newModel(rnd, m.inputs, m.tokenizer, m.embeddingSize, m.outputSize, m.layerSizes)
+	model.options = m.options
+	model.addCategories(rnd, m.categories)
+	model.copyWeights(m)
+	return model
+}
+
+// copyWeights copies the weights of another model with the same architecture
+func (m *Model) copyWeights(from *Model) {
+	source := from.tensors()
+	for i, t := range m.tensors() {
+		copy(t.Data().([]float32), source[i].Data().([]float32))
+	}
+}
+
+// replica is a copy of the model that computes the gradients for a shard of a mini batch
+type replica struct {
+	model   *Model
+	learner []*RNN
+	accumulator
+}
+
+// Parallel trains a GRU with data parallel replicas of its model; each
+// mini batch is split across the replicas, and the mean gradients of the
+// examples are averaged over the batch and applied to the shared model by
+// the solver of the GRU, so the update doesn't depend on how the examples
+// fall across the replicas
+type Parallel struct {
+	*GRU
+	replicas []*replica
+}
+
+// NewParallel creates a data parallel trainer with a replica for each worker
+func NewParallel(g *GRU, workers int) *Parallel {
+	if workers < 1 {
+		panic(fmt.Errorf("invalid number of workers %d", workers))
+	}
+	replicas := make([]*replica, workers)
+	for i := range replicas {
+		model := g.Model.clone()
+		learner := make([]*RNN, g.steps)
+		for j := range learner {
+			learner[j] = NewRNN(model)
+			err := learner[j].ModeLearn(j + 1)
+			if err != nil {
+				panic(err)
+			}
+		}
+		replicas[i] = &replica{
+			model:   model,
+			learner: learner,
+		}
+	}
+	return &Parallel{
+		GRU:      g,
+		replicas: replicas,
+	}
+}
+
+// TrainBatch trains on a mini batch of examples with one solver step, and
+// returns the average cost
+func (p *Parallel) TrainBatch(inputs [][]byte, attacks []bool) float32 {
+	return p.TrainBatchCategories(inputs, attacks, nil)
+}
+
+// TrainBatchCategories is TrainBatch that also trains the category head on
+// the categories of the attacks; categories may be nil
+func (p *Parallel) TrainBatchCategories(inputs [][]byte, attacks []bool, categories []data.Category) float32 {
+	if len(inputs) == 0 {
+		return 0
+	}
+	workers := len(p.replicas)
+	if len(inputs) < workers {
+		workers = len(inputs)
+	}
+	costs := make([]float32, workers)
+	var wait sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			r := p.replicas[i]
+			r.model.copyWeights(p.Model)
+			r.reset()
+			for j := i; j < len(inputs); j += workers {
+				costs[i] += train(r.learner, inputs[j], attacks[j], category(categories, j), p.ClassWeight(attacks[j]), &r.accumulator)
+				r.finish()
+			}
+		}(i)
+	}
+	wait.Wait()
+
+	accumulators := make([]*accumulator, workers)
+	for i, r := range p.replicas[:workers] {
+		accumulators[i] = &r.accumulator
+	}
+	grads, examples := combine(accumulators)
+	if examples == 0 {
+		return 0
+	}
+	p.step(grads, examples)
+
+	total := float32(0)
+	for _, cost := range costs {
+		total += cost
+	}
+	return total / float32(examples)
+}
+
+// combine adds the sums of the accumulators to the sums of the first one with
+// examples; it returns the sums and the total number of examples
+func combine(accumulators []*accumulator) ([][]float32, int) {
+	var grads [][]float32
+	examples := 0
+	for _, a := range accumulators {
+		if a.examples == 0 {
+			continue
+		}
+		if grads == nil {
+			grads = a.grads
+		} else {
+			for i, grad := range a.grads {
+				for j, d := range grad {
+					grads[i][j] += d
+				}
+			}
+		}
+		examples += a.examples
+	}
+	return grads, examples
+}
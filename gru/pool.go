@@ -0,0 +1,243 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pointlander/injectsec/lexer"
+)
+
+var (
+	// ErrorPoolClosed means the detector pool has been closed
+	ErrorPoolClosed = errors.New("detector pool is closed")
+)
+
+// PoolStats are statistics for a detector pool
+type PoolStats struct {
+	// Size is the maximum number of detectors
+	Size int
+	// Created is the number of detectors that have been made
+	Created int
+	// Idle is the number of detectors waiting to be used
+	Idle int
+	// InUse is the number of detectors currently checked out
+	InUse int
+	// Gets is the number of detectors handed out
+	Gets uint64
+	// Waits is the number of gets that had to wait for a detector
+	Waits uint64
+	// WaitTime is the total time spent waiting for detectors
+	WaitTime time.Duration
+	// MaxWaitTime is the longest time spent waiting for a detector
+	MaxWaitTime time.Duration
+}
+
+// DetectorPool is a bounded set of detectors that share a model and can
+// be used from multiple goroutines
+type DetectorPool struct {
+	maker *DetectorMaker
+	idle  chan *Detector
+	done  chan struct{}
+
+	// Setup is called on each detector after it is made
+	Setup func(d *Detector)
+
+	mu     sync.Mutex
+	closed bool
+	stats  PoolStats
+	// out are the detectors that are checked out
+	out map[*Detector]bool
+}
+
+// NewPool creates a pool of at most size detectors; detectors are made on demand
+func (d *DetectorMaker) NewPool(size int) *DetectorPool {
+	if size < 1 {
+		size = 1
+	}
+	return &DetectorPool{
+		maker: d,
+		idle:  make(chan *Detector, size),
+		done:  make(chan struct{}),
+		out:   make(map[*Detector]bool),
+		stats: PoolStats{
+			Size: size,
+		},
+	}
+}
+
+// Get gets a detector from the pool, waiting until one is available, the
+// context is done or the pool is closed
+func (p *DetectorPool) Get(ctx context.Context) (*Detector, error) {
+	select {
+	case <-p.done:
+		return nil, ErrorPoolClosed
+	default:
+	}
+
+	select {
+	case detector := <-p.idle:
+		p.mu.Lock()
+		p.stats.Gets++
+		p.out[detector] = true
+		p.mu.Unlock()
+		return detector, nil
+	default:
+	}
+
+	p.mu.Lock()
+	if p.stats.Created < p.stats.Size {
+		p.stats.Created++
+		p.stats.Gets++
+		p.mu.Unlock()
+		detector := p.maker.Make()
+		if p.Setup != nil {
+			p.Setup(detector)
+		}
+		p.mu.Lock()
+		p.out[detector] = true
+		p.mu.Unlock()
+		return detector, nil
+	}
+	p.stats.Waits++
+	p.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case detector := <-p.idle:
+		wait := time.Since(start)
+		p.mu.Lock()
+		p.stats.Gets++
+		p.out[detector] = true
+		p.stats.WaitTime += wait
+		if wait > p.stats.MaxWaitTime {
+			p.stats.MaxWaitTime = wait
+		}
+		p.mu.Unlock()
+		return detector, nil
+	case <-ctx.Done():
+		p.waited(start)
+		return nil, ctx.Err()
+	case <-p.done:
+		p.waited(start)
+		return nil, ErrorPoolClosed
+	}
+}
+
+// waited adds the time spent waiting since start to the statistics
+func (p *DetectorPool) waited(start time.Time) {
+	wait := time.Since(start)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.WaitTime += wait
+	if wait > p.stats.MaxWaitTime {
+		p.stats.MaxWaitTime = wait
+	}
+}
+
+// Put returns a detector to the pool; detectors returned after the pool is
+// closed are dropped, and detectors that weren't checked out of the pool,
+// or were already returned, are ignored
+func (p *DetectorPool) Put(detector *Detector) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.out[detector] {
+		return
+	}
+	delete(p.out, detector)
+	if p.closed {
+		p.stats.Created--
+		return
+	}
+	select {
+	case p.idle <- detector:
+	default:
+		p.stats.Created--
+	}
+}
+
+// Detect gets a detector from the pool, runs it on the input, and returns it to the pool
+func (p *DetectorPool) Detect(ctx context.Context, a string) (float32, error) {
+	detector, err := p.Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer p.Put(detector)
+	return detector.Detect(a)
+}
+
+// DetectDetailed is Detect with a detailed result
+func (p *DetectorPool) DetectDetailed(ctx context.Context, a string) (Result, error) {
+	detector, err := p.Get(ctx)
+	if err != nil {
+		return Result{Generator: -1}, err
+	}
+	defer p.Put(detector)
+	return detector.DetectDetailed(a)
+}
+
+// DetectContext is DetectDetailed with a hint about the context of the input
+func (p *DetectorPool) DetectContext(ctx context.Context, a string, hint lexer.Context) (Result, error) {
+	detector, err := p.Get(ctx)
+	if err != nil {
+		return Result{Generator: -1}, err
+	}
+	defer p.Put(detector)
+	return detector.DetectContext(a, hint)
+}
+
+// Explain gets a detector from the pool and explains its decision, see Detector.Explain
+func (p *DetectorPool) Explain(ctx context.Context, a string) (Explanation, error) {
+	detector, err := p.Get(ctx)
+	if err != nil {
+		return Explanation{Result: Result{Generator: -1}}, err
+	}
+	defer p.Put(detector)
+	return detector.Explain(a)
+}
+
+// DetectBatch is Detect for a batch of inputs
+func (p *DetectorPool) DetectBatch(ctx context.Context, inputs []string) ([]float32, error) {
+	detector, err := p.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Put(detector)
+	return detector.DetectBatch(inputs)
+}
+
+// Stats returns the statistics for the pool
+func (p *DetectorPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	stats.Idle = len(p.idle)
+	stats.InUse = stats.Created - stats.Idle
+	return stats
+}
+
+// Close closes the pool and drops the idle detectors; gets that are waiting
+// for a detector fail with ErrorPoolClosed, and detectors that are checked
+// out can still be returned
+func (p *DetectorPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.done)
+	for {
+		select {
+		case <-p.idle:
+			p.stats.Created--
+		default:
+			return
+		}
+	}
+}
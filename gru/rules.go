@@ -0,0 +1,458 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pointlander/injectsec/data"
+	"gopkg.in/yaml.v2"
+)
+
+// RuleAction is what a rule does when it matches
+type RuleAction int
+
+const (
+	// RuleNone is not a valid action
+	RuleNone RuleAction = iota
+	// RuleAllow means the input isn't an attack
+	RuleAllow
+	// RuleDeny means the input is an attack
+	RuleDeny
+	// RuleAdjust adds the score of the rule to the attack probability
+	RuleAdjust
+)
+
+var ruleActionNames = [...]string{
+	RuleNone:   "none",
+	RuleAllow:  "allow",
+	RuleDeny:   "deny",
+	RuleAdjust: "adjust",
+}
+
+// String returns the name of the action
+func (a RuleAction) String() string {
+	if a < 0 || int(a) >= len(ruleActionNames) {
+		return "unknown"
+	}
+	return ruleActionNames[a]
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (a RuleAction) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (a *RuleAction) UnmarshalText(text []byte) error {
+	for i, name := range ruleActionNames[RuleAllow:] {
+		if name == string(text) {
+			*a = RuleAllow + RuleAction(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown rule action %q", text)
+}
+
+// RulePart is a part of a data.Parts grammar in a rules file
+type RulePart struct {
+	// Type is the name of the part type, see data.ParsePartType; the default is a literal
+	Type     string     `json:"type,omitempty" yaml:"type,omitempty"`
+	Literal  string     `json:"literal,omitempty" yaml:"literal,omitempty"`
+	Variable int        `json:"variable,omitempty" yaml:"variable,omitempty"`
+	Max      int        `json:"max,omitempty" yaml:"max,omitempty"`
+	Parts    []RulePart `json:"parts,omitempty" yaml:"parts,omitempty"`
+}
+
+// Rule is an allow rule, a deny rule or a score adjustment; a rule has one
+// of Regex, Literal, Grammar or Parts. Rules match the lower cased input
+// after normalization
+type Rule struct {
+	// Name is reported when the rule matches
+	Name   string     `json:"name" yaml:"name"`
+	Action RuleAction `json:"action" yaml:"action"`
+	// Score is added to the attack probability by RuleAdjust
+	Score float32 `json:"score,omitempty" yaml:"score,omitempty"`
+	// Regex matches anywhere in the input unless it is anchored
+	Regex string `json:"regex,omitempty" yaml:"regex,omitempty"`
+	// Literal matches anywhere in the input
+	Literal string `json:"literal,omitempty" yaml:"literal,omitempty"`
+	// Grammar and Parts are a data.Parts grammar that matches the whole
+	// input; Parts can only be set in code
+	Grammar []RulePart        `json:"grammar,omitempty" yaml:"grammar,omitempty"`
+	Parts   func(*data.Parts) `json:"-" yaml:"-"`
+}
+
+// RuleMatch is a rule that matched an input
+type RuleMatch struct {
+	Name   string
+	Action RuleAction
+	Score  float32 `json:",omitempty"`
+}
+
+// compiledRule is a rule with its matcher
+type compiledRule struct {
+	Rule
+	literal string
+	regex   *regexp.Regexp
+}
+
+// parts converts a grammar to data.Parts
+func parts(grammar []RulePart, p *data.Parts) error {
+	for _, part := range grammar {
+		partType := data.PartTypeLiteral
+		if part.Type != "" {
+			var err error
+			partType, err = data.ParsePartType(part.Type)
+			if err != nil {
+				return err
+			}
+		}
+		converted := data.Part{
+			PartType: partType,
+			Variable: part.Variable,
+			Literal:  part.Literal,
+			Max:      part.Max,
+		}
+		if part.Parts != nil {
+			converted.Parts = data.NewParts()
+			err := parts(part.Parts, converted.Parts)
+			if err != nil {
+				return err
+			}
+		}
+		p.Parts = append(p.Parts, converted)
+	}
+	return nil
+}
+
+// compile checks and compiles a rule
+func (r Rule) compile() (*compiledRule, error) {
+	if r.Action <= RuleNone || r.Action > RuleAdjust {
+		return nil, fmt.Errorf("rule %q has an invalid action %d", r.Name, r.Action)
+	}
+	if r.Score != 0 && r.Action != RuleAdjust {
+		return nil, fmt.Errorf("rule %q has a score but isn't an adjust rule", r.Name)
+	}
+	count := 0
+	for _, set := range []bool{r.Regex != "", r.Literal != "", r.Grammar != nil, r.Parts != nil} {
+		if set {
+			count++
+		}
+	}
+	if count != 1 {
+		return nil, fmt.Errorf("rule %q needs exactly one of regex, literal, grammar or parts", r.Name)
+	}
+
+	compiled := &compiledRule{
+		Rule: r,
+	}
+	expression := r.Regex
+	switch {
+	case r.Literal != "":
+		compiled.literal = strings.ToLower(r.Literal)
+		return compiled, nil
+	case r.Grammar != nil, r.Parts != nil:
+		p := data.NewParts()
+		if r.Parts != nil {
+			r.Parts(p)
+		} else if err := parts(r.Grammar, p); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		fragment, err := p.RegexFragment()
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		expression = "^(" + fragment + ")$"
+	}
+	regex, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+	}
+	compiled.regex = regex
+	return compiled, nil
+}
+
+// match returns true if the rule matches the lower cased input
+func (r *compiledRule) match(input string) bool {
+	if r.regex != nil {
+		return r.regex.MatchString(input)
+	}
+	return strings.Contains(input, r.literal)
+}
+
+// Rules are a set of rules that can be replaced while they are in use; a
+// detector with rules applies them before the regex filters and the neural
+// network. The first allow or deny rule that matches decides the result, and
+// the scores of all of the adjust rules that match are added to the attack
+// probability. Rules are safe for concurrent use
+type Rules struct {
+	mu    sync.RWMutex
+	rules []*compiledRule
+}
+
+// NewRules creates a set of rules
+func NewRules(rules ...Rule) (*Rules, error) {
+	r := &Rules{}
+	err := r.Set(rules...)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GeneratorRules returns a deny rule with the parts of each generator that
+// has a regex and isn't SkipMatch, so that generators loaded with
+// data.LoadGenerators match inputs like the built in regex filters do. The
+// rules are named after the forms of the generators
+func GeneratorRules(generators []data.Generator) []Rule {
+	var rules []Rule
+	for _, generator := range generators {
+		if generator.SkipMatch || generator.Regex == nil {
+			continue
+		}
+		rules = append(rules, Rule{
+			Name:   generator.Form,
+			Action: RuleDeny,
+			Parts:  generator.Regex,
+		})
+	}
+	return rules
+}
+
+// Set replaces the rules; the rules are unchanged if any of them is invalid
+func (r *Rules) Set(rules ...Rule) error {
+	compiled := make([]*compiledRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i)
+		}
+		c, err := rule.compile()
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, c)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = compiled
+	return nil
+}
+
+// Rules returns a copy of the rules
+func (r *Rules) Rules() []Rule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rules := make([]Rule, len(r.rules))
+	for i, rule := range r.rules {
+		rules[i] = rule.Rule
+	}
+	return rules
+}
+
+// Match returns the rules that match the normalized input
+func (r *Rules) Match(input string) []RuleMatch {
+	input = strings.ToLower(input)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var matches []RuleMatch
+	for _, rule := range r.rules {
+		if rule.match(input) {
+			matches = append(matches, RuleMatch{
+				Name:   rule.Name,
+				Action: rule.Action,
+				Score:  rule.Score,
+			})
+		}
+	}
+	return matches
+}
+
+// Rules file formats
+const (
+	// RulesJSON is a JSON rules file
+	RulesJSON = "json"
+	// RulesYAML is a YAML rules file
+	RulesYAML = "yaml"
+)
+
+// rulesFile is the layout of a rules file
+type rulesFile struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// ReadRules reads the rules of a JSON or YAML rules file
+func ReadRules(in io.Reader, format string) ([]Rule, error) {
+	contents, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	var file rulesFile
+	switch format {
+	case RulesJSON:
+		decoder := json.NewDecoder(bytes.NewReader(contents))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&file)
+	case RulesYAML:
+		err = yaml.UnmarshalStrict(contents, &file)
+	default:
+		err = fmt.Errorf("unknown rules format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file.Rules, nil
+}
+
+// WriteRules writes rules as a JSON or YAML rules file; rules with Parts can't be written
+func WriteRules(out io.Writer, format string, rules []Rule) error {
+	for _, rule := range rules {
+		if rule.Parts != nil {
+			return fmt.Errorf("rule %q has parts, which can only be set in code", rule.Name)
+		}
+	}
+	file := rulesFile{
+		Rules: rules,
+	}
+	switch format {
+	case RulesJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(file)
+	case RulesYAML:
+		contents, err := yaml.Marshal(file)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(contents)
+		return err
+	}
+	return fmt.Errorf("unknown rules format %q", format)
+}
+
+// RulesFormat returns the format of a rules file from its extension; files
+// that don't end in .json are YAML
+func RulesFormat(path string) string {
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return RulesJSON
+	}
+	return RulesYAML
+}
+
+// LoadFile replaces the rules with the rules of a JSON or YAML file
+func (r *Rules) LoadFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	rules, err := ReadRules(in, RulesFormat(path))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return r.Set(rules...)
+}
+
+var (
+	// ErrorInterval means the interval of Watch isn't positive
+	ErrorInterval = errors.New("rules watch interval must be positive")
+)
+
+// Watch loads the rules file and then reloads it whenever its modification
+// time or size changes, until the context is done; the file is checked every
+// interval. Errors are passed to report, which may be nil, and the previous
+// rules are kept; a file that fails to load is retried every interval until
+// it loads, and an error is only reported again if it changes. Watch returns
+// ErrorInterval if the interval isn't positive, and the error of the context
+// when it is done
+func (r *Rules) Watch(ctx context.Context, path string, interval time.Duration, report func(error)) error {
+	if interval <= 0 {
+		return ErrorInterval
+	}
+	var (
+		modified time.Time
+		size     = int64(-1)
+		reported string
+	)
+	load := func() {
+		info, err := os.Stat(path)
+		if err == nil && info.ModTime().Equal(modified) && info.Size() == size {
+			return
+		}
+		if err == nil {
+			err = r.LoadFile(path)
+		}
+		if err == nil {
+			modified, size, reported = info.ModTime(), info.Size(), ""
+			return
+		}
+		if report != nil && err.Error() != reported {
+			report(err)
+		}
+		reported = err.Error()
+	}
+	load()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		load()
+	}
+}
+
+// apply applies the rules that matched to a result; it returns true if an
+// allow or deny rule decided the result
+func (result *Result) apply(matches []RuleMatch) bool {
+	result.Rules = matches
+	for _, match := range matches {
+		switch match.Action {
+		case RuleAllow:
+			result.Probability, result.Source = 0, SourceRule
+			return true
+		case RuleDeny:
+			result.Probability, result.Source = 100, SourceRule
+			return true
+		}
+	}
+	return false
+}
+
+// adjust adds the scores of the adjust rules that matched to the attack probability
+func (result *Result) adjust() {
+	if result.Source == SourceRule {
+		return
+	}
+	adjusted := false
+	for _, match := range result.Rules {
+		if match.Action == RuleAdjust {
+			result.Probability += match.Score
+			adjusted = true
+		}
+	}
+	if !adjusted {
+		return
+	}
+	if result.Probability < 0 {
+		result.Probability = 0
+	} else if result.Probability > 100 {
+		result.Probability = 100
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// DefaultWindow is the default number of tokens in a scanner window
+	DefaultWindow = 64
+	// DefaultStride is the default number of tokens between scanner windows
+	DefaultStride = 16
+)
+
+// Scanner scans long inputs for SQL injection attacks using sliding windows
+// of tokens; only the current window is kept in memory
+type Scanner struct {
+	*Engine
+	// Window is the number of tokens in a window
+	Window int
+	// Stride is the number of tokens between the starts of windows
+	Stride int
+}
+
+// ScanResult is the result of a scan
+type ScanResult struct {
+	// Probability is the peak attack probability over all windows, after the
+	// calibration of the model
+	Probability float32
+	// Offset is the byte offset of the window with the peak probability
+	Offset int64
+	// Length is the length in bytes of the window with the peak probability
+	Length int64
+	// Windows is the number of windows scanned
+	Windows int
+	// Bytes is the number of bytes scanned
+	Bytes int64
+}
+
+// NewScanner creates a new scanner
+func NewScanner(engine *Engine, window, stride int) *Scanner {
+	return &Scanner{
+		Engine: engine,
+		Window: window,
+		Stride: stride,
+	}
+}
+
+// ScanString scans a string
+func (s *Scanner) ScanString(a string) (ScanResult, error) {
+	return s.Scan(strings.NewReader(a))
+}
+
+// Scan scans the input from a reader; ASCII letters are lower cased
+func (s *Scanner) Scan(in io.Reader) (ScanResult, error) {
+	window, stride := s.Window, s.Stride
+	if window < 1 {
+		window = DefaultWindow
+	}
+	if stride < 1 {
+		stride = DefaultStride
+	}
+	if stride > window {
+		return ScanResult{}, fmt.Errorf("stride %d is larger than window %d", stride, window)
+	}
+
+	var result ScanResult
+	tokens := make([]int, 0, window)
+	starts, ends := make([]int64, 0, window), make([]int64, 0, window)
+	fresh := 0
+	evaluate := func() error {
+		probability, err := s.AttackProbability(tokens)
+		if err != nil {
+			return err
+		}
+		probability = s.calibration.Apply(probability)
+		if result.Windows == 0 || probability > result.Probability {
+			result.Probability = probability
+			result.Offset = starts[0]
+			result.Length = ends[len(ends)-1] - starts[0]
+		}
+		result.Windows++
+		fresh = 0
+		return nil
+	}
+	add := func(token int, start, end int64) error {
+		tokens, starts, ends = append(tokens, token), append(starts, start), append(ends, end)
+		fresh++
+		if len(tokens) < window {
+			return nil
+		}
+		err := evaluate()
+		if err != nil {
+			return err
+		}
+		tokens = append(tokens[:0], tokens[stride:]...)
+		starts = append(starts[:0], starts[stride:]...)
+		ends = append(ends[:0], ends[stride:]...)
+		return nil
+	}
+
+	buffer, pending, offset, eof := make([]byte, 4096), make([]byte, 0, 4096+s.tokenizer.MaxLength()), int64(0), false
+	for !eof {
+		n, err := in.Read(buffer)
+		if err == io.EOF {
+			eof = true
+		} else if err != nil {
+			return result, err
+		}
+		for _, c := range buffer[:n] {
+			if 'A' <= c && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			pending = append(pending, c)
+		}
+		result.Bytes += int64(n)
+
+		i := 0
+		for i < len(pending) && (eof || len(pending)-i >= s.tokenizer.MaxLength()) {
+			token, size := s.tokenizer.Match(pending, i)
+			err := add(token, offset, offset+int64(size))
+			if err != nil {
+				return result, err
+			}
+			i += size
+			offset += int64(size)
+		}
+		pending = append(pending[:0], pending[i:]...)
+	}
+
+	if fresh > 0 && len(tokens) > 0 {
+		err := evaluate()
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
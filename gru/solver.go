@@ -0,0 +1,175 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math"
+	"os"
+
+	G "gorgonia.org/gorgonia"
+)
+
+// RMSProp is an RMSProp solver that takes the same steps as the solver of
+// G.NewRMSPropSolver; unlike the gorgonia solver its state is exported so
+// that training can be resumed
+type RMSProp struct {
+	LearnRate float64
+	Decay     float64
+	Epsilon   float64
+	L2Reg     float64
+	ClipVal   float64
+	// Cache is the moving average of the squared gradient of each learnable;
+	// like the gorgonia solver, epsilon is added to it every step
+	Cache [][]float32
+}
+
+// NewRMSProp creates a new RMSProp solver from the training options
+func NewRMSProp(options Options) *RMSProp {
+	return &RMSProp{
+		LearnRate: options.LearnRate,
+		Decay:     0.999,
+		Epsilon:   1e-8,
+		L2Reg:     options.L2Reg,
+		ClipVal:   options.ClipVal,
+	}
+}
+
+// Step implements G.Solver
+func (s *RMSProp) Step(nodes G.Nodes) error {
+	weights, grads := make([][]float32, len(nodes)), make([][]float32, len(nodes))
+	for i, node := range nodes {
+		w, ok := node.Value().Data().([]float32)
+		if !ok {
+			return fmt.Errorf("node %d is not float32", i)
+		}
+		g, err := node.Grad()
+		if err != nil {
+			return err
+		}
+		grad, ok := g.Data().([]float32)
+		if !ok {
+			return fmt.Errorf("node %d has an invalid gradient", i)
+		}
+		weights[i], grads[i] = w, grad
+	}
+	return s.apply(weights, grads)
+}
+
+// apply updates the weights with the gradients and zeroes the gradients
+func (s *RMSProp) apply(weights, grads [][]float32) error {
+	if s.Cache == nil {
+		s.Cache = make([][]float32, len(weights))
+	} else if len(s.Cache) != len(weights) {
+		return fmt.Errorf("solver has %d caches, expected %d", len(s.Cache), len(weights))
+	}
+
+	eta, decay, epsilon := float32(s.LearnRate), float32(s.Decay), float32(s.Epsilon)
+	l2reg, clip := float32(s.L2Reg), float32(s.ClipVal)
+	for i, w := range weights {
+		grad := grads[i]
+		if len(grad) != len(w) {
+			return fmt.Errorf("gradient %d has %d values, expected %d", i, len(grad), len(w))
+		}
+		if s.Cache[i] == nil {
+			s.Cache[i] = make([]float32, len(w))
+		} else if len(s.Cache[i]) != len(w) {
+			return fmt.Errorf("cache %d has %d values, expected %d", i, len(s.Cache[i]), len(w))
+		}
+
+		// the update of the gorgonia solver: the gradient is scaled by the
+		// inverse square root of the cache, clipped, and scaled by the learn
+		// rate, and the L2 regularization isn't scaled by the learn rate
+		cache := s.Cache[i]
+		for j, d := range grad {
+			cache[j] = decay*cache[j] + (1-decay)*d*d + epsilon
+			d /= float32(math.Sqrt(float64(cache[j])))
+			if clip > 0 {
+				if d > clip {
+					d = clip
+				} else if d < -clip {
+					d = -clip
+				}
+			}
+			w[j] += -eta*d - l2reg*w[j]
+			grad[j] = 0
+		}
+	}
+	return nil
+}
+
+// Schedule is a learning rate schedule
+type Schedule struct {
+	// Kind is constant, step, exponential or cosine
+	Kind string
+	// Decay is the factor the learning rate is multiplied by for step and
+	// exponential schedules
+	Decay float64
+	// Epochs is the number of epochs between steps of a step schedule
+	Epochs int
+}
+
+// Rate returns the learning rate for an epoch out of epochs
+func (s Schedule) Rate(base float64, epoch, epochs int) (float64, error) {
+	switch s.Kind {
+	case "", "constant":
+		return base, nil
+	case "step":
+		if s.Epochs < 1 {
+			return 0, fmt.Errorf("invalid step schedule epochs %d", s.Epochs)
+		}
+		return base * math.Pow(s.Decay, float64(epoch/s.Epochs)), nil
+	case "exponential":
+		return base * math.Pow(s.Decay, float64(epoch)), nil
+	case "cosine":
+		if epochs < 1 {
+			return base, nil
+		}
+		return base * (1 + math.Cos(math.Pi*float64(epoch)/float64(epochs))) / 2, nil
+	}
+	return 0, fmt.Errorf("unknown schedule %q", s.Kind)
+}
+
+// Checkpoint is the state of a training run that is saved alongside the
+// weights so that training can be resumed
+type Checkpoint struct {
+	// Epoch is the last completed epoch
+	Epoch int
+	// Loss is the validation loss of the last completed epoch
+	Loss float64
+	// Best is the lowest validation loss and BestEpoch is its epoch
+	Best      float64
+	BestEpoch int
+	// Waited is the number of epochs since the validation loss improved
+	Waited int
+	// Solver is the state of the solver
+	Solver *RMSProp
+}
+
+// WriteCheckpointFile writes a checkpoint to a file
+func WriteCheckpointFile(file string, checkpoint *Checkpoint) error {
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return gob.NewEncoder(out).Encode(checkpoint)
+}
+
+// ReadCheckpointFile reads a checkpoint from a file
+func ReadCheckpointFile(file string) (*Checkpoint, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	checkpoint := &Checkpoint{}
+	err = gob.NewDecoder(in).Decode(checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"fmt"
+
+	"github.com/pointlander/injectsec/lexer"
+)
+
+// TokenizerSQL is the kind of SQLTokenizer
+const TokenizerSQL = "sql"
+
+// sqlClasses are the token kinds that are a single token each, in the order
+// of the vocabulary
+var sqlClasses = []lexer.Kind{
+	lexer.KindSpace,
+	lexer.KindString,
+	lexer.KindQuoted,
+	lexer.KindBacktick,
+	lexer.KindNumber,
+	lexer.KindHex,
+	lexer.KindIdentifier,
+	lexer.KindVariable,
+	lexer.KindComment,
+	lexer.KindConditional,
+}
+
+func init() {
+	RegisterTokenizer(TokenizerSQL, func(vocabulary []string) (Tokenizer, error) {
+		prefix := len(sqlClasses) + len(lexer.Operators)
+		if len(vocabulary) < prefix {
+			return nil, fmt.Errorf("sql vocabulary has %d tokens, expected at least %d", len(vocabulary), prefix)
+		}
+		tokenizer := newSQLTokenizer(lexer.New(vocabulary[prefix:]))
+		for i, token := range tokenizer.vocabulary {
+			if vocabulary[i] != token {
+				return nil, fmt.Errorf("sql token %d is %q, expected %q", i, vocabulary[i], token)
+			}
+		}
+		return tokenizer, nil
+	})
+}
+
+// SQLTokenizer is a tokenizer that lexes the input as SQL; string, numeric
+// and other literals, identifiers, comments and white space are each a
+// single token, every keyword and multi byte operator is its own token,
+// and everything else is a byte
+type SQLTokenizer struct {
+	lexer      *lexer.Lexer
+	vocabulary []string
+	classes    map[lexer.Kind]int
+	operators  map[string]int
+}
+
+// NewSQLTokenizer creates a SQL tokenizer with the keywords of the dialects;
+// all of the dialects are used if none are given
+func NewSQLTokenizer(dialects ...string) (*SQLTokenizer, error) {
+	l, err := lexer.NewDialects(dialects...)
+	if err != nil {
+		return nil, err
+	}
+	return newSQLTokenizer(l), nil
+}
+
+func newSQLTokenizer(l *lexer.Lexer) *SQLTokenizer {
+	tokenizer := &SQLTokenizer{
+		lexer:     l,
+		classes:   make(map[lexer.Kind]int, len(sqlClasses)),
+		operators: make(map[string]int, len(lexer.Operators)),
+	}
+	for _, kind := range sqlClasses {
+		tokenizer.classes[kind] = 256 + len(tokenizer.vocabulary)
+		tokenizer.vocabulary = append(tokenizer.vocabulary, "<"+kind.String()+">")
+	}
+	for _, operator := range lexer.Operators {
+		tokenizer.operators[operator] = 256 + len(tokenizer.vocabulary)
+		tokenizer.vocabulary = append(tokenizer.vocabulary, operator)
+	}
+	tokenizer.vocabulary = append(tokenizer.vocabulary, l.Keywords()...)
+	return tokenizer
+}
+
+// Lexer returns the lexer of the tokenizer
+func (s *SQLTokenizer) Lexer() *lexer.Lexer {
+	return s.lexer
+}
+
+// Kind implements Tokenizer
+func (s *SQLTokenizer) Kind() string {
+	return TokenizerSQL
+}
+
+// Vocabulary implements Tokenizer; it returns the names of the token
+// classes, followed by the operators and the keywords
+func (s *SQLTokenizer) Vocabulary() []string {
+	return s.vocabulary
+}
+
+// Size implements Tokenizer
+func (s *SQLTokenizer) Size() int {
+	return 256 + len(s.vocabulary)
+}
+
+// MaxLength implements Tokenizer
+func (s *SQLTokenizer) MaxLength() int {
+	return lexer.MaxToken
+}
+
+// Match implements Tokenizer; the input is lexed at the top level
+func (s *SQLTokenizer) Match(input []byte, i int) (token, length int) {
+	t := s.lexer.Next(input, i)
+	return s.token(input, t), t.End - t.Start
+}
+
+// Tokenize implements InputTokenizer; the input is lexed with
+// lexer.LexValue, so the keywords of an input that closes a quote, like
+// admin' or 'a'='a, are tokens
+func (s *SQLTokenizer) Tokenize(input []byte) (tokens, starts []int) {
+	lexed := s.lexer.LexValue(input)
+	tokens, starts = make([]int, 0, len(lexed)), make([]int, 0, len(lexed))
+	for _, t := range lexed {
+		tokens, starts = append(tokens, s.token(input, t)), append(starts, t.Start)
+	}
+	return tokens, starts
+}
+
+// token returns the token of a lexed token
+func (s *SQLTokenizer) token(input []byte, t lexer.Token) int {
+	switch t.Kind {
+	case lexer.KindByte, lexer.KindQuote:
+		return int(input[t.Start])
+	case lexer.KindOperator:
+		return s.operators[string(input[t.Start:t.End])]
+	case lexer.KindKeyword:
+		index, _ := s.lexer.Keyword(input, t)
+		return 256 + len(sqlClasses) + len(lexer.Operators) + index
+	}
+	return s.classes[t.Kind]
+}
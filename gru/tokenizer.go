@@ -0,0 +1,235 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Tokenizer converts lower cased input into the tokens of a model; tokens
+// 0 to 255 are single bytes and the tokens from 256 on are the vocabulary
+type Tokenizer interface {
+	// Kind is the name the tokenizer is registered with, see RegisterTokenizer
+	Kind() string
+	// Vocabulary is the definition of the tokenizer saved with the weights
+	Vocabulary() []string
+	// Size is the number of distinct tokens
+	Size() int
+	// MaxLength is the length in bytes of the longest token
+	MaxLength() int
+	// Match returns the token at position i of the input and its length in bytes
+	Match(input []byte, i int) (token, length int)
+}
+
+// TokenizerChunk is the kind of ChunkTokenizer
+const TokenizerChunk = "chunk"
+
+// tokenizers create tokenizers from their vocabulary by kind
+var tokenizers = map[string]func(vocabulary []string) (Tokenizer, error){
+	TokenizerChunk: func(vocabulary []string) (Tokenizer, error) {
+		return NewChunkTokenizer(vocabulary)
+	},
+}
+
+// RegisterTokenizer registers a kind of tokenizer so the weights of models
+// that use it can be read; it should be called from init
+func RegisterTokenizer(kind string, create func(vocabulary []string) (Tokenizer, error)) {
+	if _, ok := tokenizers[kind]; ok {
+		panic(fmt.Errorf("tokenizer %q is already registered", kind))
+	}
+	tokenizers[kind] = create
+}
+
+// NewTokenizer creates a registered kind of tokenizer from its vocabulary
+func NewTokenizer(kind string, vocabulary []string) (Tokenizer, error) {
+	create, ok := tokenizers[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown tokenizer %q", kind)
+	}
+	return create(vocabulary)
+}
+
+// ChunkTokenizer greedily matches the longest chunk of its vocabulary at
+// each position of the input, and otherwise emits the byte
+type ChunkTokenizer struct {
+	chunks    []string
+	maxLength int
+}
+
+// NewChunkTokenizer creates a chunk tokenizer; the chunks are sorted longest
+// first and then lexically, and chunk j of the sorted chunks is token 256+j
+func NewChunkTokenizer(chunks []string) (*ChunkTokenizer, error) {
+	sorted := append([]string(nil), chunks...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if la, lb := len(a), len(b); la > lb {
+			return true
+		} else if la == lb {
+			return a < b
+		}
+		return false
+	})
+	maxLength := 1
+	for i, chunk := range sorted {
+		if chunk == "" {
+			return nil, fmt.Errorf("empty chunk")
+		}
+		if i > 0 && sorted[i-1] == chunk {
+			return nil, fmt.Errorf("duplicate chunk %q", chunk)
+		}
+		if len(chunk) > maxLength {
+			maxLength = len(chunk)
+		}
+	}
+	return &ChunkTokenizer{
+		chunks:    sorted,
+		maxLength: maxLength,
+	}, nil
+}
+
+// Kind implements Tokenizer
+func (c *ChunkTokenizer) Kind() string {
+	return TokenizerChunk
+}
+
+// Vocabulary implements Tokenizer; it returns the sorted chunks
+func (c *ChunkTokenizer) Vocabulary() []string {
+	return c.chunks
+}
+
+// Size implements Tokenizer
+func (c *ChunkTokenizer) Size() int {
+	return 256 + len(c.chunks)
+}
+
+// MaxLength implements Tokenizer
+func (c *ChunkTokenizer) MaxLength() int {
+	return c.maxLength
+}
+
+// Match implements Tokenizer
+func (c *ChunkTokenizer) Match(input []byte, i int) (token, length int) {
+search:
+	for j, chunk := range c.chunks {
+		for k := 0; k < len(chunk); k++ {
+			index := i + k
+			if index >= len(input) {
+				continue search
+			}
+			if chunk[k] != input[index] {
+				continue search
+			}
+		}
+		return 256 + j, len(chunk)
+	}
+	return int(input[i]), 1
+}
+
+// DefaultTokenizer is the chunk tokenizer of Chunks; the embedded weights
+// were trained with it
+var DefaultTokenizer Tokenizer
+
+func init() {
+	tokenizer, err := NewChunkTokenizer(Chunks)
+	if err != nil {
+		panic(err)
+	}
+	DefaultTokenizer = tokenizer
+	// Chunks is kept in token order, so chunk i of Chunks is token 256+i
+	copy(Chunks, tokenizer.Vocabulary())
+}
+
+// convert converts the input into tokens
+func convert(t Tokenizer, input []byte) []int {
+	tokens, _ := tokenize(t, input)
+	return tokens
+}
+
+// InputTokenizer is a tokenizer that can tokenize a whole input differently
+// than Match does from the start of the input; it is used for whole inputs,
+// and Match is used when the input is streamed
+type InputTokenizer interface {
+	Tokenizer
+	// Tokenize returns the tokens of the input and the offset of each token
+	Tokenize(input []byte) (tokens, starts []int)
+}
+
+// tokenize converts the input into tokens and returns the offset of each
+// token in the input; token i is input[starts[i]:starts[i+1]]
+func tokenize(t Tokenizer, input []byte) (tokens, starts []int) {
+	if t, ok := t.(InputTokenizer); ok {
+		return t.Tokenize(input)
+	}
+	length, i := len(input), 0
+	tokens, starts = make([]int, 0, length), make([]int, 0, length)
+	for i < length {
+		token, size := t.Match(input, i)
+		tokens, starts = append(tokens, token), append(starts, i)
+		i += size
+	}
+
+	return tokens, starts
+}
+
+// maxLearnedChunk is the length of the longest chunk that is counted
+const maxLearnedChunk = 16
+
+// CountChunks counts the runs of two or more letters and the runs of two or
+// more symbols in the lower cased inputs
+func CountChunks(inputs [][]byte) map[string]int {
+	class := func(c byte) int {
+		switch {
+		case 'a' <= c && c <= 'z':
+			return 1
+		case '0' <= c && c <= '9', 'A' <= c && c <= 'Z':
+			return 0
+		case '!' <= c && c <= '~':
+			return 2
+		}
+		return 0
+	}
+	counts := make(map[string]int)
+	for _, input := range inputs {
+		start := 0
+		for i := 1; i <= len(input); i++ {
+			if i < len(input) && class(input[i]) == class(input[start]) {
+				continue
+			}
+			if length := i - start; length > 1 && length <= maxLearnedChunk && class(input[start]) != 0 {
+				counts[string(input[start:i])]++
+			}
+			start = i
+		}
+	}
+	return counts
+}
+
+// LearnTokenizer learns the vocabulary of a chunk tokenizer from the lower
+// cased inputs; the vocabulary is the size most frequent chunks that occur
+// at least minCount times
+func LearnTokenizer(inputs [][]byte, size, minCount int) (*ChunkTokenizer, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("invalid vocabulary size %d", size)
+	}
+	counts := CountChunks(inputs)
+	chunks := make([]string, 0, len(counts))
+	for chunk, count := range counts {
+		if count >= minCount {
+			chunks = append(chunks, chunk)
+		}
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		a, b := counts[chunks[i]], counts[chunks[j]]
+		if a == b {
+			return chunks[i] < chunks[j]
+		}
+		return a > b
+	})
+	if len(chunks) > size {
+		chunks = chunks[:size]
+	}
+	return NewChunkTokenizer(chunks)
+}
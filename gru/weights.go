@@ -0,0 +1,140 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gru
+
+import (
+	"fmt"
+
+	"github.com/pointlander/injectsec/data"
+)
+
+// A weights file is laid out as:
+//
+//	magic    8 bytes "INJECSEC"
+//	version  uint32 little endian
+//	length   uint64 little endian length of the payload
+//	payload  gob encoded header followed by the gob encoded tensors
+//	checksum uint32 little endian IEEE CRC-32 of the payload
+//
+// Legacy weights files are only the gob encoded tensors.
+const (
+	magic   = "INJECSEC"
+	version = 1
+	// maxPayload is the largest payload of a weights file
+	maxPayload = 1 << 30
+)
+
+var (
+	// ErrorVersion means the weights file version is not supported
+	ErrorVersion = fmt.Errorf("weights file version is not supported")
+	// ErrorChecksum means the weights file is corrupt
+	ErrorChecksum = fmt.Errorf("weights file checksum does not match")
+	// ErrorPayload means the payload length of the weights file is invalid
+	ErrorPayload = fmt.Errorf("weights file payload length is invalid")
+	// ErrorMismatch means the weights file does not match the model
+	ErrorMismatch = fmt.Errorf("weights file does not match model")
+)
+
+// header describes the model stored in a weights file
+type header struct {
+	Inputs        int
+	InputSize     int
+	EmbeddingSize int
+	OutputSize    int
+	LayerSizes    []int
+	// Categories is the number of outputs of the category head, if any
+	Categories int
+	// Tokenizer is the kind of the tokenizer and Chunks is its vocabulary;
+	// weights files without a kind use a chunk tokenizer
+	Tokenizer string
+	Chunks    []string
+	// the training options, see Options
+	Steps     int
+	LearnRate float64
+	L2Reg     float64
+	ClipVal   float64
+	// Calibration is the calibration of the attack probability, if any
+	Calibration *Calibration
+}
+
+// validate checks that the header can be used with this version of the code,
+// and returns the tokenizer it describes
+func (h header) validate() (Tokenizer, error) {
+	if h.Inputs < 1 || h.InputSize < 1 || h.EmbeddingSize < 1 || h.OutputSize < 1 || len(h.LayerSizes) == 0 {
+		return nil, fmt.Errorf("%w: invalid architecture %+v", ErrorMismatch, h)
+	}
+	for _, size := range h.LayerSizes {
+		if size < 1 {
+			return nil, fmt.Errorf("%w: invalid layer sizes %v", ErrorMismatch, h.LayerSizes)
+		}
+	}
+	if h.Categories < 0 || h.Categories >= int(data.Categories) {
+		return nil, fmt.Errorf("%w: invalid number of categories %d", ErrorMismatch, h.Categories)
+	}
+	kind := h.Tokenizer
+	if kind == "" {
+		kind = TokenizerChunk
+	}
+	tokenizer, err := NewTokenizer(kind, h.Chunks)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorMismatch, err)
+	}
+	vocabulary := tokenizer.Vocabulary()
+	if len(h.Chunks) != len(vocabulary) {
+		return nil, fmt.Errorf("%w: %d chunks, expected %d", ErrorMismatch, len(h.Chunks), len(vocabulary))
+	}
+	for i, chunk := range h.Chunks {
+		if chunk != vocabulary[i] {
+			return nil, fmt.Errorf("%w: chunk %d is %q, expected %q", ErrorMismatch, i, chunk, vocabulary[i])
+		}
+	}
+	if h.InputSize != tokenizer.Size() {
+		return nil, fmt.Errorf("%w: input size %d, expected %d", ErrorMismatch, h.InputSize, tokenizer.Size())
+	}
+	return tokenizer, nil
+}
+
+// check checks that the header b matches the header h of an existing model
+func (h header) check(b header) error {
+	tokenizer, err := b.validate()
+	if err != nil {
+		return err
+	}
+	if tokenizer.Kind() != h.Tokenizer {
+		return fmt.Errorf("%w: tokenizer %q, expected %q", ErrorMismatch, tokenizer.Kind(), h.Tokenizer)
+	}
+	if len(h.Chunks) != len(b.Chunks) {
+		return fmt.Errorf("%w: %d chunks, expected %d", ErrorMismatch, len(b.Chunks), len(h.Chunks))
+	}
+	for i, chunk := range h.Chunks {
+		if b.Chunks[i] != chunk {
+			return fmt.Errorf("%w: chunk %d is %q, expected %q", ErrorMismatch, i, b.Chunks[i], chunk)
+		}
+	}
+	if h.Inputs != b.Inputs {
+		return fmt.Errorf("%w: %d inputs, expected %d", ErrorMismatch, b.Inputs, h.Inputs)
+	}
+	if h.InputSize != b.InputSize {
+		return fmt.Errorf("%w: input size %d, expected %d", ErrorMismatch, b.InputSize, h.InputSize)
+	}
+	if h.EmbeddingSize != b.EmbeddingSize {
+		return fmt.Errorf("%w: embedding size %d, expected %d", ErrorMismatch, b.EmbeddingSize, h.EmbeddingSize)
+	}
+	if h.OutputSize != b.OutputSize {
+		return fmt.Errorf("%w: output size %d, expected %d", ErrorMismatch, b.OutputSize, h.OutputSize)
+	}
+	if h.Categories != b.Categories {
+		return fmt.Errorf("%w: %d categories, expected %d", ErrorMismatch, b.Categories, h.Categories)
+	}
+	if len(h.LayerSizes) != len(b.LayerSizes) {
+		return fmt.Errorf("%w: layer sizes %v, expected %v", ErrorMismatch, b.LayerSizes, h.LayerSizes)
+	}
+	for i, size := range h.LayerSizes {
+		if b.LayerSizes[i] != size {
+			return fmt.Errorf("%w: layer sizes %v, expected %v", ErrorMismatch, b.LayerSizes, h.LayerSizes)
+		}
+	}
+	return nil
+}
@@ -18,14 +18,15 @@ type DetectorMaker struct {
 
 // NewDetectorMakerWithWeights creates a new detector maker using weights
 func NewDetectorMakerWithWeights(weights io.Reader) (*DetectorMaker, error) {
-	maker := gru.NewDetectorMaker()
-	err := maker.Read(weights)
+	model, err := gru.ReadModel(weights)
 	if err != nil {
 		return nil, err
 	}
 
 	return &DetectorMaker{
-		DetectorMaker: maker,
+		DetectorMaker: &gru.DetectorMaker{
+			Model: model,
+		},
 	}, nil
 }
 
@@ -4,7 +4,33 @@
 
 package injectsec
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/pointlander/injectsec/gru"
+)
+
+var attacks = []string{
+	"test or 1337=1337 --\"",
+	" or 1=1 ",
+	"/**/or/**/1337=1337",
+}
+
+var notAttacks = []string{
+	"abc123",
+	"abc123 123abc",
+	"123",
+	"abcorabc",
+	"available",
+	"orcat1",
+	"cat1or",
+	"cat1orcat1",
+}
 
 func TestDetector(t *testing.T) {
 	maker, err := NewDetectorMaker()
@@ -13,12 +39,6 @@ func TestDetector(t *testing.T) {
 	}
 	detector := maker.Make()
 
-	attacks := []string{
-		"test or 1337=1337 --\"",
-		" or 1=1 ",
-		"/**/or/**/1337=1337",
-	}
-
 	detector.SkipRegex = true
 	for _, s := range attacks {
 		probability, err := detector.Detect(s)
@@ -40,17 +60,6 @@ func TestDetector(t *testing.T) {
 		}
 	}
 
-	notAttacks := []string{
-		"abc123",
-		"abc123 123abc",
-		"123",
-		"abcorabc",
-		"available",
-		"orcat1",
-		"cat1or",
-		"cat1orcat1",
-	}
-
 	detector.SkipRegex = true
 	for _, s := range notAttacks {
 		probability, err := detector.Detect(s)
@@ -72,3 +81,276 @@ func TestDetector(t *testing.T) {
 		}
 	}
 }
+
+func TestDetectorPool(t *testing.T) {
+	maker, err := NewDetectorMaker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := maker.NewPool(2)
+
+	inputs := []string{
+		" or 1=1 ",
+		"/**/or/**/1337=1337",
+		"abc123",
+		"available",
+	}
+	done := make(chan error, 8*len(inputs))
+	for i := 0; i < 8; i++ {
+		go func() {
+			for _, s := range inputs {
+				_, err := pool.Detect(context.Background(), s)
+				done <- err
+			}
+		}()
+	}
+	for i := 0; i < 8*len(inputs); i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := pool.Stats()
+	if stats.Created > stats.Size {
+		t.Fatal("too many detectors", stats.Created, stats.Size)
+	}
+	if stats.InUse != 0 {
+		t.Fatal("detectors were not returned", stats.InUse)
+	}
+	if stats.Gets != uint64(8*len(inputs)) {
+		t.Fatal("wrong number of gets", stats.Gets)
+	}
+}
+
+func TestDetectDetailed(t *testing.T) {
+	maker, err := NewDetectorMaker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	detector := maker.Make()
+
+	result, err := detector.DetectDetailed("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != gru.SourceEmpty {
+		t.Fatal("source should be empty", result.Source)
+	}
+
+	result, err = detector.DetectDetailed("available")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != gru.SourceNotFilter || result.Probability != 0 {
+		t.Fatal("source should be notfilter", result.Source, result.Probability)
+	}
+
+	result, err = detector.DetectDetailed("1 or 1=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != gru.SourceFilter || result.Probability != 100 {
+		t.Fatal("source should be filter", result.Source, result.Probability)
+	}
+	if result.Generator < 0 || result.Form == "" {
+		t.Fatal("generator should be set", result.Generator, result.Form)
+	}
+
+	detector.SkipRegex = true
+	result, err = detector.DetectDetailed("1 or 1=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Source != gru.SourceGRU || result.Generator != -1 {
+		t.Fatal("source should be gru", result.Source, result.Generator)
+	}
+	if len(result.Tokens) == 0 {
+		t.Fatal("tokens should be set")
+	}
+}
+
+func TestDetectBatch(t *testing.T) {
+	maker, err := NewDetectorMaker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	detector := maker.Make()
+	detector.SkipRegex = true
+	detector.BatchSize = 4
+
+	inputs := append(append([]string{""}, attacks...), notAttacks...)
+	probabilities, err := detector.DetectBatch(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(probabilities) != len(inputs) {
+		t.Fatal("wrong number of probabilities", len(probabilities))
+	}
+	for i, s := range inputs {
+		probability, err := detector.Detect(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(float64(probability-probabilities[i])) > .01 {
+			t.Fatal("batch probability doesn't match", s, probability, probabilities[i])
+		}
+	}
+}
+
+func BenchmarkDetect(b *testing.B) {
+	maker, err := NewDetectorMaker()
+	if err != nil {
+		b.Fatal(err)
+	}
+	detector := maker.Make()
+	detector.SkipRegex = true
+	inputs := append(append([]string{}, attacks...), notAttacks...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, s := range inputs {
+			_, err := detector.Detect(s)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkDetectBatch(b *testing.B) {
+	maker, err := NewDetectorMaker()
+	if err != nil {
+		b.Fatal(err)
+	}
+	detector := maker.Make()
+	detector.SkipRegex = true
+	inputs := append(append([]string{}, attacks...), notAttacks...)
+	_, err = detector.DetectBatch(inputs)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := detector.DetectBatch(inputs)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestEngine(t *testing.T) {
+	maker, err := NewDetectorMaker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	detector, engine := maker.Make(), maker.MakeEngine()
+	detector.SkipRegex, engine.SkipRegex = true, true
+
+	inputs := append(append([]string{}, attacks...), notAttacks...)
+	done := make(chan error, len(inputs))
+	for _, s := range inputs {
+		expected, err := detector.Detect(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func(s string, expected float32) {
+			probability, err := engine.Detect(s)
+			if err == nil && math.Abs(float64(probability-expected)) > .01 {
+				err = fmt.Errorf("%s: engine %f, graph %f", s, probability, expected)
+			}
+			done <- err
+		}(s, expected)
+	}
+	for range inputs {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanner(t *testing.T) {
+	maker, err := NewDetectorMaker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := gru.NewScanner(maker.Engine(), 16, 4)
+	prefix := strings.Repeat(`{"name": "available", "id": 123}, `, 64)
+	input := prefix + `{"name": " or 1=1 "}` + strings.Repeat(`, {"name": "cat1orcat1"}`, 64)
+	result, err := scanner.ScanString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Probability < 50 {
+		t.Fatal("should find a sql injection attack", result)
+	}
+	attack := int64(len(prefix)) + 10
+	if result.Offset > attack || result.Offset+result.Length < attack {
+		t.Fatal("window should contain the attack", result, attack)
+	}
+}
+
+func trainingSet() (inputs [][]byte, labels []bool) {
+	for _, s := range attacks {
+		inputs, labels = append(inputs, []byte(s)), append(labels, true)
+	}
+	for _, s := range notAttacks {
+		inputs, labels = append(inputs, []byte(s)), append(labels, false)
+	}
+	return inputs, labels
+}
+
+func meanLoss(network *gru.GRU, inputs [][]byte, labels []bool) float64 {
+	total := 0.0
+	for i, input := range inputs {
+		total += float64(network.Loss(input, labels[i]))
+	}
+	return total / float64(len(inputs))
+}
+
+func TestParallel(t *testing.T) {
+	inputs, labels := trainingSet()
+	loss := func(network *gru.GRU) float64 {
+		return meanLoss(network, inputs, labels)
+	}
+
+	options := gru.DefaultOptions()
+	options.LearnRate = 0.01
+	single := gru.NewGRUWithOptions(rand.New(rand.NewSource(1)), options)
+	initial := loss(single)
+	network := gru.NewGRUWithOptions(rand.New(rand.NewSource(1)), options)
+	parallel := gru.NewParallel(network, 4)
+	for epoch := 0; epoch < 64; epoch++ {
+		for i, input := range inputs {
+			single.Train(input, labels[i])
+		}
+		for i := 0; i < len(inputs); i += 4 {
+			end := i + 4
+			if end > len(inputs) {
+				end = len(inputs)
+			}
+			parallel.TrainBatch(inputs[i:end], labels[i:end])
+		}
+	}
+	singleLoss, parallelLoss := loss(single), loss(network)
+	t.Log(initial, singleLoss, parallelLoss)
+	if parallelLoss > initial/2 {
+		t.Fatal("parallel training didn't converge", initial, parallelLoss)
+	}
+	if parallelLoss > 2*singleLoss+0.05 {
+		t.Fatal("parallel training should converge like single threaded training", singleLoss, parallelLoss)
+	}
+}
+
+func TestTrainBatch(t *testing.T) {
+	inputs, labels := trainingSet()
+	options := gru.DefaultOptions()
+	options.LearnRate = 0.01
+	network := gru.NewGRUWithOptions(rand.New(rand.NewSource(1)), options)
+	network.SetClassWeights(float32(len(notAttacks))/float32(len(attacks)), 1)
+	initial := meanLoss(network, inputs, labels)
+	for epoch := 0; epoch < 256; epoch++ {
+		network.TrainBatch(inputs, labels)
+	}
+	if loss := meanLoss(network, inputs, labels); loss > initial/2 {
+		t.Fatal("mini batch training didn't converge", initial, loss)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import "fmt"
+
+// Context is where a value is interpolated into a query
+type Context int
+
+const (
+	// ContextNone means the context is unknown
+	ContextNone Context = iota
+	// ContextSingleQuote is inside a single quoted string: '...'
+	ContextSingleQuote
+	// ContextDoubleQuote is inside a double quoted string or identifier: "..."
+	ContextDoubleQuote
+	// ContextNumeric is a bare numeric literal: id = ...
+	ContextNumeric
+	// ContextIdentifier is a bare identifier such as a column name: order by ...
+	ContextIdentifier
+	// ContextLike is inside the single quoted pattern of a LIKE: like '...'
+	ContextLike
+)
+
+var contextNames = [...]string{
+	ContextNone:        "none",
+	ContextSingleQuote: "single",
+	ContextDoubleQuote: "double",
+	ContextNumeric:     "numeric",
+	ContextIdentifier:  "identifier",
+	ContextLike:        "like",
+}
+
+// String returns the name of the context
+func (c Context) String() string {
+	if c < 0 || int(c) >= len(contextNames) {
+		return "unknown"
+	}
+	return contextNames[c]
+}
+
+// ParseContext parses the name of a context; the empty string is ContextNone
+func ParseContext(name string) (Context, error) {
+	if name == "" {
+		return ContextNone, nil
+	}
+	for i, n := range contextNames {
+		if n == name {
+			return Context(i), nil
+		}
+	}
+	return ContextNone, fmt.Errorf("unknown context %q", name)
+}
+
+// significant is true for the tokens that change the meaning of a query
+// when they are outside of a literal; arithmetic isn't significant, so
+// names like d'angelo-smith don't escape
+func significant(input []byte, t Token) bool {
+	switch t.Kind {
+	case KindKeyword, KindOperator, KindComment, KindConditional, KindVariable:
+		return true
+	case KindByte:
+		switch input[t.Start] {
+		case '=', '<', '>', ';', '(', ')', ',', '|', '&':
+			return true
+		}
+	}
+	return false
+}
+
+// Escapes returns true if the value escapes the context when it is
+// interpolated into a query, and the tokens outside of the context can change
+// the meaning of the query. A value that only closes the context, like the
+// apostrophe in o'brien inside single quotes, doesn't escape it. Quotes are
+// escaped by a backslash in MySQL but not in the other dialects, so a value
+// escapes a quoted context if it escapes it under either rule
+func (l *Lexer) Escapes(value []byte, context Context) bool {
+	switch context {
+	case ContextSingleQuote, ContextLike:
+		return l.escapesQuote(value, context, '\'', true) || l.escapesQuote(value, context, '\'', false)
+	case ContextDoubleQuote:
+		return l.escapesQuote(value, context, '"', true) || l.escapesQuote(value, context, '"', false)
+	}
+	return escapes(value, context, l.Lex(value))
+}
+
+// escapesQuote returns true if the value escapes the quoted context; quotes
+// are escaped by a backslash if backslash is true
+func (l *Lexer) escapesQuote(value []byte, context Context, quote byte, backslash bool) bool {
+	query := make([]byte, 0, len(value)+2)
+	query = append(append(append(query, quote), value...), quote)
+	// an unterminated literal doesn't escape, it only breaks the query
+	end := literal(query, 0, backslash)
+	if end < 0 || end == len(query) {
+		return false
+	}
+	var outside []Token
+	for i := end; i < len(query); {
+		t := l.Next(query, i)
+		outside = append(outside, t)
+		i = t.End
+	}
+	return escapes(query, context, outside)
+}
+
+// escapes returns true if the tokens of the query outside of the context can
+// change the meaning of the query
+func escapes(query []byte, context Context, outside []Token) bool {
+	var tokens []Token
+	for _, t := range outside {
+		if t.Kind != KindSpace {
+			tokens = append(tokens, t)
+		}
+	}
+	switch context {
+	case ContextNumeric:
+		// a signed number
+		if len(tokens) == 2 && (query[tokens[0].Start] == '-' || query[tokens[0].Start] == '+') &&
+			tokens[0].Kind == KindByte && (tokens[1].Kind == KindNumber || tokens[1].Kind == KindHex) {
+			return false
+		}
+	case ContextIdentifier:
+		// an identifier that is a keyword, like a column named order
+		if len(tokens) == 1 {
+			return false
+		}
+	}
+	for _, t := range tokens {
+		if significant(query, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Default is a lexer with the keywords of all of the dialects
+var Default *Lexer
+
+func init() {
+	l, err := NewDialects()
+	if err != nil {
+		panic(err)
+	}
+	Default = l
+}
+
+// Escapes returns true if the value escapes the context, see Lexer.Escapes;
+// the keywords of all of the dialects are used
+func Escapes(value string, context Context) bool {
+	return Default.Escapes([]byte(value), context)
+}
@@ -0,0 +1,108 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// MySQL is the MySQL and MariaDB dialect
+	MySQL = "mysql"
+	// PostgreSQL is the PostgreSQL dialect
+	PostgreSQL = "postgres"
+	// MSSQL is the Microsoft SQL Server dialect
+	MSSQL = "mssql"
+	// Oracle is the Oracle dialect
+	Oracle = "oracle"
+	// SQLite is the SQLite dialect
+	SQLite = "sqlite"
+)
+
+// Dialects are the supported dialects
+var Dialects = []string{MySQL, PostgreSQL, MSSQL, Oracle, SQLite}
+
+// common are the keywords and functions shared by the dialects
+var common = []string{
+	"all", "alter", "and", "as", "asc", "ascii", "avg", "begin", "between", "by",
+	"case", "cast", "char", "coalesce", "concat", "count", "create", "cross",
+	"database", "declare", "delete", "desc", "distinct", "drop", "else", "end",
+	"escape", "exec", "execute", "exists", "false", "from", "function", "grant",
+	"group", "having", "if", "in", "index", "inner", "insert", "into", "is",
+	"join", "left", "length", "like", "limit", "lower", "max", "min", "not",
+	"null", "offset", "on", "or", "order", "outer", "procedure", "revoke",
+	"right", "select", "set", "substring", "sum", "table", "then", "trigger",
+	"true", "truncate", "union", "update", "upper", "user", "values", "version",
+	"view", "when", "where", "with",
+}
+
+// dialects are the keywords and functions of each dialect that are commonly
+// used in injections
+var dialects = map[string][]string{
+	MySQL: {
+		"analyse", "benchmark", "binary", "char_length", "charset", "concat_ws",
+		"current_user", "describe", "div", "dumpfile", "elt", "extractvalue",
+		"floor", "found_rows", "get_lock", "group_concat", "handler", "hex",
+		"ifnull", "information_schema", "load_file", "make_set", "mid", "mod",
+		"name_const", "ord", "outfile", "rand", "regexp", "replace", "rlike",
+		"row_count", "schema", "show", "sleep", "unhex", "updatexml", "use", "xor",
+	},
+	PostgreSQL: {
+		"array_agg", "chr", "copy", "current_database", "current_schema",
+		"current_user", "generate_series", "ilike", "lo_export", "lo_import",
+		"pg_catalog", "pg_ls_dir", "pg_read_file", "pg_shadow", "pg_sleep",
+		"pg_tables", "pg_user", "query_to_xml", "regexp_replace", "returning",
+		"session_user", "similar", "string_agg", "to_char",
+	},
+	MSSQL: {
+		"bulk", "convert", "datalength", "db_name", "delay", "host_name",
+		"is_srvrolemember", "len", "master", "nchar", "nvarchar", "opendatasource",
+		"openquery", "openrowset", "reconfigure", "shutdown", "sp_configure",
+		"sp_executesql", "sp_oacreate", "suser_sname", "syscolumns",
+		"sysdatabases", "sysobjects", "system_user", "top", "varchar", "waitfor",
+		"xp_cmdshell", "xp_dirtree",
+	},
+	Oracle: {
+		"all_tab_columns", "all_tables", "chr", "connect", "ctxsys", "dbms_lock",
+		"dbms_pipe", "decode", "drithsx", "dual", "extractvalue",
+		"get_host_address", "instr", "listagg", "minus", "nvl", "receive_message",
+		"rownum", "substr", "sys_context", "to_char", "user_tables", "userenv",
+		"utl_http", "utl_inaddr", "v$version", "xmltype",
+	},
+	SQLite: {
+		"attach", "detach", "glob", "group_concat", "hex", "instr",
+		"json_extract", "last_insert_rowid", "load_extension", "pragma", "printf",
+		"randomblob", "sqlite_master", "sqlite_schema", "sqlite_version", "substr",
+		"total_changes", "typeof", "unicode", "vacuum", "zeroblob",
+	},
+}
+
+// Keywords returns the sorted keywords of the dialects; all of the dialects
+// are used if none are given
+func Keywords(names ...string) ([]string, error) {
+	if len(names) == 0 {
+		names = Dialects
+	}
+	set := make(map[string]bool)
+	for _, keyword := range common {
+		set[keyword] = true
+	}
+	for _, name := range names {
+		keywords, ok := dialects[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown dialect %q", name)
+		}
+		for _, keyword := range keywords {
+			set[keyword] = true
+		}
+	}
+	keywords := make([]string, 0, len(set))
+	for keyword := range set {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	return keywords, nil
+}
@@ -0,0 +1,359 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lexer is a forgiving SQL lexer for detecting SQL injection; it
+// lexes any input, including fragments of SQL and unterminated literals
+package lexer
+
+import (
+	"bytes"
+	"sort"
+)
+
+// MaxToken is the maximum length of a token in bytes; longer literals and
+// comments are split
+const MaxToken = 1024
+
+// Kind is the kind of a token
+type Kind int
+
+const (
+	// KindByte is a single byte: punctuation, a single byte operator or an
+	// unknown byte
+	KindByte Kind = iota
+	// KindSpace is a run of white space
+	KindSpace
+	// KindString is a single quoted string literal
+	KindString
+	// KindQuoted is a double quoted string or identifier
+	KindQuoted
+	// KindBacktick is a backtick quoted identifier
+	KindBacktick
+	// KindNumber is a numeric literal
+	KindNumber
+	// KindHex is a hexadecimal or binary literal
+	KindHex
+	// KindIdentifier is an identifier that isn't a keyword
+	KindIdentifier
+	// KindVariable is a user or system variable such as @a or @@version
+	KindVariable
+	// KindComment is a line or block comment
+	KindComment
+	// KindConditional is the start of a MySQL executable comment or an
+	// Oracle hint, such as /*!50000 or /*+
+	KindConditional
+	// KindQuote is a quote that doesn't start a terminated literal
+	KindQuote
+	// KindOperator is a multi byte operator
+	KindOperator
+	// KindKeyword is a keyword of the dialects of the lexer
+	KindKeyword
+)
+
+var kindNames = [...]string{
+	KindByte:        "byte",
+	KindSpace:       "space",
+	KindString:      "string",
+	KindQuoted:      "quoted",
+	KindBacktick:    "backtick",
+	KindNumber:      "number",
+	KindHex:         "hex",
+	KindIdentifier:  "identifier",
+	KindVariable:    "variable",
+	KindComment:     "comment",
+	KindConditional: "conditional",
+	KindQuote:       "quote",
+	KindOperator:    "operator",
+	KindKeyword:     "keyword",
+}
+
+// String returns the name of the kind
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+// Operators are the multi byte operators, longest first
+var Operators = []string{
+	"->>",
+	"<=>",
+	"!=",
+	"&&",
+	"*/",
+	"->",
+	"::",
+	":=",
+	"<<",
+	"<=",
+	"<>",
+	">=",
+	">>",
+	"||",
+}
+
+// Token is a lexed token; the token is input[Start:End]
+type Token struct {
+	Kind  Kind
+	Start int
+	End   int
+}
+
+// Lexer lexes SQL with the keywords of a set of dialects
+type Lexer struct {
+	keywords map[string]int
+	list     []string
+}
+
+// New creates a lexer for the keywords; the keywords are lower case
+func New(keywords []string) *Lexer {
+	list := append([]string(nil), keywords...)
+	sort.Strings(list)
+	index := make(map[string]int, len(list))
+	for i, keyword := range list {
+		index[keyword] = i
+	}
+	return &Lexer{
+		keywords: index,
+		list:     list,
+	}
+}
+
+// NewDialects creates a lexer for the keywords of the dialects
+func NewDialects(dialects ...string) (*Lexer, error) {
+	keywords, err := Keywords(dialects...)
+	if err != nil {
+		return nil, err
+	}
+	return New(keywords), nil
+}
+
+// Keywords returns the sorted keywords of the lexer
+func (l *Lexer) Keywords() []string {
+	return l.list
+}
+
+// Keyword returns the index of the keyword token in the sorted keywords
+func (l *Lexer) Keyword(input []byte, t Token) (int, bool) {
+	if t.Kind != KindKeyword {
+		return 0, false
+	}
+	index, ok := l.keywords[string(bytes.ToLower(input[t.Start:t.End]))]
+	return index, ok
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' || c >= 0x80
+}
+
+func isIdentifier(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '$'
+}
+
+// literal returns the end of the quoted literal that starts at position i of
+// the input, or -1 if it isn't terminated; quotes are escaped by doubling
+// them, and by a backslash if backslash is true, except in backtick quoted
+// identifiers. MySQL escapes quotes with a backslash, the other dialects don't
+func literal(input []byte, i int, backslash bool) int {
+	c := input[i]
+	for j := i + 1; j < len(input); j++ {
+		switch input[j] {
+		case '\\':
+			if backslash && c != '`' {
+				j++
+			}
+		case c:
+			if j+1 < len(input) && input[j+1] == c {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// Next lexes the token at position i of the input
+func (l *Lexer) Next(input []byte, i int) Token {
+	end := len(input)
+	if end-i > MaxToken {
+		end = i + MaxToken
+	}
+	token := func(kind Kind, j int) Token {
+		if j > end {
+			j = end
+		}
+		return Token{Kind: kind, Start: i, End: j}
+	}
+	at := func(j int) byte {
+		if j < end {
+			return input[j]
+		}
+		return 0
+	}
+	span := func(j int, in func(c byte) bool) int {
+		for j < end && in(input[j]) {
+			j++
+		}
+		return j
+	}
+
+	c := input[i]
+	switch {
+	case isSpace(c):
+		return token(KindSpace, span(i+1, isSpace))
+	case c == '\'' || c == '"' || c == '`':
+		kind := KindString
+		if c == '"' {
+			kind = KindQuoted
+		} else if c == '`' {
+			kind = KindBacktick
+		}
+		if j := literal(input[:end], i, true); j >= 0 {
+			return token(kind, j)
+		}
+		if end-i == MaxToken {
+			return token(kind, end)
+		}
+		return token(KindQuote, i+1)
+	case c == '-' && at(i+1) == '-', c == '#':
+		j := i + 1
+		for j < end && input[j] != '\n' {
+			j++
+		}
+		return token(KindComment, j)
+	case c == '/' && at(i+1) == '*':
+		if next := at(i + 2); next == '!' || next == '+' {
+			return token(KindConditional, span(i+3, isDigit))
+		}
+		index := bytes.Index(input[i+2:end], []byte("*/"))
+		if index < 0 {
+			return token(KindComment, end)
+		}
+		return token(KindComment, i+2+index+2)
+	case c == '0' && (at(i+1) == 'x' || at(i+1) == 'X') && isHex(at(i+2)):
+		return token(KindHex, span(i+2, isHex))
+	case c == '0' && (at(i+1) == 'b' || at(i+1) == 'B') && (at(i+2) == '0' || at(i+2) == '1'):
+		return token(KindHex, span(i+2, func(c byte) bool { return c == '0' || c == '1' }))
+	case isDigit(c) || c == '.' && isDigit(at(i+1)):
+		j := span(i, isDigit)
+		if at(j) == '.' {
+			j = span(j+1, isDigit)
+		}
+		if e := at(j); e == 'e' || e == 'E' {
+			k := j + 1
+			if s := at(k); s == '+' || s == '-' {
+				k++
+			}
+			if isDigit(at(k)) {
+				j = span(k, isDigit)
+			}
+		}
+		return token(KindNumber, j)
+	case c == '@':
+		j := i + 1
+		if at(j) == '@' {
+			j++
+		}
+		if !isIdentifier(at(j)) {
+			return token(KindByte, i+1)
+		}
+		return token(KindVariable, span(j, func(c byte) bool { return isIdentifier(c) || c == '.' }))
+	case isLetter(c):
+		j := span(i+1, isIdentifier)
+		if _, ok := l.keywords[string(bytes.ToLower(input[i:j]))]; ok {
+			return token(KindKeyword, j)
+		}
+		return token(KindIdentifier, j)
+	}
+	for _, operator := range Operators {
+		if bytes.HasPrefix(input[i:end], []byte(operator)) {
+			return token(KindOperator, i+len(operator))
+		}
+	}
+	return token(KindByte, i+1)
+}
+
+// Lex lexes the input
+func (l *Lexer) Lex(input []byte) []Token {
+	var tokens []Token
+	for i := 0; i < len(input); {
+		t := l.Next(input, i)
+		tokens = append(tokens, t)
+		i = t.End
+	}
+	return tokens
+}
+
+// LexValue lexes a value that may be interpolated inside of a quoted
+// literal, where an injection usually starts by closing the literal. The
+// value is lexed at the top level, and as though it starts inside of a
+// single or a double quoted literal, with and without backslash escapes; the
+// lexing with the most significant tokens outside of literals is returned,
+// and the top level lexing on a tie. When the value starts inside of a
+// literal, the value up to the quote that closes the literal is a string or
+// quoted token
+func (l *Lexer) LexValue(input []byte) []Token {
+	best := l.Lex(input)
+	count := countSignificant(input, best)
+	for _, quote := range []byte{'\'', '"'} {
+		if bytes.IndexByte(input, quote) < 0 {
+			continue
+		}
+		query := append([]byte{quote}, input...)
+		for _, backslash := range []bool{true, false} {
+			end := literal(query, 0, backslash)
+			if end < 0 {
+				continue
+			}
+			// the end of the literal in the value
+			end--
+			kind := KindString
+			if quote == '"' {
+				kind = KindQuoted
+			}
+			var tokens []Token
+			for i := 0; i < end; i += MaxToken {
+				j := i + MaxToken
+				if j > end {
+					j = end
+				}
+				tokens = append(tokens, Token{Kind: kind, Start: i, End: j})
+			}
+			for i := end; i < len(input); {
+				t := l.Next(input, i)
+				tokens = append(tokens, t)
+				i = t.End
+			}
+			if c := countSignificant(input, tokens); c > count {
+				best, count = tokens, c
+			}
+		}
+	}
+	return best
+}
+
+// countSignificant counts the tokens that change the meaning of a query
+func countSignificant(input []byte, tokens []Token) int {
+	count := 0
+	for _, t := range tokens {
+		if significant(input, t) {
+			count++
+		}
+	}
+	return count
+}
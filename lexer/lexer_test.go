@@ -0,0 +1,166 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	l, err := NewDialects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		input  string
+		tokens string
+	}{
+		{"orcat1", "identifier"},
+		{"' or 1=1 --", "quote space keyword space number byte number space comment"},
+		{"admin' or 'a'='a", "identifier string identifier string identifier"},
+		{"'it''s' \"x\" `t`", "string space quoted space backtick"},
+		{"'a\\'b'", "string"},
+		{"1.5e-3 .5 0x41 0b101", "number space number space hex space hex"},
+		{"@@version @a @", "variable space variable space byte"},
+		{"/*!50000union*/ /*c*/ /* open", "conditional keyword operator space comment space comment"},
+		{"a<=>b||c <> d", "identifier operator identifier operator identifier space operator space identifier"},
+		{"# comment\nselect", "comment space keyword"},
+		{"SELECT Sleep(5)", "keyword space keyword byte number byte"},
+		{"pg_sleep(1);waitfor delay '0:0:5'", "keyword byte number byte byte keyword space keyword space string"},
+		{"v$version", "keyword"},
+	}
+	for _, test := range tests {
+		input := []byte(test.input)
+		var kinds []string
+		end := 0
+		for _, token := range l.Lex(input) {
+			if token.Start != end {
+				t.Fatal("tokens are not contiguous", test.input)
+			}
+			end = token.End
+			kinds = append(kinds, token.Kind.String())
+		}
+		if end != len(input) {
+			t.Fatal("tokens don't cover the input", test.input)
+		}
+		if strings.Join(kinds, " ") != test.tokens {
+			t.Fatalf("%q: got %q, expected %q", test.input, strings.Join(kinds, " "), test.tokens)
+		}
+	}
+
+	values := []struct {
+		input  string
+		tokens string
+	}{
+		{"admin' or 'a'='a", "string space keyword space string byte quote identifier"},
+		{"x\" or \"1\"=\"1", "quoted space keyword space quoted byte quote number"},
+		{"' or 1=1 --", "quote space keyword space number byte number space comment"},
+		{"o'brien", "identifier quote identifier"},
+		{"it's 'a' dog", "identifier string identifier quote space identifier"},
+	}
+	for _, value := range values {
+		input := []byte(value.input)
+		var kinds []string
+		end := 0
+		for _, token := range l.LexValue(input) {
+			if token.Start != end {
+				t.Fatal("tokens are not contiguous", value.input)
+			}
+			end = token.End
+			kinds = append(kinds, token.Kind.String())
+		}
+		if end != len(input) {
+			t.Fatal("tokens don't cover the input", value.input)
+		}
+		if strings.Join(kinds, " ") != value.tokens {
+			t.Fatalf("%q: got %q, expected %q", value.input, strings.Join(kinds, " "), value.tokens)
+		}
+	}
+
+	long := []byte("'" + strings.Repeat("a", 2*MaxToken) + "'")
+	tokens := l.Lex(long)
+	for _, token := range tokens {
+		if token.End-token.Start > MaxToken {
+			t.Fatal("token is longer than MaxToken")
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	mysql, err := Keywords(MySQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := Keywords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mysql) >= len(all) {
+		t.Fatal("all dialects should have more keywords than one", len(mysql), len(all))
+	}
+	l := New(mysql)
+	input := []byte("waitfor sleep")
+	tokens := l.Lex(input)
+	if tokens[0].Kind != KindIdentifier || tokens[2].Kind != KindKeyword {
+		t.Fatal("waitfor is a MSSQL keyword and sleep is a MySQL keyword", tokens)
+	}
+	if _, err := Keywords("db2"); err == nil {
+		t.Fatal("unknown dialects should fail")
+	}
+}
+
+func TestEscapes(t *testing.T) {
+	tests := []struct {
+		value   string
+		context Context
+		escapes bool
+	}{
+		{"o'brien", ContextSingleQuote, false},
+		{"d'angelo-smith", ContextSingleQuote, false},
+		{"x' and '1'='1", ContextSingleQuote, true},
+		{"it''s", ContextSingleQuote, false},
+		{"o\\'brien", ContextSingleQuote, false},
+		{"' or 1=1 --", ContextSingleQuote, true},
+		{"admin'--", ContextSingleQuote, true},
+		{"x' and sleep(5)#", ContextSingleQuote, true},
+		{"a\\", ContextSingleQuote, false},
+		{"\\' or 1=1 --", ContextSingleQuote, true},
+		{"\\\" or 1=1 --", ContextDoubleQuote, true},
+		{"' or 1=1 --", ContextDoubleQuote, false},
+		{"\" or \"\"=\"", ContextDoubleQuote, true},
+		{"say \"hi\"", ContextDoubleQuote, false},
+		{"42", ContextNumeric, false},
+		{"-4.2e1", ContextNumeric, false},
+		{"0x41", ContextNumeric, false},
+		{"1 or 1=1", ContextNumeric, true},
+		{"1) union select 1", ContextNumeric, true},
+		{"1;drop table users", ContextNumeric, true},
+		{"name", ContextIdentifier, false},
+		{"order", ContextIdentifier, false},
+		{"`weird name`", ContextIdentifier, false},
+		{"name desc, (select 1)", ContextIdentifier, true},
+		{"50% off_", ContextLike, false},
+		{"%' union select 1 --", ContextLike, true},
+	}
+	for _, test := range tests {
+		if escapes := Escapes(test.value, test.context); escapes != test.escapes {
+			t.Fatalf("%q in %v: escapes is %v, expected %v", test.value, test.context, escapes, test.escapes)
+		}
+	}
+
+	for _, name := range []string{"", "single", "double", "numeric", "identifier", "like"} {
+		context, err := ParseContext(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != "" && context.String() != name {
+			t.Fatal("invalid context name", context, name)
+		}
+	}
+	if _, err := ParseContext("json"); err == nil {
+		t.Fatal("unknown contexts should fail")
+	}
+}
@@ -0,0 +1,381 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package middleware provides a net/http handler that scans requests for
+// SQL injection attacks
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+)
+
+var (
+	// ErrorBodyTooLarge means the body is larger than the maximum body size
+	ErrorBodyTooLarge = errors.New("request body is too large to scan")
+	// ErrorMalformedBody means the content type or the body can't be parsed
+	ErrorMalformedBody = errors.New("request body can't be parsed")
+)
+
+// Detector detects SQL injection attacks; it is implemented by *gru.DetectorPool
+type Detector interface {
+	Detect(ctx context.Context, a string) (float32, error)
+}
+
+// Action is what the middleware does when an attack is detected
+type Action int
+
+const (
+	// ActionBlock responds with 403 Forbidden
+	ActionBlock Action = iota
+	// ActionLog logs the attack and passes the request on
+	ActionLog
+	// ActionAnnotate adds the findings to the request and passes it on
+	ActionAnnotate
+)
+
+// Source is where in the request a value was found
+type Source string
+
+const (
+	// SourceQuery is a URL query parameter
+	SourceQuery Source = "query"
+	// SourceForm is a url encoded form field
+	SourceForm Source = "form"
+	// SourceMultipart is a multipart form field
+	SourceMultipart Source = "multipart"
+	// SourceJSON is a string in a JSON body
+	SourceJSON Source = "json"
+	// SourceHeader is a header
+	SourceHeader Source = "header"
+	// SourceCookie is a cookie
+	SourceCookie Source = "cookie"
+	// SourceBody is a body without a content type, which is scanned as one value
+	SourceBody Source = "body"
+)
+
+const (
+	// DefaultThreshold is the default attack probability threshold
+	DefaultThreshold = 50
+	// DefaultMaxBodySize is the default maximum number of body bytes scanned
+	DefaultMaxBodySize = 1 << 20
+	// HeaderProbability is the request header set by ActionAnnotate
+	HeaderProbability = "X-Injectsec-Probability"
+)
+
+// DefaultHeaders are the headers scanned by default
+var DefaultHeaders = []string{
+	"User-Agent",
+	"Referer",
+	"X-Forwarded-For",
+}
+
+// Options configure the middleware
+type Options struct {
+	// Threshold is the probability above which a value is an attack; defaults to DefaultThreshold
+	Threshold float32
+	// Action is what to do when an attack is detected
+	Action Action
+	// Headers are the headers to scan; nil means DefaultHeaders
+	Headers []string
+	// SkipQuery disables scanning of the URL query
+	SkipQuery bool
+	// SkipBody disables scanning of form and JSON bodies
+	SkipBody bool
+	// SkipCookies disables scanning of cookies
+	SkipCookies bool
+	// MaxBodySize is the maximum size of a body that is scanned; larger form,
+	// JSON and untyped bodies are rejected. Defaults to DefaultMaxBodySize
+	MaxBodySize int64
+	// Logger is used for ActionLog and errors; defaults to stderr
+	Logger *log.Logger
+}
+
+// Finding is a scanned value that is an attack
+type Finding struct {
+	Source      Source
+	Name        string
+	Value       string
+	Probability float32
+}
+
+type contextKey struct{}
+
+// Findings returns the findings added to a request by ActionAnnotate
+func Findings(r *http.Request) []Finding {
+	findings, _ := r.Context().Value(contextKey{}).([]Finding)
+	return findings
+}
+
+// Middleware scans requests for SQL injection attacks
+type Middleware struct {
+	detector Detector
+	options  Options
+	next     http.Handler
+}
+
+// New creates a new middleware constructor
+func New(detector Detector, options Options) func(http.Handler) http.Handler {
+	if options.Threshold == 0 {
+		options.Threshold = DefaultThreshold
+	}
+	if options.Headers == nil {
+		options.Headers = DefaultHeaders
+	}
+	if options.MaxBodySize == 0 {
+		options.MaxBodySize = DefaultMaxBodySize
+	}
+	if options.Logger == nil {
+		options.Logger = log.New(os.Stderr, "injectsec: ", log.LstdFlags)
+	}
+	return func(next http.Handler) http.Handler {
+		return &Middleware{
+			detector: detector,
+			options:  options,
+			next:     next,
+		}
+	}
+}
+
+// Handler wraps a handler with the middleware
+func Handler(next http.Handler, detector Detector, options Options) http.Handler {
+	return New(detector, options)(next)
+}
+
+// ServeHTTP implements http.Handler
+func (m *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	findings, err := m.Scan(r)
+	if err != nil {
+		status := http.StatusServiceUnavailable
+		if errors.Is(err, ErrorBodyTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		} else if errors.Is(err, ErrorMalformedBody) {
+			status = http.StatusBadRequest
+		}
+		m.options.Logger.Printf("rejected %s %s: %v", r.Method, r.URL.Path, err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if len(findings) == 0 {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+
+	switch m.options.Action {
+	case ActionBlock:
+		for _, finding := range findings {
+			m.options.Logger.Printf("blocked %s %s: %s %q %q %.2f", r.Method, r.URL.Path,
+				finding.Source, finding.Name, finding.Value, finding.Probability)
+		}
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	case ActionLog:
+		for _, finding := range findings {
+			m.options.Logger.Printf("detected %s %s: %s %q %q %.2f", r.Method, r.URL.Path,
+				finding.Source, finding.Name, finding.Value, finding.Probability)
+		}
+	case ActionAnnotate:
+		max := float32(0)
+		for _, finding := range findings {
+			if finding.Probability > max {
+				max = finding.Probability
+			}
+		}
+		r.Header.Set(HeaderProbability, strconv.FormatFloat(float64(max), 'f', 2, 32))
+		r = r.WithContext(context.WithValue(r.Context(), contextKey{}, findings))
+	}
+	m.next.ServeHTTP(w, r)
+}
+
+// Scan scans a request and returns the values that are attacks; the body is
+// restored. Requests that can't be scanned fail closed: a form, JSON or
+// untyped body that is larger than MaxBodySize is ErrorBodyTooLarge, and a
+// malformed content type or body is ErrorMalformedBody. A body without a
+// content type is scanned as one value, and bodies of other content types
+// aren't scanned
+func (m *Middleware) Scan(r *http.Request) ([]Finding, error) {
+	ctx := r.Context()
+	var findings []Finding
+	scan := func(source Source, name string, values ...string) (bool, error) {
+		for _, value := range values {
+			probability, err := m.detector.Detect(ctx, value)
+			if err != nil {
+				return false, err
+			}
+			if probability > m.options.Threshold {
+				findings = append(findings, Finding{
+					Source:      source,
+					Name:        name,
+					Value:       value,
+					Probability: probability,
+				})
+				if m.options.Action == ActionBlock {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+	scanValues := func(source Source, values url.Values) (bool, error) {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			done, err := scan(source, key, values[key]...)
+			if done || err != nil {
+				return done, err
+			}
+		}
+		return false, nil
+	}
+
+	if !m.options.SkipQuery {
+		done, err := scanValues(SourceQuery, r.URL.Query())
+		if done || err != nil {
+			return findings, err
+		}
+	}
+
+	for _, header := range m.options.Headers {
+		done, err := scan(SourceHeader, header, r.Header[http.CanonicalHeaderKey(header)]...)
+		if done || err != nil {
+			return findings, err
+		}
+	}
+
+	if !m.options.SkipCookies {
+		for _, cookie := range r.Cookies() {
+			done, err := scan(SourceCookie, cookie.Name, cookie.Value)
+			if done || err != nil {
+				return findings, err
+			}
+		}
+	}
+
+	if m.options.SkipBody || r.Body == nil {
+		return findings, nil
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		body, err := m.readBody(r)
+		if err != nil || len(body) == 0 {
+			return findings, err
+		}
+		_, err = scan(SourceBody, "", string(body))
+		return findings, err
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return findings, fmt.Errorf("%w: %v", ErrorMalformedBody, err)
+	}
+	switch mediaType {
+	case "application/x-www-form-urlencoded", "multipart/form-data", "application/json":
+	default:
+		return findings, nil
+	}
+	body, err := m.readBody(r)
+	if err != nil {
+		return findings, err
+	}
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return findings, fmt.Errorf("%w: %v", ErrorMalformedBody, err)
+		}
+		_, err = scanValues(SourceForm, values)
+		return findings, err
+	case "multipart/form-data":
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		form, err := reader.ReadForm(m.options.MaxBodySize)
+		if err != nil {
+			return findings, fmt.Errorf("%w: %v", ErrorMalformedBody, err)
+		}
+		defer form.RemoveAll()
+		_, err = scanValues(SourceMultipart, url.Values(form.Value))
+		return findings, err
+	case "application/json":
+		var value interface{}
+		err := json.Unmarshal(body, &value)
+		if err != nil {
+			return findings, fmt.Errorf("%w: %v", ErrorMalformedBody, err)
+		}
+		_, err = walk(value, "", func(name, s string) (bool, error) {
+			return scan(SourceJSON, name, s)
+		})
+		return findings, err
+	}
+
+	return findings, nil
+}
+
+// readBody reads the body and restores it; a body larger than MaxBodySize is ErrorBodyTooLarge
+func (m *Middleware) readBody(r *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, m.options.MaxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.MultiReader(bytes.NewReader(body), r.Body),
+		Closer: r.Body,
+	}
+	if int64(len(body)) > m.options.MaxBodySize {
+		return nil, ErrorBodyTooLarge
+	}
+	return body, nil
+}
+
+func walk(value interface{}, name string, visit func(name, s string) (bool, error)) (bool, error) {
+	switch v := value.(type) {
+	case string:
+		return visit(name, v)
+	case []interface{}:
+		for _, item := range v {
+			done, err := walk(item, name, visit)
+			if done || err != nil {
+				return done, err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			path := key
+			if name != "" {
+				path = name + "." + key
+			}
+			done, err := visit(path, key)
+			if done || err != nil {
+				return done, err
+			}
+			done, err = walk(v[key], path, visit)
+			if done || err != nil {
+				return done, err
+			}
+		}
+	}
+	return false, nil
+}
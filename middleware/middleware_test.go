@@ -0,0 +1,154 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type testDetector struct{}
+
+func (testDetector) Detect(ctx context.Context, a string) (float32, error) {
+	if strings.Contains(strings.ToLower(a), " or 1=1") {
+		return 100, nil
+	}
+	return 0, nil
+}
+
+func testHandler(t *testing.T, action Action) (http.Handler, *[]byte) {
+	body := new([]byte)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		*body = data
+		w.Header().Set(HeaderProbability, r.Header.Get(HeaderProbability))
+		w.WriteHeader(http.StatusOK)
+	})
+	options := Options{
+		Action: action,
+		Logger: log.New(ioutil.Discard, "", 0),
+	}
+	return Handler(next, testDetector{}, options), body
+}
+
+func TestMiddleware(t *testing.T) {
+	handler, _ := testHandler(t, ActionBlock)
+
+	multipartBody := &bytes.Buffer{}
+	writer := multipart.NewWriter(multipartBody)
+	writer.WriteField("name", "x' or 1=1 --")
+	writer.Close()
+
+	requests := []struct {
+		name    string
+		request func() *http.Request
+		status  int
+	}{
+		{"clean", func() *http.Request {
+			return httptest.NewRequest("GET", "/?name=alice", nil)
+		}, http.StatusOK},
+		{"query", func() *http.Request {
+			return httptest.NewRequest("GET", "/?name="+url.QueryEscape("x' or 1=1 --"), nil)
+		}, http.StatusForbidden},
+		{"form", func() *http.Request {
+			r := httptest.NewRequest("POST", "/", strings.NewReader("name="+url.QueryEscape("x' or 1=1 --")))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return r
+		}, http.StatusForbidden},
+		{"multipart", func() *http.Request {
+			r := httptest.NewRequest("POST", "/", bytes.NewReader(multipartBody.Bytes()))
+			r.Header.Set("Content-Type", writer.FormDataContentType())
+			return r
+		}, http.StatusForbidden},
+		{"json", func() *http.Request {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(`{"user": {"names": ["bob", "x' or 1=1 --"]}}`))
+			r.Header.Set("Content-Type", "application/json")
+			return r
+		}, http.StatusForbidden},
+		{"header", func() *http.Request {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("User-Agent", "x' or 1=1 --")
+			return r
+		}, http.StatusForbidden},
+		{"cookie", func() *http.Request {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.AddCookie(&http.Cookie{Name: "session", Value: "x' or 1=1 --"})
+			return r
+		}, http.StatusForbidden},
+		{"untyped body", func() *http.Request {
+			return httptest.NewRequest("POST", "/", strings.NewReader("x' or 1=1 --"))
+		}, http.StatusForbidden},
+		{"oversized json", func() *http.Request {
+			padding := strings.Repeat(" ", DefaultMaxBodySize)
+			r := httptest.NewRequest("POST", "/", strings.NewReader(`{"name": "bob"`+padding+`, "x": "x' or 1=1 --"}`))
+			r.Header.Set("Content-Type", "application/json")
+			return r
+		}, http.StatusRequestEntityTooLarge},
+		{"oversized untyped body", func() *http.Request {
+			return httptest.NewRequest("POST", "/", strings.NewReader(strings.Repeat("a", DefaultMaxBodySize+1)))
+		}, http.StatusRequestEntityTooLarge},
+		{"malformed content type", func() *http.Request {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(`{"name": "x' or 1=1 --"}`))
+			r.Header.Set("Content-Type", "application/json; charset")
+			return r
+		}, http.StatusBadRequest},
+		{"malformed json", func() *http.Request {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(`{"name": "x' or 1=1 --"`))
+			r.Header.Set("Content-Type", "application/json")
+			return r
+		}, http.StatusBadRequest},
+		{"malformed form", func() *http.Request {
+			r := httptest.NewRequest("POST", "/", strings.NewReader("name=x'%20or%201=1%20--&bad=%zz"))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return r
+		}, http.StatusBadRequest},
+		{"malformed multipart", func() *http.Request {
+			r := httptest.NewRequest("POST", "/", bytes.NewReader(multipartBody.Bytes()))
+			r.Header.Set("Content-Type", "multipart/form-data")
+			return r
+		}, http.StatusBadRequest},
+		{"other content type", func() *http.Request {
+			r := httptest.NewRequest("POST", "/", strings.NewReader("x' or 1=1 --"))
+			r.Header.Set("Content-Type", "image/png")
+			return r
+		}, http.StatusOK},
+	}
+	for _, test := range requests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, test.request())
+		if recorder.Code != test.status {
+			t.Fatal(test.name, "expected status", test.status, "got", recorder.Code)
+		}
+	}
+}
+
+func TestMiddlewareAnnotate(t *testing.T) {
+	handler, body := testHandler(t, ActionAnnotate)
+	form := "name=" + url.QueryEscape("x' or 1=1 --")
+	r := httptest.NewRequest("POST", "/", strings.NewReader(form))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r)
+	if recorder.Code != http.StatusOK {
+		t.Fatal("request should not be blocked", recorder.Code)
+	}
+	if recorder.Header().Get(HeaderProbability) != "100.00" {
+		t.Fatal("request should be annotated", recorder.Header().Get(HeaderProbability))
+	}
+	if string(*body) != form {
+		t.Fatal("body was not restored", string(*body))
+	}
+}
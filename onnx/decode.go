@@ -0,0 +1,396 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// ErrorTruncated means the protobuf ended in the middle of a field
+var ErrorTruncated = fmt.Errorf("truncated protobuf")
+
+// field is a decoded protobuf field
+type field struct {
+	number int
+	wire   int
+	varint uint64
+	fixed  uint64
+	bytes  []byte
+}
+
+func readVarint(b []byte) (uint64, int, error) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * uint(i))
+		if b[i] < 0x80 {
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, ErrorTruncated
+}
+
+// parse calls fn for each field of the message b
+func parse(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		tag, n, err := readVarint(b)
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+		f := field{number: int(tag >> 3), wire: int(tag & 7)}
+		switch f.wire {
+		case wireVarint:
+			f.varint, n, err = readVarint(b)
+			if err != nil {
+				return err
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return ErrorTruncated
+			}
+			f.fixed, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return ErrorTruncated
+			}
+			f.fixed, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			length, n, err := readVarint(b)
+			if err != nil {
+				return err
+			}
+			b = b[n:]
+			if uint64(len(b)) < length {
+				return ErrorTruncated
+			}
+			f.bytes, b = b[:length], b[length:]
+		default:
+			return fmt.Errorf("unsupported wire type %d", f.wire)
+		}
+		err = fn(f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ints decodes a packed or unpacked repeated int field
+func (f field) ints() ([]int64, error) {
+	if f.wire == wireVarint {
+		return []int64{int64(f.varint)}, nil
+	}
+	var ints []int64
+	b := f.bytes
+	for len(b) > 0 {
+		v, n, err := readVarint(b)
+		if err != nil {
+			return nil, err
+		}
+		ints, b = append(ints, int64(v)), b[n:]
+	}
+	return ints, nil
+}
+
+// floats decodes a packed or unpacked repeated float field
+func (f field) floats() ([]float32, error) {
+	if f.wire == wireFixed32 {
+		return []float32{math.Float32frombits(uint32(f.fixed))}, nil
+	}
+	if len(f.bytes)%4 != 0 {
+		return nil, ErrorTruncated
+	}
+	floats := make([]float32, len(f.bytes)/4)
+	for i := range floats {
+		floats[i] = math.Float32frombits(binary.LittleEndian.Uint32(f.bytes[4*i:]))
+	}
+	return floats, nil
+}
+
+// Unmarshal decodes an ONNX protobuf
+func Unmarshal(b []byte) (*Model, error) {
+	m := &Model{}
+	err := parse(b, func(f field) error {
+		switch f.number {
+		case 1:
+			m.IRVersion = int64(f.varint)
+		case 2:
+			m.ProducerName = string(f.bytes)
+		case 3:
+			m.ProducerVersion = string(f.bytes)
+		case 7:
+			graph, err := unmarshalGraph(f.bytes)
+			if err != nil {
+				return err
+			}
+			m.Graph = graph
+		case 8:
+			var opset Opset
+			err := parse(f.bytes, func(f field) error {
+				switch f.number {
+				case 1:
+					opset.Domain = string(f.bytes)
+				case 2:
+					opset.Version = int64(f.varint)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			m.Opsets = append(m.Opsets, opset)
+		case 14:
+			var key, value string
+			err := parse(f.bytes, func(f field) error {
+				switch f.number {
+				case 1:
+					key = string(f.bytes)
+				case 2:
+					value = string(f.bytes)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if m.Metadata == nil {
+				m.Metadata = make(map[string]string)
+			}
+			m.Metadata[key] = value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if m.Graph == nil {
+		return nil, fmt.Errorf("model has no graph")
+	}
+	return m, nil
+}
+
+// Read reads an ONNX model from a Reader
+func Read(in io.Reader) (*Model, error) {
+	b, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal(b)
+}
+
+func unmarshalGraph(b []byte) (*Graph, error) {
+	g := &Graph{}
+	err := parse(b, func(f field) error {
+		switch f.number {
+		case 1:
+			node, err := unmarshalNode(f.bytes)
+			if err != nil {
+				return err
+			}
+			g.Nodes = append(g.Nodes, node)
+		case 2:
+			g.Name = string(f.bytes)
+		case 5:
+			tensor, err := unmarshalTensor(f.bytes)
+			if err != nil {
+				return err
+			}
+			g.Initializers = append(g.Initializers, tensor)
+		case 10:
+			g.Doc = string(f.bytes)
+		case 11, 12:
+			value, err := unmarshalValueInfo(f.bytes)
+			if err != nil {
+				return err
+			}
+			if f.number == 11 {
+				g.Inputs = append(g.Inputs, value)
+			} else {
+				g.Outputs = append(g.Outputs, value)
+			}
+		}
+		return nil
+	})
+	return g, err
+}
+
+func unmarshalNode(b []byte) (*Node, error) {
+	n := &Node{}
+	err := parse(b, func(f field) error {
+		switch f.number {
+		case 1:
+			n.Inputs = append(n.Inputs, string(f.bytes))
+		case 2:
+			n.Outputs = append(n.Outputs, string(f.bytes))
+		case 3:
+			n.Name = string(f.bytes)
+		case 4:
+			n.OpType = string(f.bytes)
+		case 5:
+			attribute, err := unmarshalAttribute(f.bytes)
+			if err != nil {
+				return err
+			}
+			n.Attributes = append(n.Attributes, attribute)
+		case 7:
+			n.Domain = string(f.bytes)
+		}
+		return nil
+	})
+	return n, err
+}
+
+func unmarshalAttribute(b []byte) (*Attribute, error) {
+	a := &Attribute{}
+	err := parse(b, func(f field) error {
+		switch f.number {
+		case 1:
+			a.Name = string(f.bytes)
+		case 2:
+			a.F = math.Float32frombits(uint32(f.fixed))
+		case 3:
+			a.I = int64(f.varint)
+		case 4:
+			a.S = f.bytes
+		case 5:
+			tensor, err := unmarshalTensor(f.bytes)
+			if err != nil {
+				return err
+			}
+			a.T = tensor
+		case 7:
+			floats, err := f.floats()
+			if err != nil {
+				return err
+			}
+			a.Floats = append(a.Floats, floats...)
+		case 8:
+			ints, err := f.ints()
+			if err != nil {
+				return err
+			}
+			a.Ints = append(a.Ints, ints...)
+		case 20:
+			a.Type = AttributeType(f.varint)
+		}
+		return nil
+	})
+	return a, err
+}
+
+func unmarshalTensor(b []byte) (*Tensor, error) {
+	t := &Tensor{}
+	var raw []byte
+	err := parse(b, func(f field) error {
+		switch f.number {
+		case 1:
+			dims, err := f.ints()
+			if err != nil {
+				return err
+			}
+			t.Dims = append(t.Dims, dims...)
+		case 2:
+			t.DataType = DataType(f.varint)
+		case 4:
+			floats, err := f.floats()
+			if err != nil {
+				return err
+			}
+			t.Float = append(t.Float, floats...)
+		case 7:
+			ints, err := f.ints()
+			if err != nil {
+				return err
+			}
+			t.Int64 = append(t.Int64, ints...)
+		case 8:
+			t.Name = string(f.bytes)
+		case 9:
+			raw = f.bytes
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if raw != nil {
+		switch t.DataType {
+		case DataTypeFloat:
+			if len(raw)%4 != 0 {
+				return nil, ErrorTruncated
+			}
+			t.Float = make([]float32, len(raw)/4)
+			for i := range t.Float {
+				t.Float[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+			}
+		case DataTypeInt64:
+			if len(raw)%8 != 0 {
+				return nil, ErrorTruncated
+			}
+			t.Int64 = make([]int64, len(raw)/8)
+			for i := range t.Int64 {
+				t.Int64[i] = int64(binary.LittleEndian.Uint64(raw[8*i:]))
+			}
+		default:
+			return nil, fmt.Errorf("unsupported data type %d", t.DataType)
+		}
+	}
+	length := len(t.Float)
+	if t.DataType == DataTypeInt64 {
+		length = len(t.Int64)
+	}
+	if length != t.Size() {
+		return nil, fmt.Errorf("tensor %q has %d elements, expected %d", t.Name, length, t.Size())
+	}
+	return t, nil
+}
+
+func unmarshalValueInfo(b []byte) (*ValueInfo, error) {
+	v := &ValueInfo{}
+	err := parse(b, func(f field) error {
+		switch f.number {
+		case 1:
+			v.Name = string(f.bytes)
+		case 2:
+			return parse(f.bytes, func(f field) error {
+				if f.number != 1 {
+					return nil
+				}
+				return parse(f.bytes, func(f field) error {
+					switch f.number {
+					case 1:
+						v.ElemType = DataType(f.varint)
+					case 2:
+						return parse(f.bytes, func(f field) error {
+							if f.number != 1 {
+								return nil
+							}
+							var dim Dim
+							err := parse(f.bytes, func(f field) error {
+								switch f.number {
+								case 1:
+									dim.Value = int64(f.varint)
+								case 2:
+									dim.Param = string(f.bytes)
+								}
+								return nil
+							})
+							v.Shape = append(v.Shape, dim)
+							return err
+						})
+					}
+					return nil
+				})
+			})
+		}
+		return nil
+	})
+	return v, err
+}
@@ -0,0 +1,395 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package onnx reads, writes and runs the subset of ONNX models needed to
+// serve the GRU models outside of Go
+package onnx
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"sort"
+)
+
+// DataType is the element type of a tensor
+type DataType int32
+
+const (
+	// DataTypeFloat is float32
+	DataTypeFloat DataType = 1
+	// DataTypeInt64 is int64
+	DataTypeInt64 DataType = 7
+)
+
+// AttributeType is the type of an attribute
+type AttributeType int32
+
+const (
+	// AttributeTypeFloat is a float attribute
+	AttributeTypeFloat AttributeType = 1
+	// AttributeTypeInt is an int attribute
+	AttributeTypeInt AttributeType = 2
+	// AttributeTypeString is a string attribute
+	AttributeTypeString AttributeType = 3
+	// AttributeTypeTensor is a tensor attribute
+	AttributeTypeTensor AttributeType = 4
+	// AttributeTypeFloats is a float list attribute
+	AttributeTypeFloats AttributeType = 6
+	// AttributeTypeInts is an int list attribute
+	AttributeTypeInts AttributeType = 7
+)
+
+// IRVersion is the ONNX IR version of written models
+const IRVersion = 7
+
+// Opset is an operator set the model uses
+type Opset struct {
+	Domain  string
+	Version int64
+}
+
+// Model is an ONNX model
+type Model struct {
+	IRVersion       int64
+	ProducerName    string
+	ProducerVersion string
+	Opsets          []Opset
+	Graph           *Graph
+	// Metadata are the metadata properties of the model
+	Metadata map[string]string
+}
+
+// Graph is an ONNX graph; the nodes are topologically sorted
+type Graph struct {
+	Name         string
+	Doc          string
+	Nodes        []*Node
+	Initializers []*Tensor
+	Inputs       []*ValueInfo
+	Outputs      []*ValueInfo
+}
+
+// Node is an operator in a graph
+type Node struct {
+	Name       string
+	OpType     string
+	Domain     string
+	Inputs     []string
+	Outputs    []string
+	Attributes []*Attribute
+}
+
+// Attribute is an attribute of a node
+type Attribute struct {
+	Name   string
+	Type   AttributeType
+	F      float32
+	I      int64
+	S      []byte
+	T      *Tensor
+	Floats []float32
+	Ints   []int64
+}
+
+// Tensor is a dense tensor; Float holds float32 data and Int64 holds int64 data
+type Tensor struct {
+	Name     string
+	Dims     []int64
+	DataType DataType
+	Float    []float32
+	Int64    []int64
+}
+
+// Dim is a dimension of a value; Param names a symbolic dimension
+type Dim struct {
+	Value int64
+	Param string
+}
+
+// ValueInfo describes an input or output of a graph
+type ValueInfo struct {
+	Name     string
+	ElemType DataType
+	Shape    []Dim
+}
+
+// NewFloat creates a float tensor
+func NewFloat(name string, data []float32, dims ...int64) *Tensor {
+	return &Tensor{
+		Name:     name,
+		Dims:     dims,
+		DataType: DataTypeFloat,
+		Float:    data,
+	}
+}
+
+// NewInt64 creates an int64 tensor
+func NewInt64(name string, data []int64, dims ...int64) *Tensor {
+	return &Tensor{
+		Name:     name,
+		Dims:     dims,
+		DataType: DataTypeInt64,
+		Int64:    data,
+	}
+}
+
+// Size is the number of elements of the tensor
+func (t *Tensor) Size() int {
+	size := 1
+	for _, dim := range t.Dims {
+		size *= int(dim)
+	}
+	return size
+}
+
+// Attribute returns the named attribute of the node or nil
+func (n *Node) Attribute(name string) *Attribute {
+	for _, attribute := range n.Attributes {
+		if attribute.Name == name {
+			return attribute
+		}
+	}
+	return nil
+}
+
+// Int returns the named int attribute of the node or fallback
+func (n *Node) Int(name string, fallback int64) int64 {
+	if attribute := n.Attribute(name); attribute != nil {
+		return attribute.I
+	}
+	return fallback
+}
+
+// Float returns the named float attribute of the node or fallback
+func (n *Node) Float(name string, fallback float32) float32 {
+	if attribute := n.Attribute(name); attribute != nil {
+		return attribute.F
+	}
+	return fallback
+}
+
+// String returns the named string attribute of the node or fallback
+func (n *Node) String(name string, fallback string) string {
+	if attribute := n.Attribute(name); attribute != nil {
+		return string(attribute.S)
+	}
+	return fallback
+}
+
+// IntAttribute creates an int attribute
+func IntAttribute(name string, i int64) *Attribute {
+	return &Attribute{Name: name, Type: AttributeTypeInt, I: i}
+}
+
+// FloatAttribute creates a float attribute
+func FloatAttribute(name string, f float32) *Attribute {
+	return &Attribute{Name: name, Type: AttributeTypeFloat, F: f}
+}
+
+// StringAttribute creates a string attribute
+func StringAttribute(name string, s string) *Attribute {
+	return &Attribute{Name: name, Type: AttributeTypeString, S: []byte(s)}
+}
+
+// IntsAttribute creates an int list attribute
+func IntsAttribute(name string, ints ...int64) *Attribute {
+	return &Attribute{Name: name, Type: AttributeTypeInts, Ints: ints}
+}
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendTag(b []byte, field, wire int) []byte {
+	return appendVarint(b, uint64(field<<3|wire))
+}
+
+func appendInt(b []byte, field int, v int64) []byte {
+	b = appendTag(b, field, wireVarint)
+	return appendVarint(b, uint64(v))
+}
+
+func appendBytes(b []byte, field int, data []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func appendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return appendBytes(b, field, []byte(s))
+}
+
+func appendFixed32(b []byte, v uint32) []byte {
+	b = append(b, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b[len(b)-4:], v)
+	return b
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	b = append(b, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint64(b[len(b)-8:], v)
+	return b
+}
+
+func appendFloat(b []byte, field int, f float32) []byte {
+	b = appendTag(b, field, wireFixed32)
+	return appendFixed32(b, math.Float32bits(f))
+}
+
+// Marshal encodes the model as an ONNX protobuf
+func (m *Model) Marshal() []byte {
+	var b []byte
+	b = appendInt(b, 1, m.IRVersion)
+	b = appendString(b, 2, m.ProducerName)
+	b = appendString(b, 3, m.ProducerVersion)
+	if m.Graph != nil {
+		b = appendBytes(b, 7, m.Graph.marshal())
+	}
+	for _, opset := range m.Opsets {
+		var o []byte
+		o = appendString(o, 1, opset.Domain)
+		o = appendInt(o, 2, opset.Version)
+		b = appendBytes(b, 8, o)
+	}
+	keys := make([]string, 0, len(m.Metadata))
+	for key := range m.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var p []byte
+		p = appendString(p, 1, key)
+		p = appendString(p, 2, m.Metadata[key])
+		b = appendBytes(b, 14, p)
+	}
+	return b
+}
+
+// Write writes the model to a Writer
+func (m *Model) Write(out io.Writer) error {
+	_, err := out.Write(m.Marshal())
+	return err
+}
+
+func (g *Graph) marshal() []byte {
+	var b []byte
+	for _, node := range g.Nodes {
+		b = appendBytes(b, 1, node.marshal())
+	}
+	b = appendString(b, 2, g.Name)
+	for _, initializer := range g.Initializers {
+		b = appendBytes(b, 5, initializer.marshal())
+	}
+	b = appendString(b, 10, g.Doc)
+	for _, input := range g.Inputs {
+		b = appendBytes(b, 11, input.marshal())
+	}
+	for _, output := range g.Outputs {
+		b = appendBytes(b, 12, output.marshal())
+	}
+	return b
+}
+
+func (n *Node) marshal() []byte {
+	var b []byte
+	for _, input := range n.Inputs {
+		b = appendBytes(b, 1, []byte(input))
+	}
+	for _, output := range n.Outputs {
+		b = appendBytes(b, 2, []byte(output))
+	}
+	b = appendString(b, 3, n.Name)
+	b = appendString(b, 4, n.OpType)
+	for _, attribute := range n.Attributes {
+		b = appendBytes(b, 5, attribute.marshal())
+	}
+	b = appendString(b, 7, n.Domain)
+	return b
+}
+
+func (a *Attribute) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, a.Name)
+	switch a.Type {
+	case AttributeTypeFloat:
+		b = appendFloat(b, 2, a.F)
+	case AttributeTypeInt:
+		b = appendInt(b, 3, a.I)
+	case AttributeTypeString:
+		b = appendBytes(b, 4, a.S)
+	case AttributeTypeTensor:
+		b = appendBytes(b, 5, a.T.marshal())
+	case AttributeTypeFloats:
+		for _, f := range a.Floats {
+			b = appendFloat(b, 7, f)
+		}
+	case AttributeTypeInts:
+		for _, i := range a.Ints {
+			b = appendInt(b, 8, i)
+		}
+	}
+	b = appendInt(b, 20, int64(a.Type))
+	return b
+}
+
+func (t *Tensor) marshal() []byte {
+	var b []byte
+	for _, dim := range t.Dims {
+		b = appendInt(b, 1, dim)
+	}
+	b = appendInt(b, 2, int64(t.DataType))
+	b = appendString(b, 8, t.Name)
+	var raw []byte
+	switch t.DataType {
+	case DataTypeFloat:
+		raw = make([]byte, 0, 4*len(t.Float))
+		for _, f := range t.Float {
+			raw = appendFixed32(raw, math.Float32bits(f))
+		}
+	case DataTypeInt64:
+		raw = make([]byte, 0, 8*len(t.Int64))
+		for _, i := range t.Int64 {
+			raw = appendFixed64(raw, uint64(i))
+		}
+	}
+	return appendBytes(b, 9, raw)
+}
+
+func (v *ValueInfo) marshal() []byte {
+	var shape []byte
+	for _, dim := range v.Shape {
+		var d []byte
+		if dim.Param != "" {
+			d = appendString(d, 2, dim.Param)
+		} else {
+			d = appendInt(d, 1, dim.Value)
+		}
+		shape = appendBytes(shape, 1, d)
+	}
+	var tensor []byte
+	tensor = appendInt(tensor, 1, int64(v.ElemType))
+	tensor = appendBytes(tensor, 2, shape)
+	var typ []byte
+	typ = appendBytes(typ, 1, tensor)
+
+	var b []byte
+	b = appendString(b, 1, v.Name)
+	return appendBytes(b, 2, typ)
+}
@@ -0,0 +1,154 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	model := &Model{
+		IRVersion:    IRVersion,
+		ProducerName: "test",
+		Opsets:       []Opset{{Version: 13}},
+		Graph: &Graph{
+			Name: "graph",
+			Doc:  "doc",
+			Nodes: []*Node{{
+				Name:    "softmax",
+				OpType:  "Softmax",
+				Inputs:  []string{"x"},
+				Outputs: []string{"y"},
+				Attributes: []*Attribute{
+					IntAttribute("axis", -1),
+					FloatAttribute("alpha", .5),
+					StringAttribute("direction", "forward"),
+					IntsAttribute("perm", 1, 0),
+				},
+			}},
+			Initializers: []*Tensor{
+				NewFloat("w", []float32{1, -2, 3.5, 0}, 2, 2),
+				NewInt64("s", []int64{-1, math.MinInt64}, 2),
+			},
+			Inputs:  []*ValueInfo{{Name: "x", ElemType: DataTypeFloat, Shape: []Dim{{Param: "n"}, {Value: 2}}}},
+			Outputs: []*ValueInfo{{Name: "y", ElemType: DataTypeFloat, Shape: []Dim{{Param: "n"}, {Value: 2}}}},
+		},
+		Metadata: map[string]string{"a": "1", "b": "2"},
+	}
+	buffer := &bytes.Buffer{}
+	err := model.Write(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Read(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(model, decoded) {
+		t.Fatalf("round trip failed %+v %+v", model.Graph, decoded.Graph)
+	}
+
+	b := model.Marshal()
+	for _, length := range []int{1, len(b) / 2, len(b) - 1} {
+		if _, err := Unmarshal(b[:length]); err == nil {
+			t.Fatal("truncated model should fail", length)
+		}
+	}
+}
+
+func run(t *testing.T, node *Node, inputs ...*Tensor) *Tensor {
+	t.Helper()
+	graph := &Graph{Nodes: []*Node{node}}
+	values := make(map[string]*Tensor)
+	for i, input := range inputs {
+		if input == nil {
+			continue
+		}
+		input.Name = node.Inputs[i]
+		graph.Inputs = append(graph.Inputs, &ValueInfo{Name: input.Name})
+		values[input.Name] = input
+	}
+	graph.Outputs = []*ValueInfo{{Name: node.Outputs[0]}}
+	outputs, err := (&Model{Graph: graph}).Run(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return outputs[node.Outputs[0]]
+}
+
+func TestOperators(t *testing.T) {
+	out := run(t, &Node{OpType: "Slice", Inputs: []string{"x", "starts", "ends", "axes", "steps"}, Outputs: []string{"y"}},
+		NewInt64("", []int64{1, 2, 3, 4}, 4),
+		NewInt64("", []int64{-1}, 1), NewInt64("", []int64{math.MinInt64}, 1),
+		NewInt64("", []int64{0}, 1), NewInt64("", []int64{-1}, 1))
+	if !reflect.DeepEqual(out.Int64, []int64{4, 3, 2, 1}) || !reflect.DeepEqual(out.Dims, []int64{4}) {
+		t.Fatal("invalid reverse", out)
+	}
+
+	out = run(t, &Node{OpType: "Slice", Inputs: []string{"x", "starts", "ends"}, Outputs: []string{"y"}},
+		NewFloat("", []float32{1, 2, 3, 4, 5, 6}, 2, 3),
+		NewInt64("", []int64{0, 1}, 2), NewInt64("", []int64{1, 100}, 2))
+	if !reflect.DeepEqual(out.Float, []float32{2, 3}) || !reflect.DeepEqual(out.Dims, []int64{1, 2}) {
+		t.Fatal("invalid slice", out)
+	}
+
+	out = run(t, &Node{OpType: "Reshape", Inputs: []string{"x", "shape"}, Outputs: []string{"y"}},
+		NewFloat("", []float32{1, 2, 3, 4, 5, 6}, 2, 3),
+		NewInt64("", []int64{0, -1, 1}, 3))
+	if !reflect.DeepEqual(out.Dims, []int64{2, 3, 1}) {
+		t.Fatal("invalid reshape", out.Dims)
+	}
+
+	out = run(t, &Node{OpType: "Gather", Inputs: []string{"x", "indices"}, Outputs: []string{"y"}},
+		NewFloat("", []float32{1, 2, 3, 4, 5, 6}, 3, 2),
+		NewInt64("", []int64{2, 0}, 2))
+	if !reflect.DeepEqual(out.Float, []float32{5, 6, 1, 2}) || !reflect.DeepEqual(out.Dims, []int64{2, 2}) {
+		t.Fatal("invalid gather", out)
+	}
+
+	out = run(t, &Node{OpType: "Add", Inputs: []string{"a", "b"}, Outputs: []string{"y"}},
+		NewFloat("", []float32{1, 2, 3, 4}, 2, 2),
+		NewFloat("", []float32{10, 20}, 2))
+	if !reflect.DeepEqual(out.Float, []float32{11, 22, 13, 24}) {
+		t.Fatal("invalid add", out)
+	}
+
+	out = run(t, &Node{OpType: "Concat", Inputs: []string{"a", "b"}, Outputs: []string{"y"}, Attributes: []*Attribute{IntAttribute("axis", 1)}},
+		NewFloat("", []float32{1, 2}, 2, 1),
+		NewFloat("", []float32{3, 4, 5, 6}, 2, 2))
+	if !reflect.DeepEqual(out.Float, []float32{1, 3, 4, 2, 5, 6}) || !reflect.DeepEqual(out.Dims, []int64{2, 3}) {
+		t.Fatal("invalid concat", out)
+	}
+
+	out = run(t, &Node{OpType: "Gemm", Inputs: []string{"a", "b", "c"}, Outputs: []string{"y"}, Attributes: []*Attribute{IntAttribute("transB", 1)}},
+		NewFloat("", []float32{1, 2}, 1, 2),
+		NewFloat("", []float32{1, 0, 0, 1, 1, 1}, 3, 2),
+		NewFloat("", []float32{0, 1, 2}, 3))
+	if !reflect.DeepEqual(out.Float, []float32{1, 3, 5}) || !reflect.DeepEqual(out.Dims, []int64{1, 3}) {
+		t.Fatal("invalid gemm", out)
+	}
+
+	out = run(t, &Node{OpType: "Softmax", Inputs: []string{"x"}, Outputs: []string{"y"}},
+		NewFloat("", []float32{0, 0, 1, 1}, 2, 2))
+	for _, v := range out.Float {
+		if math.Abs(float64(v)-.5) > 1e-6 {
+			t.Fatal("invalid softmax", out)
+		}
+	}
+
+	graph := &Graph{
+		Nodes:   []*Node{{OpType: "Conv", Inputs: []string{"x"}, Outputs: []string{"y"}}},
+		Inputs:  []*ValueInfo{{Name: "x"}},
+		Outputs: []*ValueInfo{{Name: "y"}},
+	}
+	_, err := (&Model{Graph: graph}).Run(map[string]*Tensor{"x": NewFloat("x", []float32{1}, 1)})
+	if !errors.Is(err, ErrorUnsupported) {
+		t.Fatal("expected unsupported operator error", err)
+	}
+}
@@ -0,0 +1,541 @@
+// Copyright 2018 The InjectSec Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package onnx
+
+import (
+	"fmt"
+	"math"
+)
+
+// ErrorUnsupported means the model uses an operator or attribute the
+// interpreter doesn't support
+var ErrorUnsupported = fmt.Errorf("unsupported")
+
+// Run is a reference interpreter for the operators used by the exported GRU
+// models; it evaluates the graph for the inputs and returns the graph outputs
+func (m *Model) Run(inputs map[string]*Tensor) (map[string]*Tensor, error) {
+	values := make(map[string]*Tensor, len(m.Graph.Initializers)+len(inputs))
+	for _, initializer := range m.Graph.Initializers {
+		values[initializer.Name] = initializer
+	}
+	for _, input := range m.Graph.Inputs {
+		if t, ok := inputs[input.Name]; ok {
+			values[input.Name] = t
+		} else if _, ok := values[input.Name]; !ok {
+			return nil, fmt.Errorf("missing input %q", input.Name)
+		}
+	}
+
+	for _, node := range m.Graph.Nodes {
+		in := make([]*Tensor, len(node.Inputs))
+		for i, name := range node.Inputs {
+			if name == "" {
+				continue
+			}
+			t, ok := values[name]
+			if !ok {
+				return nil, fmt.Errorf("node %q: unknown input %q", node.Name, name)
+			}
+			in[i] = t
+		}
+		op, ok := operators[node.OpType]
+		if !ok || node.Domain != "" {
+			return nil, fmt.Errorf("%w operator %q", ErrorUnsupported, node.OpType)
+		}
+		out, err := op(node, in)
+		if err != nil {
+			return nil, fmt.Errorf("node %q: %w", node.Name, err)
+		}
+		for i, name := range node.Outputs {
+			if name != "" && i < len(out) {
+				values[name] = out[i]
+			}
+		}
+	}
+
+	outputs := make(map[string]*Tensor, len(m.Graph.Outputs))
+	for _, output := range m.Graph.Outputs {
+		t, ok := values[output.Name]
+		if !ok {
+			return nil, fmt.Errorf("output %q was not computed", output.Name)
+		}
+		outputs[output.Name] = t
+	}
+	return outputs, nil
+}
+
+type operator func(node *Node, in []*Tensor) ([]*Tensor, error)
+
+var operators map[string]operator
+
+func init() {
+	operators = map[string]operator{
+		"Add":     add,
+		"Concat":  concat,
+		"GRU":     gru,
+		"Gather":  gather,
+		"Gemm":    gemm,
+		"Reshape": reshape,
+		"Slice":   slice,
+		"Softmax": softmax,
+	}
+}
+
+// strides returns the row major strides of dims
+func strides(dims []int64) []int {
+	s := make([]int, len(dims))
+	stride := 1
+	for i := len(dims) - 1; i >= 0; i-- {
+		s[i] = stride
+		stride *= int(dims[i])
+	}
+	return s
+}
+
+// pick creates a tensor with the elements of t at indexes
+func pick(t *Tensor, indexes []int, dims []int64) *Tensor {
+	out := &Tensor{DataType: t.DataType, Dims: dims}
+	switch t.DataType {
+	case DataTypeFloat:
+		out.Float = make([]float32, len(indexes))
+		for i, index := range indexes {
+			out.Float[i] = t.Float[index]
+		}
+	case DataTypeInt64:
+		out.Int64 = make([]int64, len(indexes))
+		for i, index := range indexes {
+			out.Int64[i] = t.Int64[index]
+		}
+	}
+	return out
+}
+
+func axis(a int64, rank int) (int, error) {
+	if a < 0 {
+		a += int64(rank)
+	}
+	if a < 0 || a >= int64(rank) {
+		return 0, fmt.Errorf("axis %d is out of range for rank %d", a, rank)
+	}
+	return int(a), nil
+}
+
+func add(node *Node, in []*Tensor) ([]*Tensor, error) {
+	a, b := in[0], in[1]
+	if a.DataType != DataTypeFloat || b.DataType != DataTypeFloat {
+		return nil, fmt.Errorf("%w Add data type", ErrorUnsupported)
+	}
+	rank := len(a.Dims)
+	if len(b.Dims) > rank {
+		rank = len(b.Dims)
+	}
+	// numpy style broadcasting
+	pad := func(dims []int64) []int64 {
+		padded := make([]int64, rank)
+		for i := range padded {
+			padded[i] = 1
+		}
+		copy(padded[rank-len(dims):], dims)
+		return padded
+	}
+	ad, bd, dims := pad(a.Dims), pad(b.Dims), make([]int64, rank)
+	for i := range dims {
+		switch {
+		case ad[i] == bd[i] || bd[i] == 1:
+			dims[i] = ad[i]
+		case ad[i] == 1:
+			dims[i] = bd[i]
+		default:
+			return nil, fmt.Errorf("can't broadcast %v and %v", a.Dims, b.Dims)
+		}
+	}
+	as, bs, os := strides(ad), strides(bd), strides(dims)
+	out := &Tensor{DataType: DataTypeFloat, Dims: dims}
+	size := 1
+	for _, dim := range dims {
+		size *= int(dim)
+	}
+	out.Float = make([]float32, size)
+	for i := range out.Float {
+		ai, bi, rest := 0, 0, i
+		for j := range dims {
+			index := rest / os[j]
+			rest %= os[j]
+			if ad[j] != 1 {
+				ai += index * as[j]
+			}
+			if bd[j] != 1 {
+				bi += index * bs[j]
+			}
+		}
+		out.Float[i] = a.Float[ai] + b.Float[bi]
+	}
+	return []*Tensor{out}, nil
+}
+
+func concat(node *Node, in []*Tensor) ([]*Tensor, error) {
+	first := in[0]
+	a, err := axis(node.Int("axis", 0), len(first.Dims))
+	if err != nil {
+		return nil, err
+	}
+	dims := append([]int64(nil), first.Dims...)
+	dims[a] = 0
+	for _, t := range in {
+		if len(t.Dims) != len(dims) || t.DataType != first.DataType {
+			return nil, fmt.Errorf("can't concatenate %v and %v", first.Dims, t.Dims)
+		}
+		for i := range dims {
+			if i != a && t.Dims[i] != first.Dims[i] {
+				return nil, fmt.Errorf("can't concatenate %v and %v", first.Dims, t.Dims)
+			}
+		}
+		dims[a] += t.Dims[a]
+	}
+
+	outer := 1
+	for _, dim := range dims[:a] {
+		outer *= int(dim)
+	}
+	out := &Tensor{DataType: first.DataType, Dims: dims}
+	for o := 0; o < outer; o++ {
+		for _, t := range in {
+			block := t.Size() / outer
+			switch t.DataType {
+			case DataTypeFloat:
+				out.Float = append(out.Float, t.Float[o*block:(o+1)*block]...)
+			case DataTypeInt64:
+				out.Int64 = append(out.Int64, t.Int64[o*block:(o+1)*block]...)
+			}
+		}
+	}
+	return []*Tensor{out}, nil
+}
+
+func gather(node *Node, in []*Tensor) ([]*Tensor, error) {
+	data, indices := in[0], in[1]
+	if node.Int("axis", 0) != 0 || len(data.Dims) == 0 {
+		return nil, fmt.Errorf("%w Gather axis", ErrorUnsupported)
+	}
+	if indices.DataType != DataTypeInt64 {
+		return nil, fmt.Errorf("%w Gather indices type", ErrorUnsupported)
+	}
+	rows, block := int(data.Dims[0]), data.Size()/int(data.Dims[0])
+	var indexes []int
+	for _, index := range indices.Int64 {
+		if index < 0 {
+			index += int64(rows)
+		}
+		if index < 0 || index >= int64(rows) {
+			return nil, fmt.Errorf("index %d is out of range", index)
+		}
+		for i := 0; i < block; i++ {
+			indexes = append(indexes, int(index)*block+i)
+		}
+	}
+	dims := append(append([]int64(nil), indices.Dims...), data.Dims[1:]...)
+	return []*Tensor{pick(data, indexes, dims)}, nil
+}
+
+func gemm(node *Node, in []*Tensor) ([]*Tensor, error) {
+	a, b := in[0], in[1]
+	if len(a.Dims) != 2 || len(b.Dims) != 2 {
+		return nil, fmt.Errorf("Gemm needs matrices")
+	}
+	alpha, beta := node.Float("alpha", 1), node.Float("beta", 1)
+	transA, transB := node.Int("transA", 0) != 0, node.Int("transB", 0) != 0
+	m, k := int(a.Dims[0]), int(a.Dims[1])
+	if transA {
+		m, k = k, m
+	}
+	kb, n := int(b.Dims[0]), int(b.Dims[1])
+	if transB {
+		kb, n = n, kb
+	}
+	if k != kb {
+		return nil, fmt.Errorf("can't multiply %v and %v", a.Dims, b.Dims)
+	}
+	at := func(i, j int) float32 {
+		if transA {
+			return a.Float[j*m+i]
+		}
+		return a.Float[i*k+j]
+	}
+	bt := func(i, j int) float32 {
+		if transB {
+			return b.Float[j*k+i]
+		}
+		return b.Float[i*n+j]
+	}
+	out := NewFloat("", make([]float32, m*n), int64(m), int64(n))
+	if len(in) > 2 && in[2] != nil {
+		c, err := add(node, []*Tensor{out, in[2]})
+		if err != nil {
+			return nil, err
+		}
+		out = c[0]
+		for i := range out.Float {
+			out.Float[i] *= beta
+		}
+	}
+	for i := 0; i < m; i++ {
+		for j := 0; j < n; j++ {
+			sum := float32(0)
+			for l := 0; l < k; l++ {
+				sum += at(i, l) * bt(l, j)
+			}
+			out.Float[i*n+j] += alpha * sum
+		}
+	}
+	return []*Tensor{out}, nil
+}
+
+func reshape(node *Node, in []*Tensor) ([]*Tensor, error) {
+	data, shape := in[0], in[1]
+	if shape.DataType != DataTypeInt64 {
+		return nil, fmt.Errorf("%w Reshape shape type", ErrorUnsupported)
+	}
+	dims, infer, size := make([]int64, len(shape.Int64)), -1, int64(1)
+	for i, dim := range shape.Int64 {
+		switch {
+		case dim == 0 && node.Int("allowzero", 0) == 0:
+			if i >= len(data.Dims) {
+				return nil, fmt.Errorf("can't copy dimension %d of %v", i, data.Dims)
+			}
+			dim = data.Dims[i]
+		case dim == -1:
+			if infer >= 0 {
+				return nil, fmt.Errorf("more than one inferred dimension")
+			}
+			infer = i
+			continue
+		case dim < 0:
+			return nil, fmt.Errorf("invalid dimension %d", dim)
+		}
+		dims[i] = dim
+		size *= dim
+	}
+	if infer >= 0 {
+		if size == 0 || int64(data.Size())%size != 0 {
+			return nil, fmt.Errorf("can't reshape %v to %v", data.Dims, shape.Int64)
+		}
+		dims[infer] = int64(data.Size()) / size
+		size *= dims[infer]
+	}
+	if size != int64(data.Size()) {
+		return nil, fmt.Errorf("can't reshape %v to %v", data.Dims, shape.Int64)
+	}
+	out := *data
+	out.Name, out.Dims = "", dims
+	return []*Tensor{&out}, nil
+}
+
+func slice(node *Node, in []*Tensor) ([]*Tensor, error) {
+	if len(in) < 3 || in[1] == nil || in[2] == nil {
+		return nil, fmt.Errorf("Slice needs starts and ends")
+	}
+	data := in[0]
+	rank := len(data.Dims)
+	get := func(i int, fallback func(int) int64) ([]int64, error) {
+		if i < len(in) && in[i] != nil {
+			if in[i].DataType != DataTypeInt64 {
+				return nil, fmt.Errorf("%w Slice input type", ErrorUnsupported)
+			}
+			return in[i].Int64, nil
+		}
+		values := make([]int64, len(in[1].Int64))
+		for j := range values {
+			values[j] = fallback(j)
+		}
+		return values, nil
+	}
+	starts, err := get(1, nil)
+	if err != nil {
+		return nil, err
+	}
+	ends, err := get(2, nil)
+	if err != nil {
+		return nil, err
+	}
+	axes, err := get(3, func(j int) int64 { return int64(j) })
+	if err != nil {
+		return nil, err
+	}
+	steps, err := get(4, func(int) int64 { return 1 })
+	if err != nil {
+		return nil, err
+	}
+	if len(ends) != len(starts) || len(axes) != len(starts) || len(steps) != len(starts) {
+		return nil, fmt.Errorf("invalid Slice inputs")
+	}
+
+	begin, step, dims := make([]int64, rank), make([]int64, rank), append([]int64(nil), data.Dims...)
+	for i := range step {
+		step[i] = 1
+	}
+	for i, a := range axes {
+		a, err := axis(a, rank)
+		if err != nil {
+			return nil, err
+		}
+		dim, start, end, s := data.Dims[a], starts[i], ends[i], steps[i]
+		if s == 0 {
+			return nil, fmt.Errorf("invalid step 0")
+		}
+		if start < 0 {
+			start += dim
+		}
+		if end < 0 {
+			end += dim
+		}
+		var count int64
+		if s > 0 {
+			start, end = clamp(start, 0, dim), clamp(end, 0, dim)
+			if end > start {
+				count = (end - start + s - 1) / s
+			}
+		} else {
+			start, end = clamp(start, 0, dim-1), clamp(end, -1, dim-1)
+			if start > end {
+				count = (start - end - s - 1) / -s
+			}
+		}
+		begin[a], step[a], dims[a] = start, s, count
+	}
+
+	size := 1
+	for _, dim := range dims {
+		size *= int(dim)
+	}
+	ds, os := strides(data.Dims), strides(dims)
+	indexes := make([]int, size)
+	for i := range indexes {
+		index, rest := 0, i
+		for j := range dims {
+			k := rest / os[j]
+			rest %= os[j]
+			index += int(begin[j]+int64(k)*step[j]) * ds[j]
+		}
+		indexes[i] = index
+	}
+	return []*Tensor{pick(data, indexes, dims)}, nil
+}
+
+func clamp(v, min, max int64) int64 {
+	if v < min {
+		return min
+	} else if v > max {
+		return max
+	}
+	return v
+}
+
+func softmax(node *Node, in []*Tensor) ([]*Tensor, error) {
+	data := in[0]
+	a, err := axis(node.Int("axis", -1), len(data.Dims))
+	if err != nil {
+		return nil, err
+	}
+	inner, length := 1, int(data.Dims[a])
+	for _, dim := range data.Dims[a+1:] {
+		inner *= int(dim)
+	}
+	out := &Tensor{DataType: DataTypeFloat, Dims: data.Dims, Float: make([]float32, len(data.Float))}
+	for base := 0; base < len(data.Float); base += length * inner {
+		for i := 0; i < inner; i++ {
+			max := math.Inf(-1)
+			for j := 0; j < length; j++ {
+				max = math.Max(max, float64(data.Float[base+j*inner+i]))
+			}
+			sum := 0.0
+			for j := 0; j < length; j++ {
+				sum += math.Exp(float64(data.Float[base+j*inner+i]) - max)
+			}
+			for j := 0; j < length; j++ {
+				index := base + j*inner + i
+				out.Float[index] = float32(math.Exp(float64(data.Float[index])-max) / sum)
+			}
+		}
+	}
+	return []*Tensor{out}, nil
+}
+
+// gru implements the forward GRU with the default activations
+func gru(node *Node, in []*Tensor) ([]*Tensor, error) {
+	if node.String("direction", "forward") != "forward" || node.Attribute("activations") != nil ||
+		node.Int("layout", 0) != 0 {
+		return nil, fmt.Errorf("%w GRU attributes", ErrorUnsupported)
+	}
+	if len(in) > 4 && in[4] != nil {
+		return nil, fmt.Errorf("%w GRU sequence_lens", ErrorUnsupported)
+	}
+	x, w, r := in[0], in[1], in[2]
+	if len(x.Dims) != 3 || len(w.Dims) != 3 || len(r.Dims) != 3 {
+		return nil, fmt.Errorf("invalid GRU inputs")
+	}
+	steps, batch, inputSize := int(x.Dims[0]), int(x.Dims[1]), int(x.Dims[2])
+	hidden := int(node.Int("hidden_size", r.Dims[2]))
+	if int(w.Dims[1]) != 3*hidden || int(w.Dims[2]) != inputSize || int(r.Dims[1]) != 3*hidden || int(r.Dims[2]) != hidden {
+		return nil, fmt.Errorf("invalid GRU weights %v %v", w.Dims, r.Dims)
+	}
+	bias := make([]float32, 6*hidden)
+	if len(in) > 3 && in[3] != nil {
+		if in[3].Size() != 6*hidden {
+			return nil, fmt.Errorf("invalid GRU bias %v", in[3].Dims)
+		}
+		copy(bias, in[3].Float)
+	}
+	h := make([]float32, batch*hidden)
+	if len(in) > 5 && in[5] != nil {
+		copy(h, in[5].Float)
+	}
+	linearBeforeReset := node.Int("linear_before_reset", 0) != 0
+
+	// row returns gate g of row i of the weights times v
+	row := func(weights []float32, cols, g, i int, v []float32) float32 {
+		sum, offset := float32(0), (g*hidden+i)*cols
+		for j, value := range v {
+			sum += weights[offset+j] * value
+		}
+		return sum
+	}
+	sigmoid := func(x float32) float32 {
+		return float32(1 / (1 + math.Exp(-float64(x))))
+	}
+	y := make([]float32, steps*batch*hidden)
+	for t := 0; t < steps; t++ {
+		next := make([]float32, batch*hidden)
+		for b := 0; b < batch; b++ {
+			xt := x.Float[(t*batch+b)*inputSize : (t*batch+b+1)*inputSize]
+			ht := h[b*hidden : (b+1)*hidden]
+			z, reset := make([]float32, hidden), make([]float32, hidden)
+			for i := range z {
+				z[i] = sigmoid(row(w.Float, inputSize, 0, i, xt) + row(r.Float, hidden, 0, i, ht) + bias[i] + bias[3*hidden+i])
+				reset[i] = sigmoid(row(w.Float, inputSize, 1, i, xt) + row(r.Float, hidden, 1, i, ht) + bias[hidden+i] + bias[4*hidden+i])
+			}
+			gated := make([]float32, hidden)
+			for i := range gated {
+				gated[i] = reset[i] * ht[i]
+			}
+			for i := range z {
+				var candidate float32
+				if linearBeforeReset {
+					candidate = row(w.Float, inputSize, 2, i, xt) + bias[2*hidden+i] +
+						reset[i]*(row(r.Float, hidden, 2, i, ht)+bias[5*hidden+i])
+				} else {
+					candidate = row(w.Float, inputSize, 2, i, xt) + bias[2*hidden+i] +
+						row(r.Float, hidden, 2, i, gated) + bias[5*hidden+i]
+				}
+				next[b*hidden+i] = (1-z[i])*float32(math.Tanh(float64(candidate))) + z[i]*ht[i]
+			}
+		}
+		h = next
+		copy(y[t*batch*hidden:], h)
+	}
+	return []*Tensor{
+		NewFloat("", y, int64(steps), 1, int64(batch), int64(hidden)),
+		NewFloat("", h, 1, int64(batch), int64(hidden)),
+	}, nil
+}